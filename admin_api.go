@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// --- Admin API ---
+
+// Admin API Configuration (Read from Environment Variables)
+var adminAPIToken = os.Getenv("ADMIN_API_TOKEN") // Bearer token, the API only starts when set
+var adminAPIAddr = os.Getenv("ADMIN_API_ADDR")   // Listen address, defaults to 127.0.0.1:8080
+
+// auditLogCollectionName records every change made through the admin API
+const auditLogCollectionName = "audit_log"
+
+// auditLog is the audit collection, nil when MongoDB isn't connected (entries are still printed)
+var auditLog *mongo.Collection
+
+// AuditEntry is one config change made through the admin API
+type AuditEntry struct {
+	Action     string      `bson:"action" json:"action"` // e.g. "PUT /api/keywords"
+	Before     interface{} `bson:"before" json:"before"`
+	After      interface{} `bson:"after" json:"after"`
+	RemoteAddr string      `bson:"remote_addr" json:"remote_addr"`
+	At         time.Time   `bson:"at" json:"at"`
+}
+
+// recordAudit prints the change and stores it in the audit collection.
+func recordAudit(entry AuditEntry) {
+	fmt.Printf("AUDIT: %s from %s at %s\n", entry.Action, entry.RemoteAddr, entry.At.Format(time.RFC3339))
+	if auditLog == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := auditLog.InsertOne(ctx, entry); err != nil {
+		fmt.Printf("Error storing audit log entry for %s: %v\n", entry.Action, err)
+	}
+}
+
+// persistConfigMu serializes persistConfigField, so concurrent changes don't drop each other's field
+var persistConfigMu sync.Mutex
+
+// persistConfigField writes value under key in the config file (or the stored config in Mongo
+// config mode), keeping its other settings, so changes survive a restart. The file is created if there is none yet.
+func persistConfigField(key string, value interface{}) error {
+	persistConfigMu.Lock()
+	defer persistConfigMu.Unlock()
+	if mongoConfigEnabled {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return setStoredConfigField(key, raw)
+	}
+	path := configPath
+	if path == "" {
+		path = "config.json"
+	}
+	fields := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	fields[key] = raw
+	data, err = json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write a temporary file and rename it, so a crash never leaves a half-written config
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary config file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+	_, err = tmp.Write(append(data, '\n'))
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// requireToken wraps a handler with bearer token authentication.
+func requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminAPIToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writeJSON sends value as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		fmt.Printf("Error writing admin API response: %v\n", err)
+	}
+}
+
+// handleKeywords serves GET and PUT /api/keywords. A PUT replaces the ungrouped keywords;
+// running cycles finish with the old ones, the next cycle uses the new ones.
+func handleKeywords(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		configMu.RLock()
+		current := keywords
+		configMu.RUnlock()
+		writeJSON(w, http.StatusOK, current)
+
+	case http.MethodPut:
+		var specs []KeywordSpec
+		if err := json.NewDecoder(r.Body).Decode(&specs); err != nil {
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for i, spec := range specs {
+			if err := spec.validate(); err != nil {
+				http.Error(w, fmt.Sprintf("keywords[%d] (%s): %v", i, spec.label(), err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		configMu.Lock()
+		if len(specs) == 0 && len(keywordGroups) == 0 {
+			configMu.Unlock()
+			http.Error(w, "at least one keyword is required", http.StatusBadRequest)
+			return
+		}
+		before := keywords
+		keywords = specs
+		resetKeywordPatterns()
+		configMu.Unlock()
+
+		if err := persistConfigField("keywords", specs); err != nil {
+			fmt.Printf("Error persisting keywords: %v\n", err)
+		}
+		recordAudit(AuditEntry{Action: "PUT /api/keywords", Before: before, After: specs, RemoteAddr: r.RemoteAddr, At: time.Now()})
+		writeJSON(w, http.StatusOK, specs)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSubreddits serves GET and PUT /api/subreddits. A PUT replaces the monitored subreddits
+// and restarts the poll jobs once their running cycles have finished.
+func handleSubreddits(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		configMu.RLock()
+		current := subredditConfigs
+		configMu.RUnlock()
+		writeJSON(w, http.StatusOK, current)
+
+	case http.MethodPut:
+		var configs []SubredditConfig
+		if err := json.NewDecoder(r.Body).Decode(&configs); err != nil {
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(configs) == 0 {
+			http.Error(w, "at least one subreddit is required", http.StatusBadRequest)
+			return
+		}
+		seen := map[string]bool{}
+		for i, sub := range configs {
+			if sub.Name == "" {
+				http.Error(w, fmt.Sprintf("subreddits[%d] has an empty name", i), http.StatusBadRequest)
+				return
+			}
+			if seen[strings.ToLower(sub.Name)] {
+				http.Error(w, fmt.Sprintf("subreddit %s is listed twice", sub.Name), http.StatusBadRequest)
+				return
+			}
+			seen[strings.ToLower(sub.Name)] = true
+			if err := sub.validate(); err != nil {
+				http.Error(w, fmt.Sprintf("subreddit %s: %v", sub.Name, err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		configMu.Lock()
+		before := subredditConfigs
+		setSubredditConfigs(configs)
+		configMu.Unlock()
+		signalConfigReload()
+
+		if err := persistConfigField("subreddits", configs); err != nil {
+			fmt.Printf("Error persisting subreddits: %v\n", err)
+		}
+		recordAudit(AuditEntry{Action: "PUT /api/subreddits", Before: before, After: configs, RemoteAddr: r.RemoteAddr, At: time.Now()})
+		writeJSON(w, http.StatusOK, configs)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// StatusResponse is the body of GET /api/status
+type StatusResponse struct {
+	Breakers        []BreakerStatus `json:"breakers"`
+	MongoReconnects int64           `json:"mongo_reconnects"`
+	Panics          int64           `json:"panics"`     // Recovered since startup
+	HeldItems       int             `json:"held_items"` // Held in memory while the store fails, see storeOutage
+}
+
+// handleStatus serves GET /api/status with the state of the Reddit circuit breakers, the
+// number of MongoDB reconnection attempts, of recovered panics and of items held for the store.
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, StatusResponse{
+		Breakers:        []BreakerStatus{postsBreaker.status(), commentsBreaker.status()},
+		MongoReconnects: mongoReconnects.Load(),
+		Panics:          panicCount.Load(),
+		HeldItems:       storeOutage.held(),
+	})
+}
+
+// BackfillRequest is the body of POST /api/backfill
+type BackfillRequest struct {
+	Subreddit string `json:"subreddit"`
+	Days      int    `json:"days"` // Defaults to defaultQuietBackfillDays
+}
+
+// handleBackfill serves GET and POST /api/backfill. A POST starts a quiet backfill of a subreddit,
+// a GET lists the backfills with their progress.
+func handleBackfill(w http.ResponseWriter, r *http.Request) {
+	if quietBackfills == nil {
+		http.Error(w, "backfills need MongoDB", http.StatusServiceUnavailable)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		jobs, err := quietBackfills.list(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, jobs)
+
+	case http.MethodPost:
+		var request BackfillRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.Days == 0 {
+			request.Days = defaultQuietBackfillDays
+		}
+		job, err := quietBackfills.start(request.Subreddit, request.Days)
+		switch {
+		case errors.Is(err, errInvalidBackfill):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, errBackfillInProgress):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		recordAudit(AuditEntry{Action: "POST /api/backfill", After: request, RemoteAddr: r.RemoteAddr, At: time.Now()})
+		writeJSON(w, http.StatusAccepted, job)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// runAdminAPI serves the admin API until ctx is cancelled. It does nothing without ADMIN_API_TOKEN.
+func runAdminAPI(ctx context.Context) {
+	if adminAPIToken == "" {
+		return
+	}
+	addr := adminAPIAddr
+	if addr == "" {
+		addr = "127.0.0.1:8080"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/keywords", requireToken(handleKeywords))
+	mux.HandleFunc("/api/subreddits", requireToken(handleSubreddits))
+	mux.HandleFunc("/api/status", requireToken(handleStatus))
+	mux.HandleFunc("/api/backfill", requireToken(handleBackfill))
+	mux.HandleFunc("/api/stats", requireToken(handleStats))
+	mux.HandleFunc("/api/matches", requireDashboardAuth(handleAPIMatches))
+	mux.HandleFunc("/dashboard", requireDashboardAuth(handleDashboard))
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctxShutdown)
+	}()
+
+	fmt.Println("Admin API listening on", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("Error running admin API: %v\n", err)
+	}
+}
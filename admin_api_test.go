@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestPersistConfigFieldConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := configPath
+	configPath = filepath.Join(dir, "config.json")
+	t.Cleanup(func() { configPath = oldPath })
+	if err := os.WriteFile(configPath, []byte(`{"search_mode": true}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := persistConfigField(fmt.Sprintf("field_%d", i), i); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 11 {
+		t.Errorf("config has %d fields, want search_mode and all 10 persisted ones: %s", len(fields), data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("%d files left in the config directory, want only config.json", len(entries))
+	}
+}
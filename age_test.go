@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCreatedTime(t *testing.T) {
+	tests := []struct {
+		name       string
+		createdUtc float64
+		want       time.Time
+	}{
+		{"zero", 0, time.Time{}},
+		{"negative", -1, time.Time{}},
+		{"NaN", math.NaN(), time.Time{}},
+		{"out of range", 1e300, time.Time{}},
+		{"whole seconds", 1700000000, time.Unix(1700000000, 0).UTC()},
+		{"float seconds", 1700000000.25, time.Unix(1700000000, 250000000).UTC()},
+	}
+	for _, tt := range tests {
+		if got := createdTime(tt.createdUtc); !got.Equal(tt.want) {
+			t.Errorf("%s: createdTime(%v) = %v, want %v", tt.name, tt.createdUtc, got, tt.want)
+		}
+	}
+}
+
+func TestTooOldReason(t *testing.T) {
+	oldMaxAge, oldLookback, oldStartedAt, oldBackfill := maxItemAge, startupLookback, startedAt, backfillMode
+	t.Cleanup(func() {
+		maxItemAge, startupLookback, startedAt, backfillMode = oldMaxAge, oldLookback, oldStartedAt, oldBackfill
+	})
+	ago := func(d time.Duration) float64 {
+		return float64(time.Now().Add(-d).UnixNano()) / 1e9
+	}
+
+	tests := []struct {
+		name       string
+		createdUtc float64
+		maxAge     time.Duration
+		lookback   time.Duration
+		startedAgo time.Duration
+		backfill   bool
+		wantTooOld bool
+	}{
+		{"zero timestamp", 0, time.Hour, time.Hour, 0, false, false},
+		{"recent", ago(time.Minute), time.Hour, time.Hour, 0, false, false},
+		{"float seconds just over max age", ago(time.Hour + 1500*time.Millisecond), time.Hour, 0, 0, false, true},
+		{"older than max age", ago(2 * time.Hour), time.Hour, 0, 0, false, true},
+		{"max age disabled", ago(2 * time.Hour), 0, 0, 0, false, false},
+		{"before the startup lookback", ago(3 * time.Hour), 0, time.Hour, time.Hour, false, true},
+		{"within the startup lookback", ago(90 * time.Minute), 0, time.Hour, time.Hour, false, false},
+		{"backfill mode", ago(48 * time.Hour), time.Hour, time.Hour, 0, true, false},
+	}
+	for _, tt := range tests {
+		maxItemAge, startupLookback, backfillMode = tt.maxAge, tt.lookback, tt.backfill
+		startedAt = time.Now().Add(-tt.startedAgo)
+		if got := tooOldReason(tt.createdUtc); (got != "") != tt.wantTooOld {
+			t.Errorf("%s: tooOldReason = %q, want too old %v", tt.name, got, tt.wantTooOld)
+		}
+	}
+}
+
+func TestAgeCheckedListings(t *testing.T) {
+	tests := []struct {
+		post Post
+		want bool
+	}{
+		{Post{Listing: "new"}, true},
+		{Post{Listing: "multireddit/new"}, true},
+		{Post{Listing: "multireddit/hot"}, false},
+		{Post{Listing: "top:week"}, false},
+		{Post{Listing: "new", Held: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.post.ageChecked(); got != tt.want {
+			t.Errorf("ageChecked(%q, held %v) = %v, want %v", tt.post.Listing, tt.post.Held, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// --- Alerts ---
+
+// Alert is a match notification. Plain notifiers get its Subject and Body through Notify,
+// channels that build their own messages (push, chat) implement AlertNotifier to get the details.
+type Alert struct {
+	Subject   string
+	Body      string
+	Kind      string // "post" or "comment"
+	Subreddit string
+	Permalink string // Reddit permalink, without the host
+	Title     string // Post title, or the parent post's title for comments
+	Snippet   string // Start of the matched text
+	Keywords  []string
+	Groups    []string
+	Priority  string // Highest priority of the matched keyword groups, see alertPriorities
+	SMS       bool   // A matched keyword group has "sms": true
+
+	KeywordPriority string // Highest priority of the matched keywords, see keywordPriorities
+}
+
+// URL returns the alert's link on Reddit.
+func (a Alert) URL() string {
+	return "https://www.reddit.com" + a.Permalink
+}
+
+// AlertNotifier is a Notifier that formats match alerts itself
+type AlertNotifier interface {
+	Notifier
+	NotifyAlert(alert Alert) error
+}
+
+// notifyAlert sends alert through notifier, as an Alert if it takes them, as subject and body otherwise.
+func notifyAlert(notifier Notifier, alert Alert) (err error) {
+	defer recoverPanic(fmt.Sprintf("notifier %T", notifier), &err)
+	if alertNotifier, ok := notifier.(AlertNotifier); ok {
+		return alertNotifier.NotifyAlert(alert)
+	}
+	return notifier.Notify(alert.Subject, alert.Body)
+}
+
+// A match has two priorities, on two scales that don't mix:
+//   - Its keyword priority, the highest "priority" of its keywords (keywordPriorities), decides
+//     when it is delivered: low matches only go to the daily digest, medium ones are batched and
+//     high ones are sent at once, led by highPriorityTag and pushed to Pushover at least at high.
+//   - Its group priority, the highest "priority" of its keyword groups (alertPriorities), decides
+//     how urgently push channels present it, see ntfyPriorities and pushoverPriorities. It doesn't
+//     change when the match is delivered.
+//
+// medium and normal are the defaults of their scale. A keyword group's priority doesn't apply to
+// its keywords: a high priority group of medium keywords is batched, then pushed as high.
+
+// alertPriorities are the keyword group priorities, lowest first. Groups without one are normal.
+var alertPriorities = []string{"low", "normal", "high", "emergency"}
+
+// validAlertPriority reports whether priority is a known keyword group priority or empty.
+func validAlertPriority(priority string) bool {
+	return priority == "" || priorityRank(priority) >= 0
+}
+
+// priorityRank returns the position of priority in alertPriorities, -1 if unknown.
+func priorityRank(priority string) int {
+	for i, p := range alertPriorities {
+		if p == priority {
+			return i
+		}
+	}
+	return -1
+}
+
+// alertPriority returns the highest priority of the named keyword groups, "normal" if none sets one.
+func (r matchRules) alertPriority(groups []string) string {
+	priority := "normal"
+	for _, group := range r.groups {
+		if containsFold(groups, group.Name) && priorityRank(group.Priority) > priorityRank(priority) {
+			priority = group.Priority
+		}
+	}
+	return priority
+}
+
+// keywordPriorities are the keyword priorities, lowest first. Keywords without one are medium.
+var keywordPriorities = []string{"low", "medium", "high"}
+
+// highPriorityTag leads the subject of high priority matches
+const highPriorityTag = "[HIGH PRIORITY] "
+
+// validKeywordPriority reports whether priority is a known keyword priority or empty.
+func validKeywordPriority(priority string) bool {
+	return priority == "" || slices.Contains(keywordPriorities, priority)
+}
+
+// keywordPriority returns the highest priority of the keywords in found, keywords without one counting as medium.
+// High priority matches skip batching, low priority ones are left to the daily digest.
+func (r matchRules) keywordPriority(found []string) string {
+	specs := slices.Clone(r.keywords)
+	for _, group := range r.groups {
+		specs = append(specs, group.Keywords...)
+	}
+	priority := ""
+	for _, spec := range specs {
+		if !containsKeyword(found, spec.label()) {
+			continue
+		}
+		specPriority := spec.Priority
+		if specPriority == "" {
+			specPriority = "medium"
+		}
+		if slices.Index(keywordPriorities, specPriority) > slices.Index(keywordPriorities, priority) {
+			priority = specPriority
+		}
+	}
+	if priority == "" {
+		return "medium" // Domain and search monitor matches
+	}
+	return priority
+}
+
+// smsAlert reports whether one of the named keyword groups is marked for SMS.
+func (r matchRules) smsAlert(groups []string) bool {
+	for _, group := range r.groups {
+		if group.SMS && containsFold(groups, group.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxSnippetLength caps the matched text quoted in alerts, in characters
+const maxSnippetLength = 280
+
+// alertSnippet returns the start of text on one line, for alerts.
+func alertSnippet(text string) string {
+	return truncate(strings.Join(strings.Fields(text), " "), maxSnippetLength)
+}
+
+// alertChannels returns the notifiers of the given ntfy topic, Teams webhook and Pushover user key,
+// skipping those that are empty.
+func alertChannels(ntfy, teams, pushoverUser string) []Notifier {
+	channels := []Notifier{}
+	if ntfy != "" {
+		channels = append(channels, NewNtfyNotifier(ntfy))
+	}
+	if teams != "" {
+		channels = append(channels, NewTeamsNotifier(teams))
+	}
+	if pushoverUser != "" && pushoverAppToken != "" {
+		channels = append(channels, NewPushoverNotifier(pushoverUser))
+	}
+	return channels
+}
+
+// topLevelAlertChannels returns the channels alerts of the top-level config go to besides email.
+// Matrix, Kafka, AMQP, NATS and SQS are only configured at the top level.
+func topLevelAlertChannels() []Notifier {
+	channels := alertChannels(ntfyTopic, teamsWebhookURL, pushoverUserKey)
+	if matrixRoomID != "" {
+		channels = append(channels, NewMatrixNotifier())
+	}
+	if kafkaTopic != "" {
+		channels = append(channels, NewKafkaNotifier())
+	}
+	if amqpURL != "" {
+		channels = append(channels, NewAMQPNotifier())
+	}
+	if natsURL != "" {
+		channels = append(channels, NewNATSNotifier())
+	}
+	if sqsQueueURL != "" {
+		channels = append(channels, NewSQSNotifier())
+	}
+	return channels
+}
+
+// withAlertChannels returns email plus channels, or email alone if there are none. With Twilio
+// configured, SMS is added for alerts of sms keyword groups, announcing the daily cap through email.
+func withAlertChannels(email Notifier, channels []Notifier) Notifier {
+	if logNotifications {
+		return email // Printed only, nothing goes out
+	}
+	if twilio.AccountSID != "" {
+		channels = append(slices.Clip(channels), NewSMSNotifier(email))
+	}
+	if len(channels) == 0 {
+		return email
+	}
+	return append(MultiNotifier{email}, channels...)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// --- AMQP (RabbitMQ) ---
+
+// AMQP settings from the config file, match events are published to the exchange when amqpURL is set
+var amqpURL = ""
+var amqpExchange = "reddit_monitor"
+var amqpExchangeType = "topic"
+var amqpRoutingKeyTemplate = "match.{subreddit}.{keyword}"
+
+// amqpExchangeTypes are the accepted values of amqp_exchange_type
+var amqpExchangeTypes = map[string]bool{"direct": true, "fanout": true, "topic": true, "headers": true}
+
+// amqpConn is the publisher shared by every AMQPNotifier, connected at startup and closed on shutdown
+var amqpConn = &amqpPublisher{}
+
+// AMQPNotifier publishes a MatchEvent for every alert to the configured exchange
+type AMQPNotifier struct {
+	publisher *amqpPublisher
+}
+
+// NewAMQPNotifier returns a Notifier publishing to the configured AMQP exchange.
+func NewAMQPNotifier() *AMQPNotifier {
+	return &AMQPNotifier{publisher: amqpConn}
+}
+
+// Notify does nothing: the exchange carries match events only, digests and summaries go to the
+// other channels.
+func (n *AMQPNotifier) Notify(subject, body string) error {
+	return nil
+}
+
+// NotifyBatch publishes an event for every alert of a batched cycle.
+func (n *AMQPNotifier) NotifyBatch(subject, body string, alerts []Alert) error {
+	errs := []error{}
+	for _, alert := range alerts {
+		if err := n.NotifyAlert(alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyAlert publishes the match as a persistent JSON message, routed by amqpRoutingKey.
+func (n *AMQPNotifier) NotifyAlert(alert Alert) error {
+	body, err := json.Marshal(MatchEvent{SchemaVersion: matchEventSchemaVersion, NotificationData: notificationData(alert)})
+	if err != nil {
+		return err
+	}
+	routingKey := amqpRoutingKey(amqpRoutingKeyTemplate, alert)
+	err = withRetry("AMQP publish", func() error {
+		return n.publisher.publish(routingKey, body)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to AMQP exchange %s: %w", amqpExchange, err)
+	}
+	fmt.Printf("Match event published to AMQP exchange %s (%s)\n", amqpExchange, routingKey)
+	return nil
+}
+
+// routingKeyUnsafe matches what can't be part of a routing key word: dots separate words in topic
+// exchanges, keyword labels carry spaces and options
+var routingKeyUnsafe = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// amqpRoutingKey fills {subreddit} and {keyword} in template. A match of several keywords is
+// published once, under its first keyword.
+func amqpRoutingKey(template string, alert Alert) string {
+	keyword := ""
+	if len(alert.Keywords) > 0 {
+		keyword = keywordLabelName(alert.Keywords[0])
+	}
+	return strings.NewReplacer("{subreddit}", routingKeyWord(alert.Subreddit), "{keyword}", routingKeyWord(keyword)).Replace(template)
+}
+
+// routingKeyWord lowercases s and replaces what can't be part of a routing key word with "_".
+func routingKeyWord(s string) string {
+	return strings.Trim(routingKeyUnsafe.ReplaceAllString(strings.ToLower(s), "_"), "_")
+}
+
+// keywordLabelName returns the keyword of a stored label, without its options and matched text,
+// e.g. "VA" for "VA [title, case-sensitive]".
+func keywordLabelName(label string) string {
+	label, _, _ = strings.Cut(label, " = ")
+	label, _, _ = strings.Cut(label, " [")
+	return label
+}
+
+// Timeouts of connecting and of a publish, and the longest wait between reconnect attempts
+const (
+	amqpTimeout           = 10 * time.Second
+	amqpMaxReconnectDelay = time.Minute
+)
+
+// amqpPublisher publishes over one amqp091-go connection and channel in confirm mode, so a publish
+// returns once the broker took the message. A dropped connection is reopened in the background,
+// backing off from 1s to amqpMaxReconnectDelay between attempts.
+type amqpPublisher struct {
+	mu           sync.Mutex
+	conn         amqpChannel // Nil while disconnected
+	settings     string      // URL, exchange and type the connection was opened with
+	reconnecting bool
+	closed       bool
+}
+
+// amqpChannel is the connection and confirm-mode channel the publisher publishes on
+type amqpChannel interface {
+	// publish publishes msg and waits for the broker's confirm, reporting whether it was an ack.
+	publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (acked bool, err error)
+	isClosed() bool
+	close() error
+}
+
+// amqpConnection is an amqpChannel over an amqp091-go connection and its channel
+type amqpConnection struct {
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func (c *amqpConnection) publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (bool, error) {
+	confirm, err := c.channel.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, msg)
+	if err != nil {
+		return false, err
+	}
+	return confirm.WaitContext(ctx)
+}
+
+func (c *amqpConnection) isClosed() bool { return c.conn.IsClosed() }
+func (c *amqpConnection) close() error   { return c.conn.Close() }
+
+// start connects and declares the exchange in the background, so startup doesn't wait on the broker.
+func (p *amqpPublisher) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reconnectLocked()
+}
+
+// reconnectLocked starts the reconnect loop unless it runs already. p.mu must be held.
+func (p *amqpPublisher) reconnectLocked() {
+	if p.reconnecting || p.closed {
+		return
+	}
+	p.reconnecting = true
+	go p.reconnect()
+}
+
+// reconnect connects until it succeeds or the publisher is closed, backing off between attempts.
+func (p *amqpPublisher) reconnect() {
+	delay := time.Second
+	for {
+		p.mu.Lock()
+		if p.closed || p.conn != nil {
+			p.reconnecting = false
+			p.mu.Unlock()
+			return
+		}
+		err := p.connect()
+		if err == nil {
+			p.reconnecting = false
+			p.mu.Unlock()
+			fmt.Printf("Connected to AMQP, publishing to exchange %s (%s)\n", amqpExchange, amqpExchangeType)
+			return
+		}
+		p.mu.Unlock()
+		fmt.Printf("WARN: AMQP connection failed, retrying in %v: %v\n", delay, err)
+		time.Sleep(delay)
+		delay = min(delay*2, amqpMaxReconnectDelay)
+	}
+}
+
+// publish publishes body to the exchange as a persistent JSON message and waits for the broker's
+// confirm. Without a connection it fails at once with a retryable error, the reconnect loop
+// running in the background.
+func (p *amqpPublisher) publish(routingKey string, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil && (p.settings != amqpSettings() || p.conn.isClosed()) {
+		p.drop() // Config reload changed the broker or exchange, or the connection broke
+	}
+	if p.conn == nil {
+		p.reconnectLocked()
+		return &retryableError{err: errors.New("not connected to the AMQP broker, reconnecting")}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), amqpTimeout)
+	defer cancel()
+	acked, err := p.conn.publish(ctx, amqpExchange, routingKey, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         body,
+	})
+	if err == nil && !acked {
+		return &retryableError{err: errors.New("broker refused the message (nack)")}
+	}
+	if err != nil {
+		p.drop()
+		p.reconnectLocked()
+		return &retryableError{err: err}
+	}
+	return nil
+}
+
+// amqpSettings identifies the configured broker and exchange.
+func amqpSettings() string {
+	return amqpURL + " " + amqpExchange + " " + amqpExchangeType
+}
+
+// connect opens the connection and channel, enables publisher confirms and declares the exchange
+// (durable). p.mu must be held.
+func (p *amqpPublisher) connect() error {
+	settings := amqpSettings()
+	conn, err := amqp.DialConfig(amqpURL, amqp.Config{
+		Dial:       amqp.DefaultDial(amqpTimeout),
+		Properties: amqp.Table{"connection_name": "reddit-monitor"},
+	})
+	if err != nil {
+		return err
+	}
+	channel, err := conn.Channel()
+	if err == nil {
+		err = channel.Confirm(false)
+	}
+	if err == nil {
+		err = channel.ExchangeDeclare(amqpExchange, amqpExchangeType, true, false, false, false, nil)
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	p.conn, p.settings = &amqpConnection{conn: conn, channel: channel}, settings
+	return nil
+}
+
+// drop closes the connection, it is assumed broken or outdated. p.mu must be held.
+func (p *amqpPublisher) drop() {
+	if p.conn != nil {
+		p.conn.close()
+		p.conn, p.settings = nil, ""
+	}
+}
+
+// close closes the connection and stops reconnecting, on shutdown. A publish in flight finishes
+// first.
+func (p *amqpPublisher) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.conn != nil {
+		if err := p.conn.close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+			fmt.Printf("Error closing AMQP connection: %v\n", err)
+		}
+		p.conn = nil
+	}
+}
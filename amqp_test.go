@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestAMQPRoutingKey(t *testing.T) {
+	tests := []struct {
+		template string
+		alert    Alert
+		want     string
+	}{
+		{"match.{subreddit}.{keyword}", Alert{Subreddit: "WholesalingHouses", Keywords: []string{"VA"}}, "match.wholesalinghouses.va"},
+		{"match.{subreddit}.{keyword}", Alert{Subreddit: "golang", Keywords: []string{"cold call [title, case-sensitive]", "leads"}}, "match.golang.cold_call"},
+		{"match.{subreddit}.{keyword}", Alert{Subreddit: "golang", Keywords: []string{`phone = "555.1234"`}}, "match.golang.phone"},
+		{"{subreddit}", Alert{Subreddit: "golang"}, "golang"},
+		{"match.{subreddit}.{keyword}", Alert{Subreddit: "golang"}, "match.golang."},
+	}
+	for _, test := range tests {
+		if got := amqpRoutingKey(test.template, test.alert); got != test.want {
+			t.Errorf("amqpRoutingKey(%q, %v %v) = %q, want %q", test.template, test.alert.Subreddit, test.alert.Keywords, got, test.want)
+		}
+	}
+}
+
+// amqpPublish is a publish the fake channel received
+type amqpPublish struct {
+	exchange, routingKey string
+	msg                  amqp.Publishing
+}
+
+// fakeAMQPChannel keeps what is published on it, answering with the acks and errs in turn (ack by default)
+type fakeAMQPChannel struct {
+	published []amqpPublish
+	acks      []bool
+	errs      []error
+	closed    bool
+}
+
+func (c *fakeAMQPChannel) publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (bool, error) {
+	c.published = append(c.published, amqpPublish{exchange: exchange, routingKey: routingKey, msg: msg})
+	acked, err := true, error(nil)
+	if len(c.acks) > 0 {
+		acked, c.acks = c.acks[0], c.acks[1:]
+	}
+	if len(c.errs) > 0 {
+		err, c.errs = c.errs[0], c.errs[1:]
+	}
+	return acked, err
+}
+
+func (c *fakeAMQPChannel) isClosed() bool { return c.closed }
+
+func (c *fakeAMQPChannel) close() error {
+	c.closed = true
+	return nil
+}
+
+// fakeAMQPNotifier returns an AMQPNotifier publishing on channel, as if connected with the current
+// settings. It doesn't reconnect after a failure.
+func fakeAMQPNotifier(channel *fakeAMQPChannel) (*AMQPNotifier, *amqpPublisher) {
+	publisher := &amqpPublisher{conn: channel, settings: amqpSettings(), reconnecting: true}
+	return &AMQPNotifier{publisher: publisher}, publisher
+}
+
+func TestAMQPNotifyAlertMessage(t *testing.T) {
+	channel := &fakeAMQPChannel{}
+	notifier, _ := fakeAMQPNotifier(channel)
+	alert := Alert{
+		Kind:      "comment",
+		Subreddit: "WholesalingHouses",
+		Permalink: "/r/WholesalingHouses/comments/1/a/c2/",
+		Keywords:  []string{"cold call [title, case-sensitive]", "VA"},
+		Priority:  "normal",
+	}
+	if err := notifier.NotifyAlert(alert); err != nil {
+		t.Fatalf("NotifyAlert: %v", err)
+	}
+	if len(channel.published) != 1 {
+		t.Fatalf("%d message(s) published, want 1", len(channel.published))
+	}
+	published := channel.published[0]
+	if published.exchange != amqpExchange || published.routingKey != "match.wholesalinghouses.cold_call" {
+		t.Errorf("published to %s with key %s, want %s with match.wholesalinghouses.cold_call", published.exchange, published.routingKey, amqpExchange)
+	}
+	if published.msg.ContentType != "application/json" || published.msg.DeliveryMode != amqp.Persistent {
+		t.Errorf("content type %q, delivery mode %d, want persistent JSON", published.msg.ContentType, published.msg.DeliveryMode)
+	}
+	var event MatchEvent
+	if err := json.Unmarshal(published.msg.Body, &event); err != nil {
+		t.Fatalf("body isn't a JSON match event: %v", err)
+	}
+	if event.SchemaVersion != matchEventSchemaVersion || event.Kind != "comment" || event.Permalink != alert.Permalink || !slices.Equal(event.Keywords, alert.Keywords) {
+		t.Errorf("event = %+v, want the alert's at schema version %d", event, matchEventSchemaVersion)
+	}
+}
+
+func TestAMQPPublishFailures(t *testing.T) {
+	withFastRetries(t)
+	tests := []struct {
+		name          string
+		channel       *fakeAMQPChannel
+		wantPublishes int
+		wantErr       bool
+		wantDropped   bool
+	}{
+		{"nack then ack", &fakeAMQPChannel{acks: []bool{false, true}}, 2, false, false},
+		{"always nacked", &fakeAMQPChannel{acks: []bool{false, false, false, false}}, retryAttempts, true, false},
+		{"channel error", &fakeAMQPChannel{errs: []error{errors.New("channel closed")}}, 1, true, true},
+	}
+	for _, tt := range tests {
+		notifier, publisher := fakeAMQPNotifier(tt.channel)
+		err := notifier.NotifyAlert(Alert{Subreddit: "golang", Keywords: []string{"VA"}})
+		if len(tt.channel.published) != tt.wantPublishes || (err != nil) != tt.wantErr {
+			t.Errorf("%s: %d publish(es), error %v, want %d and error %v", tt.name, len(tt.channel.published), err, tt.wantPublishes, tt.wantErr)
+		}
+		if dropped := tt.channel.closed && publisher.conn == nil; dropped != tt.wantDropped {
+			t.Errorf("%s: connection dropped %v, want %v", tt.name, dropped, tt.wantDropped)
+		}
+	}
+}
+
+func TestAMQPPublishDropsOutdatedConnection(t *testing.T) {
+	channel := &fakeAMQPChannel{}
+	_, publisher := fakeAMQPNotifier(channel)
+	publisher.settings = "amqp://old-broker reddit_monitor topic" // Config reload changed the broker
+
+	err := publisher.publish("match.golang.va", []byte("{}"))
+	var retryable *retryableError
+	if !errors.As(err, &retryable) || !channel.closed || publisher.conn != nil || len(channel.published) != 0 {
+		t.Errorf("publish = %v with connection closed %v, want the old connection dropped and a retryable error", err, channel.closed)
+	}
+}
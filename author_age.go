@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// --- Author Account Age ---
+
+// minAuthorAge skips matches by accounts younger than this (0 disables the check)
+var minAuthorAge time.Duration
+
+// Author age cache limits: entries expire after a day, and the oldest is evicted beyond the cap
+const (
+	authorAgeTTL        = 24 * time.Hour
+	authorAgeMaxEntries = 10000
+)
+
+// UserAboutResponse is the part of /user/<name>/about.json used to get the account age
+type UserAboutResponse struct {
+	Data struct {
+		CreatedUtc float64 `json:"created_utc"`
+	} `json:"data"`
+}
+
+// authorAgeEntry is a cached account creation time
+type authorAgeEntry struct {
+	created   time.Time
+	fetchedAt time.Time
+}
+
+// authorAgeCache remembers account creation times so each author is looked up at most once a day
+type authorAgeCache struct {
+	mu      sync.Mutex
+	entries map[string]authorAgeEntry
+}
+
+// authorAges is the shared cache of account creation times
+var authorAges = &authorAgeCache{entries: map[string]authorAgeEntry{}}
+
+// get returns the cached creation time of author, if fresh.
+func (c *authorAgeCache) get(author string, now time.Time) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[author]
+	if !ok || now.Sub(entry.fetchedAt) >= authorAgeTTL {
+		return time.Time{}, false
+	}
+	return entry.created, true
+}
+
+// put caches the creation time of author. When the cache is full, expired entries are dropped
+// and, if that frees nothing, the oldest entry is evicted.
+func (c *authorAgeCache) put(author string, created, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[author]; !ok && len(c.entries) >= authorAgeMaxEntries {
+		oldest, oldestAt := "", now
+		for name, entry := range c.entries {
+			if now.Sub(entry.fetchedAt) >= authorAgeTTL {
+				delete(c.entries, name)
+			} else if entry.fetchedAt.Before(oldestAt) {
+				oldest, oldestAt = name, entry.fetchedAt
+			}
+		}
+		if len(c.entries) >= authorAgeMaxEntries {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[author] = authorAgeEntry{created: created, fetchedAt: now}
+}
+
+// fetchAuthorCreated retrieves when author's account was created from their about page.
+func (c *RedditClient) fetchAuthorCreated(ctx context.Context, author string) (time.Time, error) {
+	endpoint := "https://www.reddit.com/user/" + url.PathEscape(author) + "/about.json"
+	about, err := getRedditJSON[UserAboutResponse](ctx, c, endpoint)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if about.Data.CreatedUtc == 0 {
+		return time.Time{}, fmt.Errorf("no created_utc for u/%s (suspended?)", author)
+	}
+	return createdTime(about.Data.CreatedUtc), nil
+}
+
+// authorTooNew reports whether author's account is younger than min_author_age_days. Deleted
+// authors and failed lookups are let through, so Reddit errors never drop matches.
+func authorTooNew(author string) bool {
+	if minAuthorAge <= 0 || author == "" || author == "[deleted]" {
+		return false
+	}
+	now := time.Now()
+	created, ok := authorAges.get(author, now)
+	if !ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		var err error
+		created, err = redditClient.fetchAuthorCreated(ctx, author)
+		if err != nil {
+			fmt.Printf("WARN: Could not look up the account age of u/%s, not filtering it: %v\n", author, err)
+			return false
+		}
+		authorAges.put(author, created, now)
+	}
+	return now.Sub(created) < minAuthorAge
+}
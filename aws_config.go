@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// --- AWS SDK ---
+
+// loadAWSConfig loads the AWS SDK config: the region and the SDK's default credential chain
+// (environment, shared files, SSO, the ECS and EC2 roles). Requests use the proxy of httpTransport,
+// see configureProxy.
+func loadAWSConfig(ctx context.Context) (aws.Config, error) {
+	client := awshttp.NewBuildableClient().WithTransportOptions(func(transport *http.Transport) {
+		transport.Proxy = httpTransport.Proxy
+	})
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithHTTPClient(client))
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("error loading AWS config: %w", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// --- Historical Backfill ---
+
+// backfillsCollectionName holds one document per subreddit that has been backfilled
+const backfillsCollectionName = "backfills"
+
+// BackfillRecord marks a subreddit as backfilled so restarts skip it
+type BackfillRecord struct {
+	Subreddit    string    `bson:"subreddit"` // Lowercased, subreddit names are case-insensitive
+	Pages        int       `bson:"pages"`     // Pages actually fetched
+	BackfilledAt time.Time `bson:"backfilled_at"`
+}
+
+// backfillSubreddits backfills every subreddit with backfill_pages that has no backfill record yet.
+// Failures are logged and retried on the next start, since no record is written for them.
+func backfillSubreddits(ctx context.Context, backfills *mongo.Collection, store Store, notifier Notifier) {
+	for _, cfg := range subredditConfigs {
+		if cfg.BackfillPages == 0 || ctx.Err() != nil {
+			continue
+		}
+		key := strings.ToLower(cfg.Name)
+
+		ctxFind, cancelFind := context.WithTimeout(ctx, 5*time.Second)
+		err := backfills.FindOne(ctxFind, map[string]interface{}{"subreddit": key}).Err()
+		cancelFind()
+		if err == nil {
+			continue // Already backfilled
+		}
+		if err != mongo.ErrNoDocuments {
+			fmt.Printf("Error checking backfill status of r/%s: %v\n", cfg.Name, err)
+			continue
+		}
+
+		pages, err := backfillSubreddit(ctx, cfg, store, notifier)
+		if err != nil {
+			fmt.Printf("Error backfilling r/%s after %d page(s), will retry on next start: %v\n", cfg.Name, pages, err)
+			continue
+		}
+
+		ctxInsert, cancelInsert := context.WithTimeout(ctx, 5*time.Second)
+		_, err = backfills.InsertOne(ctxInsert, BackfillRecord{Subreddit: key, Pages: pages, BackfilledAt: time.Now()})
+		cancelInsert()
+		if err != nil {
+			fmt.Printf("Error recording backfill of r/%s: %v\n", cfg.Name, err)
+			continue
+		}
+		fmt.Printf("Backfilled r/%s: %d page(s).\n", cfg.Name, pages)
+	}
+}
+
+// withQueryParam returns endpoint with the query parameter key set to value.
+func withQueryParam(endpoint, key, value string) string {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	query := parsed.Query()
+	query.Set(key, value)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// backfillSubreddit walks up to cfg.BackfillPages pages of the new listing using after tokens,
+// processing each page through the normal pipeline. Returns the number of pages fetched.
+func backfillSubreddit(ctx context.Context, cfg SubredditConfig, store Store, notifier Notifier) (int, error) {
+	fmt.Printf("Backfilling up to %d page(s) of r/%s...\n", cfg.BackfillPages, cfg.Name)
+	after := ""
+	for page := 0; page < cfg.BackfillPages; page++ {
+		if err := ctx.Err(); err != nil {
+			return page, err
+		}
+		endpoint := listingEndpoint(cfg.Name, "new")
+		if cfg.isMultireddit() {
+			endpoint = cfg.MultiredditURL
+		}
+		if after != "" {
+			endpoint = withQueryParam(endpoint, "after", after)
+		}
+		posts, next, err := redditClient.fetchPostsPage(ctx, endpoint)
+		if err != nil {
+			return page, err
+		}
+		for i := range posts {
+			posts[i].Listing = "backfill"
+			if cfg.isMultireddit() {
+				posts[i].Subreddit = cfg.Name
+			}
+		}
+		processPosts(store, notifier, currentMatchRules(), posts)
+
+		if next == "" {
+			return page + 1, nil // Reached the end of the listing
+		}
+		after = next
+	}
+	return cfg.BackfillPages, nil
+}
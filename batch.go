@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// --- Per-Cycle Notification Batching ---
+
+// batchNotifications holds back a cycle's alerts and sends them as one notification per channel
+var batchNotifications = false
+
+// BatchNotifier is a Notifier that sends the alerts of a batched cycle itself, such as one event
+// per match, instead of the combined message
+type BatchNotifier interface {
+	Notifier
+	NotifyBatch(subject, body string, alerts []Alert) error
+}
+
+// notifyBatch sends the combined message of alerts through notifier, or the alerts themselves if
+// it takes them.
+func notifyBatch(notifier Notifier, subject, body string, alerts []Alert) (err error) {
+	defer recoverPanic(fmt.Sprintf("notifier %T", notifier), &err)
+	if batchNotifier, ok := notifier.(BatchNotifier); ok {
+		return batchNotifier.NotifyBatch(subject, body, alerts)
+	}
+	return notifier.Notify(subject, body)
+}
+
+// batchNotifier collects alerts instead of sending them, flush sends them through notifier at once
+type batchNotifier struct {
+	notifier Notifier
+	profile  string // Leads the combined subject, "" for the top-level config
+	mu       sync.Mutex
+	alerts   []batchedAlert
+}
+
+// batchedAlert is a queued alert with the match it is for, recorded once the batch is sent.
+// Alerts queued through Notify have no store.
+type batchedAlert struct {
+	alert Alert
+	store Store
+	rules matchRules
+	item  ProcessedItem
+}
+
+// newBatchNotifier returns a batchNotifier sending the alerts of profile through notifier.
+func newBatchNotifier(notifier Notifier, profile string) *batchNotifier {
+	return &batchNotifier{notifier: notifier, profile: profile}
+}
+
+// Notify queues the message until flush.
+func (b *batchNotifier) Notify(subject, body string) error {
+	return b.NotifyAlert(Alert{Subject: subject, Body: body})
+}
+
+// NotifyAlert queues the alert until flush. High priority alerts are sent at once.
+func (b *batchNotifier) NotifyAlert(alert Alert) error {
+	if alert.KeywordPriority == "high" {
+		return notifyAlert(b.notifier, alert)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.alerts = append(b.alerts, batchedAlert{alert: alert})
+	return nil
+}
+
+// queueMatch queues the alert for item, which flush records in store once the batch is sent.
+// An item already queued this cycle, such as a post found by both a listing and a search, is
+// only queued once.
+func (b *batchNotifier) queueMatch(store Store, rules matchRules, item ProcessedItem, alert Alert) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, queued := range b.alerts {
+		if queued.store != nil && queued.item.Permalink == item.Permalink {
+			return
+		}
+	}
+	threadAlerts.record(rules.profile, item.Subreddit, item.Permalink, item.Keywords)
+	b.alerts = append(b.alerts, batchedAlert{alert: alert, store: store, rules: rules, item: item})
+}
+
+// flush sends the queued alerts as one notification, e.g. "Reddit Keyword Alert: 5 new matches",
+// and empties the queue. A single alert is sent as is, an empty queue sends nothing. Channels that
+// send events rather than messages get the alerts themselves, see BatchNotifier.
+// Matched items are recorded once the batch is sent. If it fails, every item goes through
+// notificationFailed, like an alert that failed on its own.
+func (b *batchNotifier) flush() error {
+	b.mu.Lock()
+	queued := b.alerts
+	b.alerts = nil
+	b.mu.Unlock()
+
+	err := b.send(queued)
+	for _, q := range queued {
+		switch {
+		case q.store == nil:
+		case err != nil:
+			notificationFailed(q.store, q.rules, q.item, q.alert, err)
+		default:
+			markItem(q.store, q.item)
+		}
+	}
+	return err
+}
+
+// send sends the queued alerts through the notifier, combined if there is more than one.
+func (b *batchNotifier) send(queued []batchedAlert) error {
+	switch len(queued) {
+	case 0:
+		return nil
+	case 1:
+		return notifyAlert(b.notifier, queued[0].alert)
+	}
+	alerts := make([]Alert, len(queued))
+	var body strings.Builder
+	for i, q := range queued {
+		alerts[i] = q.alert
+		if i > 0 {
+			body.WriteString("\n\n")
+		}
+		fmt.Fprintf(&body, "%d. %s\n%s", i+1, q.alert.Subject, q.alert.Body)
+	}
+	subject := fmt.Sprintf("Reddit Keyword Alert: %d new matches", len(alerts))
+	if b.profile != "" {
+		subject = "[" + b.profile + "] " + subject
+	}
+	return notifyBatch(b.notifier, subject, body.String(), alerts)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// --- Bolt Store ---
+
+// boltBucket holds the processed items, keyed by permalink
+var boltBucket = []byte("processed_items")
+
+// boltIndexBucket indexes the items of boltBucket that aren't duplicates by content hash and by
+// post name, for FindDuplicate. Keys are "<field>\x00<value>\x00<processed at><permalink>" with
+// empty values, so the entries of a hash or post sort oldest first.
+var boltIndexBucket = []byte("processed_items_index")
+
+// boltIndexTime formats processed times in index keys: fixed width in UTC, so keys sort by time
+const boltIndexTime = "20060102150405.000000000"
+
+// boltStoreCleanupInterval is how often expired items are deleted
+const boltStoreCleanupInterval = time.Hour
+
+// BoltStore is a Store keeping every item as JSON in a bbolt database file, for deployments
+// without MongoDB for processed items. bbolt syncs every write transaction to disk.
+type BoltStore struct {
+	db  *bolt.DB
+	ttl time.Duration
+}
+
+// NewBoltStore opens the bbolt database at path, creating it and its bucket if needed.
+func NewBoltStore(path string, ttl time.Duration) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening bolt store: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		items, err := tx.CreateBucketIfNotExists(boltBucket)
+		if err != nil || tx.Bucket(boltIndexBucket) != nil {
+			return err
+		}
+		index, err := tx.CreateBucket(boltIndexBucket)
+		if err != nil {
+			return err
+		}
+		// A database written before the index existed: index the items it holds
+		return items.ForEach(func(key, value []byte) error {
+			var item ProcessedItem
+			if json.Unmarshal(value, &item) != nil {
+				return nil // Deleted by the next cleanup
+			}
+			return indexBoltItem(index, item)
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating bolt bucket: %w", err)
+	}
+	return &BoltStore{db: db, ttl: ttl}, nil
+}
+
+// boltIndexPrefix returns the prefix of the index keys of field ("hash" or "post") and value.
+func boltIndexPrefix(field, value string) []byte {
+	return []byte(field + "\x00" + value + "\x00")
+}
+
+// boltIndexKeys returns the index keys of item, none for duplicates: notes go to the match that alerted.
+func boltIndexKeys(item ProcessedItem) [][]byte {
+	if item.DuplicateOf != "" {
+		return nil
+	}
+	suffix := item.ProcessedAt.UTC().Format(boltIndexTime) + item.Permalink
+	keys := [][]byte{}
+	if item.ContentHash != "" {
+		keys = append(keys, append(boltIndexPrefix("hash", item.ContentHash), suffix...))
+	}
+	if item.PostName != "" {
+		keys = append(keys, append(boltIndexPrefix("post", item.PostName), suffix...))
+	}
+	return keys
+}
+
+// indexBoltItem adds the index keys of item to index.
+func indexBoltItem(index *bolt.Bucket, item ProcessedItem) error {
+	for _, key := range boltIndexKeys(item) {
+		if err := index.Put(key, []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firstIndexed returns the first item indexed under prefix from the key from on, nil if there is none.
+func firstIndexed(tx *bolt.Tx, prefix, from []byte) *ProcessedItem {
+	items := tx.Bucket(boltBucket)
+	cursor := tx.Bucket(boltIndexBucket).Cursor()
+	for key, _ := cursor.Seek(from); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		value := items.Get(key[len(prefix)+len(boltIndexTime):])
+		if value == nil {
+			continue // Left behind by a cleanup that couldn't decode the item
+		}
+		var item ProcessedItem
+		if json.Unmarshal(value, &item) == nil {
+			return &item
+		}
+	}
+	return nil
+}
+
+// Has reports whether the permalink is a key of the bucket.
+func (s *BoltStore) Has(ctx context.Context, permalink string) (bool, error) {
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket(boltBucket).Get([]byte(permalink)) != nil
+		return nil
+	})
+	return found, err
+}
+
+// Mark stores the item as JSON under its permalink, returning ErrAlreadyProcessed if the key exists.
+func (s *BoltStore) Mark(ctx context.Context, item ProcessedItem) error {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		if bucket.Get([]byte(item.Permalink)) != nil {
+			return ErrAlreadyProcessed
+		}
+		if err := bucket.Put([]byte(item.Permalink), value); err != nil {
+			return err
+		}
+		return indexBoltItem(tx.Bucket(boltIndexBucket), item)
+	})
+}
+
+// FindDuplicate looks up the content hash and post name in the index bucket, see Store.
+func (s *BoltStore) FindDuplicate(ctx context.Context, contentHash, parentName string, since time.Time) (*ProcessedItem, error) {
+	var earliest *ProcessedItem
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if contentHash != "" {
+			prefix := boltIndexPrefix("hash", contentHash)
+			earliest = firstIndexed(tx, prefix, append(prefix, since.UTC().Format(boltIndexTime)...))
+		}
+		if parentName != "" {
+			prefix := boltIndexPrefix("post", parentName)
+			if item := firstIndexed(tx, prefix, prefix); item != nil && (earliest == nil || item.ProcessedAt.Before(earliest.ProcessedAt)) {
+				earliest = item
+			}
+		}
+		return nil
+	})
+	return earliest, err
+}
+
+// AddAlsoPostedIn appends note to the item's AlsoPostedIn, once.
+func (s *BoltStore) AddAlsoPostedIn(ctx context.Context, permalink, note string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		value := bucket.Get([]byte(permalink))
+		if value == nil {
+			return nil
+		}
+		var item ProcessedItem
+		if err := json.Unmarshal(value, &item); err != nil {
+			return err
+		}
+		if slices.Contains(item.AlsoPostedIn, note) {
+			return nil
+		}
+		item.AlsoPostedIn = append(item.AlsoPostedIn, note)
+		updated, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(permalink), updated)
+	})
+}
+
+// cleanup deletes the items processed longer than the TTL ago with their index keys, and values
+// that don't decode. It returns the number deleted.
+func (s *BoltStore) cleanup() (int, error) {
+	cutoff := time.Now().Add(-s.ttl)
+	deleted := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, index := tx.Bucket(boltBucket), tx.Bucket(boltIndexBucket)
+		expired, expiredIndex := [][]byte{}, [][]byte{}
+		cursor := bucket.Cursor()
+		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+			var item ProcessedItem
+			if err := json.Unmarshal(value, &item); err != nil {
+				fmt.Printf("WARN: Deleting malformed bolt store item %s\n", key)
+			} else if !item.ProcessedAt.Before(cutoff) {
+				continue
+			}
+			expired = append(expired, slices.Clone(key)) // Keys are only valid in the transaction
+			expiredIndex = append(expiredIndex, boltIndexKeys(item)...)
+		}
+		// Deleting while the cursor moves would skip keys
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		for _, key := range expiredIndex {
+			if err := index.Delete(key); err != nil {
+				return err
+			}
+		}
+		deleted = len(expired)
+		return nil
+	})
+	return deleted, err
+}
+
+// runCompaction deletes expired items every boltStoreCleanupInterval until ctx is done.
+func (s *BoltStore) runCompaction(ctx context.Context) {
+	ticker := time.NewTicker(boltStoreCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.cleanup()
+			if err != nil {
+				fmt.Printf("Error cleaning up bolt store %s: %v\n", s.db.Path(), err)
+			} else if deleted > 0 {
+				fmt.Printf("Deleted %d expired item(s) from bolt store %s\n", deleted, s.db.Path())
+			}
+		}
+	}
+}
+
+// close closes the database, on shutdown.
+func (s *BoltStore) close() {
+	if err := s.db.Close(); err != nil {
+		fmt.Printf("Error closing bolt store: %v\n", err)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// --- Circuit Breakers ---
+
+// breakerThreshold is how many consecutive failures open a circuit
+const breakerThreshold = 5
+
+// breakerBaseCooldown is the first cool-down of an open circuit, doubled every time a probe fails
+const breakerBaseCooldown = 1 * time.Minute
+
+// breakerMaxCooldown caps the cool-down
+const breakerMaxCooldown = 30 * time.Minute
+
+// ErrCircuitOpen is returned instead of fetching while a circuit is open
+var ErrCircuitOpen = errors.New("circuit open, skipping request")
+
+// Circuit states
+const (
+	circuitClosed   = "closed"    // Requests go through
+	circuitOpen     = "open"      // Requests are skipped until the cool-down ends
+	circuitHalfOpen = "half-open" // One probe request is in flight
+)
+
+// circuitBreaker stops requests to an endpoint type after breakerThreshold consecutive failures.
+// After the cool-down a single probe is let through: success closes the circuit, failure reopens
+// it with twice the cool-down.
+type circuitBreaker struct {
+	name     string
+	mu       sync.Mutex
+	state    string
+	failures int
+	cooldown time.Duration
+	openedAt time.Time
+}
+
+// BreakerStatus is a breaker's state as reported by the status endpoint
+type BreakerStatus struct {
+	Name     string     `json:"name"`
+	State    string     `json:"state"`
+	Failures int        `json:"consecutive_failures"`
+	RetryAt  *time.Time `json:"retry_at,omitempty"` // When an open circuit lets a probe through
+}
+
+// Posts and comments endpoints fail independently, so each has its own breaker
+var postsBreaker = newCircuitBreaker("posts")
+var commentsBreaker = newCircuitBreaker("comments")
+
+// newCircuitBreaker returns a closed breaker.
+func newCircuitBreaker(name string) *circuitBreaker {
+	return &circuitBreaker{name: name, state: circuitClosed, cooldown: breakerBaseCooldown}
+}
+
+// allow reports whether a request may be sent. An open circuit whose cool-down is over turns
+// half-open and allows exactly one probe.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitClosed:
+		return true
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = circuitHalfOpen
+		fmt.Printf("Info: %s circuit half-open, sending a probe request\n", b.name)
+		return true
+	default:
+		return false // A probe is already in flight
+	}
+}
+
+// record updates the breaker with the outcome of an allowed request.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		if b.state != circuitClosed {
+			fmt.Printf("Info: %s circuit closed, requests resume\n", b.name)
+		}
+		b.state, b.failures, b.cooldown = circuitClosed, 0, breakerBaseCooldown
+		return
+	}
+
+	b.failures++
+	switch {
+	case b.state == circuitHalfOpen:
+		b.cooldown = min(b.cooldown*2, breakerMaxCooldown)
+		b.open(err)
+	case b.state == circuitClosed && b.failures >= breakerThreshold:
+		b.open(err)
+	}
+}
+
+// open opens the circuit for the current cool-down. Callers hold b.mu.
+func (b *circuitBreaker) open(err error) {
+	b.state, b.openedAt = circuitOpen, time.Now()
+	fmt.Printf("WARN: %s circuit open after %d consecutive failure(s), skipping requests for %v (last error: %v)\n",
+		b.name, b.failures, b.cooldown, err)
+}
+
+// status returns the breaker's current state.
+func (b *circuitBreaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := BreakerStatus{Name: b.name, State: b.state, Failures: b.failures}
+	if b.state == circuitOpen {
+		retryAt := b.openedAt.Add(b.cooldown)
+		status.RetryAt = &retryAt
+	}
+	return status
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// --- Cron Scheduler ---
+
+// cronJobs runs every recurring task (daily digest, reports) on its cron schedule. It is started
+// in main and stopped on shutdown, waiting for running tasks.
+var cronJobs = newCron()
+
+// parseCronSchedule parses a standard 5-field cron expression such as "0 8 * * *" or "*/15 9-17 * * mon-fri",
+// or a descriptor such as "@daily". Times are local.
+func parseCronSchedule(spec string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+	}
+	return schedule, nil
+}
+
+// Cron runs named tasks on cron schedules, each firing in its own goroutine
+type Cron struct {
+	cron *cron.Cron
+}
+
+// newCron returns a stopped Cron without tasks.
+func newCron() *Cron {
+	return &Cron{cron: cron.New()}
+}
+
+// AddFunc registers task under name to run on the cron expression spec. Tasks can be added
+// before or after Start.
+func (c *Cron) AddFunc(name, spec string, task func()) error {
+	schedule, err := parseCronSchedule(spec)
+	if err != nil {
+		return err
+	}
+	c.cron.Schedule(schedule, cron.FuncJob(func() {
+		defer recoverPanic(name, nil)
+		task()
+	}))
+	if next := schedule.Next(time.Now()); !next.IsZero() {
+		fmt.Printf("Scheduled %s (%s), next run %s\n", name, spec, next.Format(time.RFC1123))
+	}
+	return nil
+}
+
+// Start runs the scheduler in the background. Starting a running Cron does nothing.
+func (c *Cron) Start() {
+	c.cron.Start()
+}
+
+// Stop stops scheduling tasks. The returned context is done once running tasks have finished.
+func (c *Cron) Stop() context.Context {
+	return c.cron.Stop()
+}
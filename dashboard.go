@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Dashboard ---
+
+// Dashboard page sizes
+const (
+	dashboardDefaultLimit = 50
+	dashboardMaxLimit     = 200
+)
+
+// requireDashboardAuth wraps a handler with the admin API token, sent either as a bearer token or,
+// so browsers can log in, as the basic auth password with any username.
+func requireDashboardAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			_, token, ok = r.BasicAuth()
+		}
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminAPIToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="rmonitor"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// matchQuery is a page of matches filtered by subreddit, keyword and date range, from the query string
+type matchQuery struct {
+	Subreddit string
+	Keyword   string
+	Search    string // Mongo text search over the stored titles and texts, results sorted by relevance
+	From      string // YYYY-MM-DD, local time, inclusive
+	To        string // YYYY-MM-DD, local time, inclusive
+	Page      int64
+	Limit     int64
+}
+
+// parseMatchQuery reads the filters and page of a /dashboard or /api/matches request.
+func parseMatchQuery(values url.Values) (matchQuery, error) {
+	q := matchQuery{
+		Subreddit: strings.TrimPrefix(strings.TrimSpace(values.Get("subreddit")), "r/"),
+		Keyword:   strings.TrimSpace(values.Get("keyword")),
+		Search:    strings.TrimSpace(values.Get("q")),
+		From:      values.Get("from"),
+		To:        values.Get("to"),
+		Page:      1,
+		Limit:     dashboardDefaultLimit,
+	}
+	for _, date := range []string{q.From, q.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.ParseInLocation(time.DateOnly, date, time.Local); err != nil {
+			return q, fmt.Errorf("invalid date %q, use YYYY-MM-DD", date)
+		}
+	}
+	if page := values.Get("page"); page != "" {
+		n, err := strconv.ParseInt(page, 10, 64)
+		if err != nil || n < 1 {
+			return q, fmt.Errorf("page must be a positive number")
+		}
+		q.Page = n
+	}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n < 1 || n > dashboardMaxLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", dashboardMaxLimit)
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
+
+// filter returns the Mongo filter of the query, the matches filter of the matches subcommand
+// bounded by the date range, and the text search if any.
+func (q matchQuery) filter() map[string]interface{} {
+	var since time.Time
+	if q.From != "" {
+		since, _ = time.ParseInLocation(time.DateOnly, q.From, time.Local)
+	}
+	filter := matchesFilter(since, q.Keyword, q.Subreddit)
+	if q.To != "" {
+		until, _ := time.ParseInLocation(time.DateOnly, q.To, time.Local)
+		filter["processed_at"] = map[string]interface{}{"$gte": since, "$lt": until.AddDate(0, 0, 1)}
+	}
+	if q.Search != "" {
+		filter["$text"] = map[string]interface{}{"$search": q.Search}
+	}
+	return filter
+}
+
+// values returns the query string of the query at page, for pagination links.
+func (q matchQuery) values(page int64) string {
+	values := url.Values{}
+	for key, value := range map[string]string{"subreddit": q.Subreddit, "keyword": q.Keyword, "q": q.Search, "from": q.From, "to": q.To} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	if q.Limit != dashboardDefaultLimit {
+		values.Set("limit", strconv.FormatInt(q.Limit, 10))
+	}
+	values.Set("page", strconv.FormatInt(page, 10))
+	return values.Encode()
+}
+
+// findMatches returns the page of matches selected by q, newest first or, when searching, most
+// relevant first, and the total number of matches. Search results carry a highlighted excerpt.
+func findMatches(ctx context.Context, q matchQuery) ([]ProcessedItem, int64, error) {
+	filter := q.filter()
+	total, err := processedItemsCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error counting matches: %w", err)
+	}
+	findOptions := matchesFindOptions(q.Search).
+		SetSkip((q.Page - 1) * q.Limit).
+		SetLimit(q.Limit)
+	cursor, err := processedItemsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying matches: %w", err)
+	}
+	items := []ProcessedItem{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, 0, fmt.Errorf("error decoding matches: %w", err)
+	}
+	if q.Search != "" {
+		highlightSearch(items, q.Search)
+	}
+	return items, total, nil
+}
+
+// MatchesResponse is the body of GET /api/matches
+type MatchesResponse struct {
+	Matches []ProcessedItem `json:"matches"`
+	Page    int64           `json:"page"`
+	Limit   int64           `json:"limit"`
+	Total   int64           `json:"total"`
+}
+
+// handleAPIMatches serves GET /api/matches?subreddit=&keyword=&q=&from=&to=&page=&limit=.
+func handleAPIMatches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if processedItemsCollection == nil {
+		http.Error(w, "MongoDB is not connected", http.StatusServiceUnavailable)
+		return
+	}
+	q, err := parseMatchQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	items, total, err := findMatches(ctx, q)
+	if err != nil {
+		fmt.Printf("Error serving /api/matches: %v\n", err)
+		http.Error(w, "failed to query matches", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, MatchesResponse{Matches: items, Page: q.Page, Limit: q.Limit, Total: total})
+}
+
+// highlightTerms returns the text to highlight for stored keyword labels: the text a pattern matched,
+// near terms, or the keyword without its options. Patterns that stored no text are skipped.
+func highlightTerms(labels []string) []string {
+	terms := []string{}
+	for _, label := range labels {
+		if _, matched, ok := strings.Cut(label, " = "); ok {
+			if unquoted, err := strconv.Unquote(matched); err == nil {
+				terms = append(terms, unquoted)
+			}
+			continue
+		}
+		if strings.HasPrefix(label, "re:") || strings.HasPrefix(label, domainMatchPrefix) || strings.HasPrefix(label, searchMatchPrefix) {
+			continue
+		}
+		if near, ok := strings.CutPrefix(label, "near("); ok {
+			near, _, _ = strings.Cut(near, ";")
+			terms = append(terms, strings.Split(near, ", ")...)
+			continue
+		}
+		keyword, _, _ := strings.Cut(label, " [")
+		terms = append(terms, keyword)
+	}
+	return terms
+}
+
+// highlightKeywords escapes text and wraps the matched keywords in <mark>.
+func highlightKeywords(text string, labels []string) template.HTML {
+	return template.HTML(wrapKeywords(text, labels, "mark"))
+}
+
+// wrapKeywords escapes text as HTML and wraps the matched keywords in the element tag, e.g. "b".
+func wrapKeywords(text string, labels []string, tag string) string {
+	alternatives := []string{}
+	for _, term := range highlightTerms(labels) {
+		if term = strings.TrimSpace(term); term != "" {
+			alternatives = append(alternatives, regexp.QuoteMeta(term))
+		}
+	}
+	if len(alternatives) == 0 {
+		return html.EscapeString(text)
+	}
+	re, err := regexp.Compile("(?i)" + strings.Join(alternatives, "|"))
+	if err != nil {
+		return html.EscapeString(text)
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:loc[0]]))
+		b.WriteString("<" + tag + ">" + html.EscapeString(text[loc[0]:loc[1]]) + "</" + tag + ">")
+		last = loc[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// dashboardTemplate renders the matches page
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"searchHighlight": func(item ProcessedItem) template.HTML {
+		return template.HTML(item.Highlight) // Escaped by searchExcerpt
+	},
+	"highlight": func(item ProcessedItem) template.HTML {
+		text := item.Snippet
+		if item.Snapshot != nil && item.Snapshot.Text != "" {
+			text = item.Snapshot.Text // Complete, also after the item is deleted
+		}
+		if text == "" {
+			text = item.Title
+		}
+		return highlightKeywords(text, item.Keywords)
+	},
+	"title": func(item ProcessedItem) string {
+		if item.Snapshot != nil && item.Snapshot.Title != "" {
+			return item.Snapshot.Title
+		}
+		if item.Title != "" {
+			return item.Title
+		}
+		return item.Permalink
+	},
+	"join": strings.Join,
+	"time": func(t time.Time) string { return t.Local().Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Reddit Monitor - matches</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+form { margin-bottom: 1.5em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; vertical-align: top; padding: 0.5em; border-bottom: 1px solid #ddd; }
+.meta { color: #666; font-size: 0.9em; }
+.error { color: #b00; }
+mark { background: #ffe066; }
+.status-removed, .status-deleted { color: #b00; }
+.text { white-space: pre-wrap; max-height: 12em; overflow-y: auto; }
+</style>
+</head>
+<body>
+<h1>Recent matches</h1>
+<form method="get" action="/dashboard">
+  <label>Subreddit <input name="subreddit" value="{{.Query.Subreddit}}"></label>
+  <label>Keyword <input name="keyword" value="{{.Query.Keyword}}"></label>
+  <label>Search <input name="q" value="{{.Query.Search}}"></label>
+  <label>From <input type="date" name="from" value="{{.Query.From}}"></label>
+  <label>To <input type="date" name="to" value="{{.Query.To}}"></label>
+  <button type="submit">Filter</button>
+  <a href="/dashboard">Clear</a>
+</form>
+{{if .Error}}<p class="error">{{.Error}}</p>{{else}}
+<p class="meta">{{.Total}} match(es), page {{.Query.Page}} of {{.Pages}}</p>
+<table>
+<tr><th>When</th><th>Subreddit</th><th>Match</th><th>Keywords</th><th>Status</th></tr>
+{{range .Matches}}<tr>
+  <td class="meta">{{time .ProcessedAt}}</td>
+  <td>r/{{.Subreddit}}</td>
+  <td><a href="https://www.reddit.com{{.Permalink}}">{{title .}}</a> <span class="meta">{{.Kind}}{{if .Snapshot}} by u/{{.Snapshot.Author}}{{end}}</span><br><div class="text">{{if .Highlight}}{{searchHighlight .}}{{else}}{{highlight .}}{{end}}</div></td>
+  <td>{{join .Keywords ", "}}</td>
+  <td class="status-{{.Status}}">{{if .Status}}{{.Status}}{{if .Score}} <span class="meta">{{.Score}} points</span>{{end}}{{else}}<span class="meta">unchecked</span>{{end}}</td>
+</tr>{{else}}<tr><td colspan="5">No matches.</td></tr>{{end}}
+</table>
+<p>{{if .Prev}}<a href="/dashboard?{{.Prev}}">&larr; Newer</a>{{end}} {{if .Next}}<a href="/dashboard?{{.Next}}">Older &rarr;</a>{{end}}</p>
+{{end}}
+</body>
+</html>
+`))
+
+// dashboardPage is the data of the dashboard template
+type dashboardPage struct {
+	Query   matchQuery
+	Matches []ProcessedItem
+	Total   int64
+	Pages   int64
+	Prev    template.URL // Query string of the newer page, empty on the first
+	Next    template.URL // Query string of the older page, empty on the last
+	Error   string
+}
+
+// handleDashboard serves GET /dashboard, the matches page.
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	page := dashboardPage{}
+	q, err := parseMatchQuery(r.URL.Query())
+	page.Query = q
+	switch {
+	case err != nil:
+		page.Error = err.Error()
+	case processedItemsCollection == nil:
+		page.Error = "MongoDB is not connected, no matches to show."
+	default:
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		page.Matches, page.Total, err = findMatches(ctx, q)
+		if err != nil {
+			fmt.Printf("Error serving /dashboard: %v\n", err)
+			page.Error = "Failed to query matches."
+		}
+	}
+	page.Pages = max(1, (page.Total+q.Limit-1)/q.Limit)
+	if q.Page > 1 {
+		page.Prev = template.URL(q.values(q.Page - 1))
+	}
+	if q.Page < page.Pages {
+		page.Next = template.URL(q.values(q.Page + 1))
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, page); err != nil {
+		fmt.Printf("Error rendering dashboard: %v\n", err)
+	}
+}
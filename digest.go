@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Daily Digest ---
+
+// dailyDigestEnabled turns on the daily summary email. It is sent on dailyDigestSchedule (a cron
+// expression), or at dailyDigestTime (local time, HH:MM) when no schedule is set.
+var dailyDigestEnabled = false
+var dailyDigestTime = "08:00"
+var dailyDigestSchedule = ""
+
+// digestCronSpec returns the cron expression the digest is sent on.
+func digestCronSpec() (string, error) {
+	if dailyDigestSchedule != "" {
+		return dailyDigestSchedule, nil
+	}
+	t, err := time.Parse("15:04", dailyDigestTime)
+	if err != nil {
+		return "", fmt.Errorf("invalid daily digest time %q, use HH:MM", dailyDigestTime)
+	}
+	return fmt.Sprintf("%d %d * * *", t.Minute(), t.Hour()), nil
+}
+
+// DigestScheduler sends a summary of the past 24 hours of processed items on its schedule
+type DigestScheduler struct {
+	spec       string
+	collection *mongo.Collection // processed_items, the notification history
+	notifier   Notifier
+}
+
+// NewDigestScheduler returns a digest sent on the cron expression spec, once registered.
+func NewDigestScheduler(spec string, collection *mongo.Collection, notifier Notifier) (*DigestScheduler, error) {
+	if _, err := parseCronSchedule(spec); err != nil {
+		return nil, fmt.Errorf("invalid daily digest schedule: %w", err)
+	}
+	return &DigestScheduler{spec: spec, collection: collection, notifier: notifier}, nil
+}
+
+// Register schedules the digest with c.
+func (d *DigestScheduler) Register(c *Cron) error {
+	return c.AddFunc("daily digest", d.spec, d.fire)
+}
+
+// fire sends the digest.
+func (d *DigestScheduler) fire() {
+	if err := d.send(time.Now()); err != nil {
+		fmt.Printf("Error sending daily digest: %v\n", err)
+	}
+}
+
+// send queries the items processed in the 24 hours before now and emails the digest.
+func (d *DigestScheduler) send(now time.Time) error {
+	filter := map[string]interface{}{
+		"processed_at": map[string]interface{}{"$gte": now.Add(-24 * time.Hour)},
+		"skipped":      map[string]interface{}{"$exists": false}, // Old items were never matched
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cursor, err := d.collection.Find(ctx, filter, options.Find().SetSort(map[string]interface{}{"processed_at": 1}))
+	if err != nil {
+		return fmt.Errorf("error querying processed items: %w", err)
+	}
+	var items []ProcessedItem
+	if err := cursor.All(ctx, &items); err != nil {
+		return fmt.Errorf("error decoding processed items: %w", err)
+	}
+
+	matches := 0
+	for _, item := range items {
+		if !item.NearMiss && item.DuplicateOf == "" {
+			matches++
+		}
+	}
+	subject := fmt.Sprintf("Reddit Keyword Daily Digest: %d match(es) on %s", matches, now.Format("2006-01-02"))
+	return d.notifier.Notify(subject, formatDigest(items))
+}
+
+// formatDigest builds the digest body: match counts per keyword and subreddit, then a section per
+// subreddit listing its matches and near misses. Suppressed duplicates are left out.
+func formatDigest(items []ProcessedItem) string {
+	if len(items) == 0 {
+		return "No matches in the past 24 hours."
+	}
+
+	// Group items by subreddit, and count matches per keyword and subreddit
+	bySubreddit := map[string][]ProcessedItem{}
+	keywordCounts := map[string]map[string]int{}
+	for _, item := range items {
+		if item.DuplicateOf != "" {
+			continue
+		}
+		bySubreddit[item.Subreddit] = append(bySubreddit[item.Subreddit], item)
+		if item.NearMiss {
+			continue
+		}
+		for _, keyword := range item.Keywords {
+			if keywordCounts[keyword] == nil {
+				keywordCounts[keyword] = map[string]int{}
+			}
+			keywordCounts[keyword][item.Subreddit]++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("Matches per keyword in the past 24 hours:\n")
+	for _, keyword := range sortedKeys(keywordCounts) {
+		total := 0
+		perSubreddit := []string{}
+		for _, subreddit := range sortedKeys(keywordCounts[keyword]) {
+			count := keywordCounts[keyword][subreddit]
+			total += count
+			perSubreddit = append(perSubreddit, fmt.Sprintf("r/%s %d", subreddit, count))
+		}
+		fmt.Fprintf(&b, "  %s: %d (%s)\n", keyword, total, strings.Join(perSubreddit, ", "))
+	}
+
+	for _, subreddit := range sortedKeys(bySubreddit) {
+		fmt.Fprintf(&b, "\nr/%s\n", subreddit)
+		nearMisses := []ProcessedItem{}
+		for _, item := range bySubreddit[subreddit] {
+			if item.NearMiss {
+				nearMisses = append(nearMisses, item)
+				continue
+			}
+			fmt.Fprintf(&b, "  %v https://www.reddit.com%s", item.Keywords, item.Permalink)
+			if item.KeywordPriority == "low" {
+				b.WriteString(" (low priority, not alerted)")
+			}
+			b.WriteString("\n")
+			if len(item.AlsoPostedIn) > 0 {
+				fmt.Fprintf(&b, "    also posted in %s\n", strings.Join(item.AlsoPostedIn, ", "))
+			}
+		}
+		if len(nearMisses) > 0 {
+			b.WriteString("  Near misses:\n")
+			for _, item := range nearMisses {
+				fmt.Fprintf(&b, "  %v https://www.reddit.com%s\n", item.Keywords, item.Permalink)
+			}
+		}
+	}
+	return b.String()
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
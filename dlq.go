@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Notification Dead Letter Queue ---
+
+// notificationDLQCollectionName holds alerts that kept failing, until drain-dlq sends them
+const notificationDLQCollectionName = "notification_dlq"
+
+// notificationDLQ is the dead letter collection, nil when not connected (failed items then retry forever)
+var notificationDLQ *mongo.Collection
+
+// maxNotificationRetries is how often a queued notification is retried before it is dead-lettered
+var maxNotificationRetries = 3
+
+// DeadLetter is an alert that failed max_retries times, stored in notification_dlq
+type DeadLetter struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Permalink string             `bson:"permalink"`
+	Subreddit string             `bson:"subreddit"`
+	Kind      string             `bson:"kind"`
+	Profile   string             `bson:"profile,omitempty"` // Profile whose notifier failed, empty for the top-level config
+	Alert     Alert              `bson:"alert"`
+	Channels  []string           `bson:"channels,omitempty"` // Channels that failed, all if empty, see channelName
+	Error     string             `bson:"error"`              // Last failure
+	Failures  int                `bson:"failures"`
+	FailedAt  time.Time          `bson:"failed_at"`
+}
+
+// deadLetter moves a queued notification that failed for the last time to notification_dlq and
+// flags its processed item as dead-lettered.
+func deadLetter(ctx context.Context, queued QueuedNotification, err error) error {
+	if notificationDLQ == nil {
+		return fmt.Errorf("%s is not connected", notificationDLQCollectionName)
+	}
+	letter := DeadLetter{
+		Permalink: queued.Permalink,
+		Subreddit: queued.Subreddit,
+		Kind:      queued.Kind,
+		Profile:   queued.Profile,
+		Alert:     queued.Alert,
+		Channels:  queued.Channels,
+		Error:     err.Error(),
+		Failures:  queued.RetryCount + 1, // The first attempt and every retry
+		FailedAt:  time.Now(),
+	}
+	if _, insertErr := notificationDLQ.InsertOne(ctx, letter); insertErr != nil {
+		return insertErr
+	}
+	fmt.Printf("WARN: Notification for %s failed %d times on %s, moved to %s (run drain-dlq once the notifier is fixed): %v\n",
+		queued.Permalink, letter.Failures, channelList(letter.Channels), notificationDLQCollectionName, err)
+	if processedItemsCollection != nil {
+		filter := map[string]interface{}{"permalink": queued.Permalink, "profile": profileFilter(queued.Profile)}
+		_, updateErr := processedItemsCollection.UpdateOne(ctx, filter,
+			map[string]interface{}{"$set": map[string]interface{}{"dead_lettered": true}})
+		if updateErr != nil {
+			fmt.Printf("Error flagging processed item %s as dead-lettered: %v\n", queued.Permalink, updateErr)
+		}
+	}
+	return nil
+}
+
+// dlqNotifier returns the notifier a dead letter is sent through: its profile's, or the top-level one.
+func dlqNotifier(profile string) Notifier {
+	for i := range profiles {
+		if profile != "" && profiles[i].Name == profile {
+			return profiles[i].notifier()
+		}
+	}
+	return withAlertChannels(newNotifier(recipientEmail), topLevelAlertChannels())
+}
+
+// runDrainDLQ sends the alerts in notification_dlq again through the channels they failed on, oldest
+// first, and removes those that were sent. Alerts that still fail stay queued with the new error and
+// the channels still failing. Returns the process exit code.
+func runDrainDLQ(args []string) int {
+	fs := flag.NewFlagSet("drain-dlq", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only list the queued alerts, don't send them")
+	limit := fs.Int("limit", 0, "send at most this many alerts (0 for all)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := loadConfig(); err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return 1
+	}
+	if *dryRun {
+		if mongoURI == "" {
+			fmt.Println("FATAL: MONGODB_URI environment variable must be set.")
+			return 1
+		}
+	} else if err := checkEnv(); err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return 1
+	}
+
+	client, err := connectMongo()
+	if err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return 1
+	}
+	defer client.Disconnect(context.Background())
+	defer smtpConn.close()
+	collection := client.Database(mongoDatabaseName).Collection(notificationDLQCollectionName)
+	processed := client.Database(mongoDatabaseName).Collection(processedItemsCollectionName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	findOptions := options.Find().SetSort(map[string]interface{}{"failed_at": 1})
+	if *limit > 0 {
+		findOptions.SetLimit(int64(*limit))
+	}
+	cursor, err := collection.Find(ctx, map[string]interface{}{}, findOptions)
+	if err != nil {
+		fmt.Printf("Error querying %s: %v\n", notificationDLQCollectionName, err)
+		return 1
+	}
+	defer cursor.Close(ctx)
+
+	sent, failed := 0, 0
+	for cursor.Next(ctx) {
+		var letter DeadLetter
+		if err := cursor.Decode(&letter); err != nil {
+			fmt.Printf("Error decoding dead letter: %v\n", err)
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("%s  %s in r/%s, failed %d times on %s: %s\n  https://www.reddit.com%s\n",
+				letter.FailedAt.Local().Format(time.DateTime), letter.Kind, letter.Subreddit, letter.Failures,
+				channelList(letter.Channels), letter.Error, letter.Permalink)
+			continue
+		}
+
+		if err := notifyAlertChannels(dlqNotifier(letter.Profile), letter.Alert, letter.Channels); err != nil {
+			failed++
+			still := mergeFailures(splitFailure(err))
+			fmt.Printf("Error sending %s on %s, keeping it queued: %v\n", letter.Permalink, channelList(still.channels), err)
+			ctxUpdate, cancelUpdate := context.WithTimeout(ctx, 5*time.Second)
+			_, updateErr := collection.UpdateByID(ctxUpdate, letter.ID, map[string]interface{}{
+				"$set": map[string]interface{}{"error": err.Error(), "failed_at": time.Now(), "channels": still.channels},
+				"$inc": map[string]interface{}{"failures": 1},
+			})
+			cancelUpdate()
+			if updateErr != nil {
+				fmt.Printf("Error updating dead letter %s: %v\n", letter.Permalink, updateErr)
+			}
+			continue
+		}
+		sent++
+
+		ctxUpdate, cancelUpdate := context.WithTimeout(ctx, 5*time.Second)
+		if _, err := collection.DeleteOne(ctxUpdate, map[string]interface{}{"_id": letter.ID}); err != nil {
+			fmt.Printf("Error removing sent dead letter %s: %v\n", letter.Permalink, err)
+		}
+		if _, err := processed.UpdateMany(ctxUpdate,
+			map[string]interface{}{"permalink": letter.Permalink, "dead_lettered": true},
+			map[string]interface{}{"$unset": map[string]interface{}{"dead_lettered": ""}}); err != nil {
+			fmt.Printf("Error updating processed item %s: %v\n", letter.Permalink, err)
+		}
+		cancelUpdate()
+	}
+	if err := cursor.Err(); err != nil {
+		fmt.Printf("Error reading %s: %v\n", notificationDLQCollectionName, err)
+		return 1
+	}
+
+	if *dryRun {
+		return 0
+	}
+	fmt.Printf("Drained %s: %d sent, %d still failing.\n", notificationDLQCollectionName, sent, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
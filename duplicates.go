@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Duplicate Comment Detection ---
+
+// commentHashesCollectionName stores body hashes of recent comments, expired by a TTL index
+const commentHashesCollectionName = "comment_hashes"
+
+// duplicateCommentWindow is how long a comment body is remembered
+const duplicateCommentWindow = 24 * time.Hour
+
+// duplicateMaxDistance is the most differing simhash bits for two bodies to count as duplicates.
+// 6 of 64 bits keeps them over 90% similar.
+const duplicateMaxDistance = 6
+
+// duplicateMinWords skips short bodies ("thanks!", "following"), which are too alike to compare
+const duplicateMinWords = 5
+
+// defaultBotAccounts are skipped in every subreddit, in addition to the configured bot_accounts
+var defaultBotAccounts = []string{
+	"AutoModerator", "RepostSleuthBot", "RemindMeBot", "sneakpeekbot", "WikiSummarizerBot",
+	"SaveVideo", "savevideobot", "AmputatorBot", "B0tRank", "haikusbot",
+}
+
+// botAccounts are the configured bot accounts, skipped alongside defaultBotAccounts
+var botAccounts = []string{}
+
+// isBotAccount reports whether author is a known bot.
+func isBotAccount(author string) bool {
+	return containsFold(defaultBotAccounts, author) || containsFold(botAccounts, author)
+}
+
+// CommentHash is a recent comment's body hash, stored so restarts keep the duplicate window
+type CommentHash struct {
+	Permalink string    `bson:"permalink"`
+	Hash      int64     `bson:"hash"` // simhash of the normalized body, signed for BSON
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+// commentDeduper remembers simhashes of recent comment bodies, in memory and optionally in MongoDB.
+type commentDeduper struct {
+	mu         sync.Mutex
+	recent     []CommentHash
+	collection *mongo.Collection // nil keeps hashes in memory only
+}
+
+// duplicateComments is the shared deduper, nil when the heuristic is disabled
+var duplicateComments *commentDeduper
+
+// duplicateCommentCheck enables skipping comments nearly identical to one seen in the last 24 hours
+var duplicateCommentCheck = true
+
+// newCommentDeduper returns a deduper persisting to collection (if not nil), loading the hashes
+// of the last window and ensuring the TTL index that expires them.
+func newCommentDeduper(collection *mongo.Collection) *commentDeduper {
+	d := &commentDeduper{collection: collection}
+	if collection == nil {
+		return d
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	indexModel := mongo.IndexModel{
+		Keys:    map[string]interface{}{"created_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(int32(duplicateCommentWindow.Seconds())),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, indexModel); err != nil {
+		fmt.Printf("WARN: Could not create TTL index on %s: %v\n", commentHashesCollectionName, err)
+	}
+
+	cursor, err := collection.Find(ctx, map[string]interface{}{
+		"created_at": map[string]interface{}{"$gte": time.Now().Add(-duplicateCommentWindow)},
+	})
+	if err != nil {
+		fmt.Printf("WARN: Could not load recent comment hashes: %v\n", err)
+		return d
+	}
+	if err := cursor.All(ctx, &d.recent); err != nil {
+		fmt.Printf("WARN: Could not load recent comment hashes: %v\n", err)
+	}
+	return d
+}
+
+// seen reports whether the comment's body is a near duplicate of another comment seen within
+// the window, and remembers it either way. The same comment fetched again is not a duplicate.
+func (d *commentDeduper) seen(comment Comment) bool {
+	words := strings.Fields(strings.ToLower(foldText(normalizeText(comment.Body))))
+	if len(words) < duplicateMinWords {
+		return false
+	}
+	entry := CommentHash{Permalink: comment.Permalink, Hash: int64(simhash(words)), CreatedAt: time.Now()}
+
+	d.mu.Lock()
+	cutoff := entry.CreatedAt.Add(-duplicateCommentWindow)
+	kept := d.recent[:0]
+	duplicate, known := false, false
+	for _, recent := range d.recent {
+		if recent.CreatedAt.Before(cutoff) {
+			continue // Expired
+		}
+		kept = append(kept, recent)
+		if recent.Permalink == entry.Permalink {
+			known = true
+		} else if bits.OnesCount64(uint64(recent.Hash^entry.Hash)) <= duplicateMaxDistance {
+			duplicate = true
+		}
+	}
+	if !known {
+		kept = append(kept, entry)
+	}
+	d.recent = kept
+	d.mu.Unlock()
+
+	if d.collection != nil && !known {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := d.collection.InsertOne(ctx, entry); err != nil {
+			fmt.Printf("WARN: Could not store comment hash: %v\n", err)
+		}
+	}
+	return duplicate
+}
+
+// simhash computes a 64-bit similarity hash over word pairs, so bodies that share most of
+// their text differ in few bits.
+func simhash(words []string) uint64 {
+	var weights [64]int
+	for i := range words {
+		shingle := words[i]
+		if i+1 < len(words) {
+			shingle += " " + words[i+1]
+		}
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// --- Duplicate Post Detection ---
+
+// contentHash hashes the post's lowercased, whitespace-collapsed title and selftext, so the same
+// text posted to several subreddits hashes the same.
+func (p Post) contentHash() string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(p.Title+"\n"+p.Selftext), " "))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// findDuplicatePost returns the earlier match this post duplicates: the same content within
+// duplicatePostWindow, or the original of a crosspost. Lookup errors are logged and treated as no duplicate.
+func findDuplicatePost(store Store, post Post) *ProcessedItem {
+	parentName := ""
+	if post.CrosspostParentID != "" {
+		parentName = "t3_" + post.CrosspostParentID
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	original, err := store.FindDuplicate(ctx, post.contentHash(), parentName, time.Now().Add(-duplicatePostWindow))
+	if err != nil {
+		fmt.Printf("Error checking store for duplicates of %s: %v\n", post.Permalink, err)
+		return nil
+	}
+	return original
+}
+
+// --- Thread Notification Window ---
+
+// dedupWindow suppresses repeat notifications of a keyword in the same thread (0 disables)
+var dedupWindow time.Duration
+
+// threadAlerts remembers when each subreddit+keyword+post was last notified
+var threadAlerts = &threadAlertWindow{sent: map[string]time.Time{}}
+
+// threadAlertWindow is an in-memory map of notification keys to send times, entries expire after dedupWindow.
+// Unlike processed_items it doesn't stop items being matched, only repeat notifications.
+type threadAlertWindow struct {
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+// threadPostID extracts the post ID from a post or comment permalink, /r/<sub>/comments/<post id>/...
+func threadPostID(permalink string) string {
+	parts := strings.Split(strings.Trim(permalink, "/"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "comments" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// threadAlertKeys returns the dedup keys of the matched keywords, nil if the thread is unknown.
+// Profiles alert independently, so their keys are prefixed with the profile name.
+func threadAlertKeys(profile, subreddit, permalink string, found []string) []string {
+	postID := threadPostID(permalink)
+	if postID == "" {
+		return nil
+	}
+	keys := make([]string, 0, len(found))
+	for _, keyword := range found {
+		key := strings.ToLower(subreddit) + "+" + keyword + "+" + postID
+		if profile != "" {
+			key = profile + "/" + key
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// suppress reports whether every matched keyword was already notified in this thread within the window.
+func (w *threadAlertWindow) suppress(profile, subreddit, permalink string, found []string) bool {
+	keys := threadAlertKeys(profile, subreddit, permalink, found)
+	if dedupWindow <= 0 || len(keys) == 0 {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	for key, sentAt := range w.sent {
+		if now.Sub(sentAt) >= dedupWindow {
+			delete(w.sent, key)
+		}
+	}
+	for _, key := range keys {
+		if _, ok := w.sent[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// record notes a sent notification for the matched keywords.
+func (w *threadAlertWindow) record(profile, subreddit, permalink string, found []string) {
+	if dedupWindow <= 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	for _, key := range threadAlertKeys(profile, subreddit, permalink, found) {
+		w.sent[key] = now
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// --- DynamoDB Store ---
+
+// dynamoDBTableName is the DynamoDB table processed items are kept in instead of MongoDB, when set
+var dynamoDBTableName = os.Getenv("DYNAMODB_TABLE_NAME")
+
+// dynamoDBEndpoint overrides the regional endpoint, e.g. http://localhost:8000 for DynamoDB Local
+var dynamoDBEndpoint = os.Getenv("DYNAMODB_ENDPOINT")
+
+// dynamoDBTTL is how long items live, DynamoDB deletes them after their expires_at
+var dynamoDBTTL = time.Duration(envInt("DYNAMODB_TTL_DAYS", 30)) * 24 * time.Hour
+
+// dynamoStartupTimeout bounds checking, creating and waiting for the table at startup
+const dynamoStartupTimeout = 2 * time.Minute
+
+// Indexes of the table for duplicate detection, keyed by content_hash and post_name with
+// processed_at as sort key. Both are sparse: only items with the attribute are in them.
+const (
+	dynamoContentHashIndex = "content_hash-processed_at"
+	dynamoPostNameIndex    = "post_name-processed_at"
+)
+
+// DynamoStore is a Store keeping items in a DynamoDB table keyed by permalink, through the AWS SDK
+// with its default credential chain.
+type DynamoStore struct {
+	table   string
+	ttl     time.Duration
+	client  *dynamodb.Client
+	indexed bool // The table has the duplicate detection indexes
+}
+
+// dynamoString returns a string attribute.
+func dynamoString(s string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: s}
+}
+
+// dynamoNumber returns a number attribute.
+func dynamoNumber(n int64) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(n, 10)}
+}
+
+// dynamoStr returns the string attribute name of item, "" if missing.
+func dynamoStr(item map[string]types.AttributeValue, name string) string {
+	if attr, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return attr.Value
+	}
+	return ""
+}
+
+// NewDynamoStore returns a DynamoStore on table, creating the table with TTL on expires_at if it
+// doesn't exist.
+func NewDynamoStore(table string, ttl time.Duration) (*DynamoStore, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoStartupTimeout)
+	defer cancel()
+	cfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Region == "" && dynamoDBEndpoint == "" {
+		return nil, fmt.Errorf("AWS_REGION must be set to use DYNAMODB_TABLE_NAME")
+	}
+	client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		if dynamoDBEndpoint != "" {
+			o.BaseEndpoint = aws.String(dynamoDBEndpoint)
+		}
+		if o.Region == "" {
+			o.Region = "us-east-1" // Only signed over, local endpoints ignore it
+		}
+	})
+	s := &DynamoStore{table: table, ttl: ttl, client: client}
+	if err := s.ensureTable(ctx); err != nil {
+		return nil, fmt.Errorf("error setting up DynamoDB table %s: %w", table, err)
+	}
+	return s, nil
+}
+
+// ensureTable creates the table if it doesn't exist, waits for it to be active and enables TTL.
+func (s *DynamoStore) ensureTable(ctx context.Context) error {
+	_, err := s.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(s.table)})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		fmt.Printf("Creating DynamoDB table %s...\n", s.table)
+		err = s.createTable(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	described, err := dynamodb.NewTableExistsWaiter(s.client).WaitForOutput(ctx,
+		&dynamodb.DescribeTableInput{TableName: aws.String(s.table)}, dynamoStartupTimeout)
+	if err != nil {
+		return fmt.Errorf("table not active: %w", err)
+	}
+
+	indexes := map[string]bool{}
+	for _, index := range described.Table.GlobalSecondaryIndexes {
+		indexes[aws.ToString(index.IndexName)] = true
+	}
+	s.indexed = indexes[dynamoContentHashIndex] && indexes[dynamoPostNameIndex]
+	if !s.indexed {
+		fmt.Printf("WARN: DynamoDB table %s lacks the %s and %s indexes, duplicate posts won't be detected\n",
+			s.table, dynamoContentHashIndex, dynamoPostNameIndex)
+	}
+	return s.ensureTTL(ctx)
+}
+
+// createTable creates the table, billed per request, with the duplicate detection indexes.
+func (s *DynamoStore) createTable(ctx context.Context) error {
+	index := func(name, hashKey string) types.GlobalSecondaryIndex {
+		return types.GlobalSecondaryIndex{
+			IndexName: aws.String(name),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String(hashKey), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("processed_at"), KeyType: types.KeyTypeRange},
+			},
+			Projection: &types.Projection{
+				ProjectionType:   types.ProjectionTypeInclude,
+				NonKeyAttributes: []string{"subreddit", "duplicate_of"},
+			},
+		}
+	}
+	_, err := s.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String(s.table),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("permalink"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("content_hash"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("post_name"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("processed_at"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("permalink"), KeyType: types.KeyTypeHash}},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			index(dynamoContentHashIndex, "content_hash"),
+			index(dynamoPostNameIndex, "post_name"),
+		},
+	})
+	return err
+}
+
+// ensureTTL enables DynamoDB's TTL on expires_at unless it is already on.
+func (s *DynamoStore) ensureTTL(ctx context.Context) error {
+	described, err := s.client.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String(s.table)})
+	if err != nil {
+		return err
+	}
+	if ttl := described.TimeToLiveDescription; ttl != nil {
+		switch ttl.TimeToLiveStatus {
+		case types.TimeToLiveStatusEnabled, types.TimeToLiveStatusEnabling:
+			if name := aws.ToString(ttl.AttributeName); name != "expires_at" {
+				fmt.Printf("WARN: DynamoDB table %s expires items on %s, not expires_at, processed items won't expire\n", s.table, name)
+			}
+			return nil
+		case types.TimeToLiveStatusDisabling:
+			fmt.Printf("WARN: TTL of DynamoDB table %s is being disabled, processed items won't expire\n", s.table)
+			return nil
+		}
+	}
+	_, err = s.client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName:               aws.String(s.table),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{AttributeName: aws.String("expires_at"), Enabled: aws.Bool(true)},
+	})
+	return err
+}
+
+// Has reports whether an item with the permalink exists, with a strongly consistent read.
+func (s *DynamoStore) Has(ctx context.Context, permalink string) (bool, error) {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(s.table),
+		Key:                  map[string]types.AttributeValue{"permalink": dynamoString(permalink)},
+		ProjectionExpression: aws.String("permalink"),
+		ConsistentRead:       aws.Bool(true),
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(result.Item) > 0, nil
+}
+
+// Mark puts the item unless its permalink exists, returning ErrAlreadyProcessed then. The item is
+// stored as JSON, next to the attributes lookups and the TTL need.
+func (s *DynamoStore) Mark(ctx context.Context, item ProcessedItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	attrs := map[string]types.AttributeValue{
+		"permalink":    dynamoString(item.Permalink),
+		"subreddit":    dynamoString(item.Subreddit),
+		"processed_at": dynamoNumber(item.ProcessedAt.Unix()),
+		"expires_at":   dynamoNumber(time.Now().Add(s.ttl).Unix()),
+		"item":         dynamoString(string(data)),
+	}
+	// Empty strings can't be index keys, the attributes are left out instead
+	for name, value := range map[string]string{"content_hash": item.ContentHash, "post_name": item.PostName, "duplicate_of": item.DuplicateOf} {
+		if value != "" {
+			attrs[name] = dynamoString(value)
+		}
+	}
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.table),
+		Item:                attrs,
+		ConditionExpression: aws.String("attribute_not_exists(permalink)"),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrAlreadyProcessed
+	}
+	return err
+}
+
+// FindDuplicate queries the content_hash index within the window and the post_name index, and
+// returns the earliest item that isn't a duplicate itself, see Store.
+func (s *DynamoStore) FindDuplicate(ctx context.Context, contentHash, parentName string, since time.Time) (*ProcessedItem, error) {
+	if !s.indexed {
+		return nil, nil
+	}
+	var earliest *ProcessedItem
+	lookups := []struct {
+		index, key, value string
+		since             time.Time
+	}{
+		{dynamoContentHashIndex, "content_hash", contentHash, since},
+		{dynamoPostNameIndex, "post_name", parentName, time.Unix(0, 0)},
+	}
+	for _, lookup := range lookups {
+		if lookup.value == "" {
+			continue
+		}
+		item, err := s.queryEarliest(ctx, lookup.index, lookup.key, lookup.value, lookup.since)
+		if err != nil {
+			return nil, err
+		}
+		if item != nil && (earliest == nil || item.ProcessedAt.Before(earliest.ProcessedAt)) {
+			earliest = item
+		}
+	}
+	return earliest, nil
+}
+
+// queryEarliest returns the earliest item of index with key = value processed since since, skipping
+// duplicates. Only permalink, subreddit and processed_at are set, the attributes of the index.
+func (s *DynamoStore) queryEarliest(ctx context.Context, index, key, value string, since time.Time) (*ProcessedItem, error) {
+	pages := dynamodb.NewQueryPaginator(s.client, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String(index),
+		KeyConditionExpression: aws.String(key + " = :value AND processed_at >= :since"),
+		FilterExpression:       aws.String("attribute_not_exists(duplicate_of)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":value": dynamoString(value),
+			":since": dynamoNumber(since.Unix()),
+		},
+		ScanIndexForward: aws.Bool(true), // Oldest first
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// The filter applies after a page is read, a page can be empty with more to come
+		if len(page.Items) == 0 {
+			continue
+		}
+		found := page.Items[0]
+		item := &ProcessedItem{Permalink: dynamoStr(found, "permalink"), Subreddit: dynamoStr(found, "subreddit")}
+		if attr, ok := found["processed_at"].(*types.AttributeValueMemberN); ok {
+			seconds, _ := strconv.ParseInt(attr.Value, 10, 64)
+			item.ProcessedAt = time.Unix(seconds, 0).UTC()
+		}
+		return item, nil
+	}
+	return nil, nil
+}
+
+// AddAlsoPostedIn adds note to the item's also_posted_in string set. Missing items are left alone.
+func (s *DynamoStore) AddAlsoPostedIn(ctx context.Context, permalink, note string) error {
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(s.table),
+		Key:                       map[string]types.AttributeValue{"permalink": dynamoString(permalink)},
+		UpdateExpression:          aws.String("ADD also_posted_in :note"),
+		ConditionExpression:       aws.String("attribute_exists(permalink)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":note": &types.AttributeValueMemberSS{Value: []string{note}}},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+	return err
+}
+
+// Ping checks that the table answers, for the store outage buffer.
+func (s *DynamoStore) Ping(ctx context.Context) error {
+	_, err := s.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(s.table)})
+	return err
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// --- Email Messages and Providers ---
+
+// emailProvider picks the email delivery: smtp, sendgrid, mailgun or ses. Empty picks Mailgun when
+// mailgun_api_key is configured, SendGrid when SENDGRID_API_KEY is set and Gmail SMTP otherwise.
+var emailProvider = ""
+
+// validEmailProviders are the accepted values of email_provider
+var validEmailProviders = map[string]bool{"": true, "smtp": true, "sendgrid": true, "mailgun": true, "ses": true}
+
+// activeEmailProvider returns the provider alerts are emailed through.
+func activeEmailProvider() string {
+	switch {
+	case emailProvider != "":
+		return emailProvider
+	case mailgun.APIKey != "":
+		return "mailgun"
+	case sendgridAPIKey != "":
+		return "sendgrid"
+	}
+	return "smtp"
+}
+
+// checkEmailProvider verifies the credentials of the active provider are set.
+func checkEmailProvider() error {
+	switch activeEmailProvider() {
+	case "mailgun":
+		if mailgun.APIKey == "" {
+			return fmt.Errorf("mailgun_api_key must be set to send through Mailgun")
+		}
+		// The other Mailgun settings are validated with the config file
+	case "sendgrid":
+		if sendgridAPIKey == "" {
+			return fmt.Errorf("SENDGRID_API_KEY must be set to send through SendGrid")
+		}
+		if sendgridFrom == "" && gmailUser == "" {
+			return fmt.Errorf("SENDGRID_FROM (or GMAIL_USER) must be set to send through SendGrid")
+		}
+	case "ses":
+		return checkSESEnv()
+	default:
+		if gmailUser == "" || gmailAppPassword == "" {
+			return fmt.Errorf("email environment variables (GMAIL_USER, GMAIL_APP_PASSWORD, RECIPIENT_EMAIL) must be set")
+		}
+	}
+	return nil
+}
+
+// emailMessage is an alert email. Every provider builds it with newEmailMessage, so an alert
+// reads the same whichever way it is delivered.
+type emailMessage struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// newEmailMessage builds the email of an alert, with an HTML version of the plain text body.
+func newEmailMessage(from, to, subject, body string) emailMessage {
+	return emailMessage{From: from, To: to, Subject: subject, Text: body, HTML: htmlBody(body)}
+}
+
+// smtpData formats the plain text message for SMTP (RFC 822 style).
+func (m emailMessage) smtpData() []byte {
+	// Note: Ensure correct line endings (\r\n) for email headers/body separation.
+	return []byte("To: " + m.To + "\r\n" +
+		"Subject: " + m.Subject + "\r\n" +
+		"\r\n" + // Empty line separates headers from body
+		m.Text + "\r\n")
+}
+
+// mimeData formats the message for SMTP as multipart/alternative, plain text first and HTML second.
+func (m emailMessage) mimeData() []byte {
+	var b strings.Builder
+	writer := multipart.NewWriter(&b)
+	b.WriteString("To: " + m.To + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", m.Subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary() + "\r\n" +
+		"\r\n")
+	for _, part := range []struct{ contentType, content string }{{"text/plain", m.Text}, {"text/html", m.HTML}} {
+		w, _ := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		qp := quotedprintable.NewWriter(w)
+		qp.Write([]byte(part.content))
+		qp.Close()
+	}
+	writer.Close()
+	return []byte(b.String())
+}
+
+// urlPattern finds links in alert bodies
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// htmlBody renders a plain text alert as HTML, keeping line breaks and making links clickable.
+func htmlBody(body string) string {
+	escaped := html.EscapeString(body)
+	linked := urlPattern.ReplaceAllStringFunc(escaped, func(link string) string {
+		return `<a href="` + link + `">` + link + `</a>`
+	})
+	return strings.ReplaceAll(linked, "\n", "<br>\n")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// --- Conditional Listing Requests ---
+
+// listingETags holds the ETag of every listing endpoint, so unchanged listings come back as a 304
+// instead of the full payload. Endpoints are keyed by URL, so a reload keeping a subreddit's
+// endpoint keeps its ETag; swapConfig clears the cache when the subreddit list changes.
+var listingETags = &etagCache{tags: map[string]string{}}
+
+// etagCache maps endpoints to the ETag of their last response
+type etagCache struct {
+	mu   sync.Mutex
+	tags map[string]string
+}
+
+// get returns the ETag stored for endpoint, or "".
+func (c *etagCache) get(endpoint string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tags[endpoint]
+}
+
+// set stores the ETag of endpoint, "" forgets it.
+func (c *etagCache) set(endpoint, etag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if etag == "" {
+		delete(c.tags, endpoint)
+		return
+	}
+	c.tags[endpoint] = etag
+}
+
+// clear forgets every ETag.
+func (c *etagCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tags = map[string]string{}
+}
+
+// getListingJSON is getRedditJSON sending the endpoint's stored ETag. On a 304 it logs "not modified"
+// and returns modified false, so the caller skips the listing; otherwise the new ETag is stored.
+func getListingJSON[T any](ctx context.Context, c *RedditClient, endpoint string) (result T, modified bool, err error) {
+	resp, err := c.redditGetIfNoneMatch(ctx, endpoint, listingETags.get(endpoint))
+	if err != nil {
+		return result, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		fmt.Printf("Listing %s not modified, skipping\n", endpoint)
+		return result, false, nil
+	}
+	result, err = decodeRedditJSON[T](resp, endpoint)
+	if err != nil {
+		return result, false, err
+	}
+	listingETags.set(endpoint, resp.Header.Get("ETag"))
+	return result, true, nil
+}
+
+// monitoredSubredditNames lists the subreddits of the top-level config and of every profile,
+// for telling whether a reload changed them. Callers hold configMu.
+func monitoredSubredditNames() string {
+	names := append([]string{}, subreddits...)
+	for _, profile := range profiles {
+		for _, sub := range profile.Subreddits {
+			names = append(names, profile.Name+"/"+sub.Name)
+		}
+	}
+	sort.Strings(names)
+	return strings.ToLower(strings.Join(names, ","))
+}
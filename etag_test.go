@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListingETagIsSentBackAndNotModifiedSkipped(t *testing.T) {
+	oldETags := listingETags
+	listingETags = &etagCache{tags: map[string]string{}}
+	t.Cleanup(func() { listingETags = oldETags })
+
+	ifNoneMatch := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"children": [{"kind": "t3", "data": {"title": "VA leads", "permalink": "/r/golang/comments/1/a/", "name": "t3_1"}}]}}`))
+	}))
+	defer server.Close()
+	client := testRedditClient(server.Client())
+	endpoint := server.URL + "/r/golang/new.json"
+
+	posts, _, _, err := client.getPostListing(context.Background(), endpoint)
+	if err != nil || len(posts) != 1 {
+		t.Fatalf("first fetch = %d post(s), %v, want the listing", len(posts), err)
+	}
+	if got := listingETags.get(endpoint); got != `"v1"` {
+		t.Errorf("stored ETag = %q, want the response's", got)
+	}
+
+	posts, _, _, err = client.getPostListing(context.Background(), endpoint)
+	if err != nil || len(posts) != 0 {
+		t.Errorf("fetch of an unchanged listing = %d post(s), %v, want it skipped", len(posts), err)
+	}
+	if len(ifNoneMatch) != 2 || ifNoneMatch[0] != "" || ifNoneMatch[1] != `"v1"` {
+		t.Errorf("If-None-Match headers = %q, want none, then the stored ETag", ifNoneMatch)
+	}
+	if got := listingETags.get(endpoint); got != `"v1"` {
+		t.Errorf("ETag after a 304 = %q, want it kept", got)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// --- Export Subcommand ---
+
+// exportFlushRows is how many rows are written between flushes, so large exports stream
+const exportFlushRows = 1000
+
+// exportColumns is the CSV header
+var exportColumns = []string{"timestamp", "subreddit", "type", "keywords", "title", "permalink", "author", "score", "text"}
+
+// runExport streams the matches processed between --from and --to (inclusive days, local time)
+// to stdout or --out as CSV or JSON lines. Items are read through a cursor and never held
+// in memory all at once. Returns the process exit code.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	from := fs.String("from", "", "first day to export, YYYY-MM-DD (required)")
+	to := fs.String("to", "", "last day to export, YYYY-MM-DD (default today)")
+	format := fs.String("format", "csv", "output format: csv or jsonl")
+	out := fs.String("out", "", "file to write, default stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "csv" && *format != "jsonl" {
+		fmt.Printf("Invalid --format %q, must be csv or jsonl\n", *format)
+		return 2
+	}
+	start, err := time.ParseInLocation("2006-01-02", *from, time.Local)
+	if err != nil {
+		fmt.Printf("Invalid --from %q, use YYYY-MM-DD\n", *from)
+		return 2
+	}
+	end := time.Now()
+	if *to != "" {
+		day, err := time.ParseInLocation("2006-01-02", *to, time.Local)
+		if err != nil {
+			fmt.Printf("Invalid --to %q, use YYYY-MM-DD\n", *to)
+			return 2
+		}
+		end = day.AddDate(0, 0, 1) // Include the whole last day
+	}
+	if mongoURI == "" {
+		fmt.Println("FATAL: MONGODB_URI environment variable must be set.")
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Printf("Error creating %s: %v\n", *out, err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	client, err := connectMongo()
+	if err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return 1
+	}
+	defer client.Disconnect(context.Background())
+	collection := client.Database(mongoDatabaseName).Collection(processedItemsCollectionName,
+		options.Collection().SetReadPreference(readpref.SecondaryPreferred()))
+
+	filter := matchesFilter(start, "", "")
+	filter["processed_at"] = map[string]interface{}{"$gte": start, "$lt": end}
+	findOptions := options.Find().
+		SetSort(map[string]interface{}{"processed_at": 1}). // Oldest first
+		SetBatchSize(exportFlushRows)
+
+	ctx := context.Background() // No timeout, large exports take a while
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		fmt.Printf("Error querying matches: %v\n", err)
+		return 1
+	}
+	defer cursor.Close(ctx)
+
+	rows, err := writeExport(w, *format, func(item *ProcessedItem) (bool, error) {
+		if !cursor.Next(ctx) {
+			return false, cursor.Err()
+		}
+		return true, cursor.Decode(item)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error exporting matches after %d row(s): %v\n", rows, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d match(es).\n", rows)
+	return 0
+}
+
+// writeExport writes the items returned by next until it reports false, flushing every exportFlushRows
+// rows. Returns the number of rows written.
+func writeExport(w io.Writer, format string, next func(*ProcessedItem) (bool, error)) (int, error) {
+	buffered := bufio.NewWriter(w)
+	var csvWriter *csv.Writer
+	var encoder *json.Encoder
+	if format == "csv" {
+		csvWriter = csv.NewWriter(buffered) // Quotes fields with commas, quotes and newlines
+		if err := csvWriter.Write(exportColumns); err != nil {
+			return 0, err
+		}
+	} else {
+		encoder = json.NewEncoder(buffered)
+	}
+
+	flush := func() error {
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+		return buffered.Flush()
+	}
+
+	rows := 0
+	for {
+		var item ProcessedItem
+		ok, err := next(&item)
+		if err != nil {
+			return rows, err
+		}
+		if !ok {
+			break
+		}
+		if csvWriter != nil {
+			err = csvWriter.Write(exportRecord(item))
+		} else {
+			err = encoder.Encode(item)
+		}
+		if err != nil {
+			return rows, err
+		}
+		rows++
+		if rows%exportFlushRows == 0 {
+			if err := flush(); err != nil {
+				return rows, err
+			}
+		}
+	}
+	return rows, flush()
+}
+
+// exportRecord returns the CSV columns of an item, from its snapshot if it has one. Items stored
+// before titles were recorded fall back to a snippet of their full text, if that was stored.
+func exportRecord(item ProcessedItem) []string {
+	title, author, score, text := item.Title, item.Author, "", item.Snippet
+	if item.Snapshot != nil {
+		title, author, text = item.Snapshot.Title, item.Snapshot.Author, item.Snapshot.Text
+		score = strconv.Itoa(item.Snapshot.Score)
+	}
+	if title == "" && item.FullText != "" {
+		title = truncate(strings.Join(strings.Fields(item.FullText), " "), 200)
+	}
+	return []string{
+		item.ProcessedAt.Local().Format(time.RFC3339),
+		item.Subreddit,
+		item.Kind,
+		strings.Join(item.Keywords, "; "),
+		title,
+		"https://www.reddit.com" + item.Permalink,
+		author,
+		score,
+		text,
+	}
+}
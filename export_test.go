@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportItems returns a next function for writeExport going through items.
+func exportItems(items []ProcessedItem) func(*ProcessedItem) (bool, error) {
+	return func(item *ProcessedItem) (bool, error) {
+		if len(items) == 0 {
+			return false, nil
+		}
+		*item, items = items[0], items[1:]
+		return true, nil
+	}
+}
+
+func TestWriteExportCSVEscaping(t *testing.T) {
+	processedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	items := []ProcessedItem{
+		{
+			ProcessedAt: processedAt,
+			Subreddit:   "VirtualAssistants",
+			Kind:        "post",
+			Keywords:    []string{"VA", "leads"},
+			Title:       `Cheap "VA" leads, fast`,
+			Permalink:   "/r/VirtualAssistants/comments/1/cheap/",
+			Author:      "seller",
+		},
+		{
+			ProcessedAt: processedAt,
+			Subreddit:   "forhire",
+			Kind:        "comment",
+			Keywords:    []string{"VA"},
+			Title:       "Line one\nline two, with a comma\r\nand \"quotes\"",
+			Permalink:   "/r/forhire/comments/2/hiring/c3/",
+			Author:      "buyer",
+		},
+	}
+
+	var out bytes.Buffer
+	rows, err := writeExport(&out, "csv", exportItems(items))
+	if err != nil || rows != len(items) {
+		t.Fatalf("writeExport = %d, %v, want %d rows", rows, err, len(items))
+	}
+	if !strings.Contains(out.String(), `"Cheap ""VA"" leads, fast"`) {
+		t.Errorf("title with quotes and a comma isn't quoted and escaped:\n%s", out.String())
+	}
+
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("export isn't valid CSV: %v", err)
+	}
+	if len(records) != len(items)+1 || !slices.Equal(records[0], exportColumns) {
+		t.Fatalf("export has %d record(s), header %q, want a header and %d rows", len(records), records[0], len(items))
+	}
+	for i, item := range items {
+		record := records[i+1]
+		if len(record) != len(exportColumns) {
+			t.Errorf("row %d has %d fields, want %d", i, len(record), len(exportColumns))
+			continue
+		}
+		// encoding/csv reads \r\n inside quoted fields back as \n
+		if want := strings.ReplaceAll(item.Title, "\r\n", "\n"); record[4] != want {
+			t.Errorf("row %d title = %q, want %q", i, record[4], want)
+		}
+		if want := strings.Join(item.Keywords, "; "); record[3] != want {
+			t.Errorf("row %d keywords = %q, want %q", i, record[3], want)
+		}
+		if want := "https://www.reddit.com" + item.Permalink; record[5] != want {
+			t.Errorf("row %d permalink = %q, want %q", i, record[5], want)
+		}
+	}
+}
+
+func TestWriteExportJSONLines(t *testing.T) {
+	items := []ProcessedItem{
+		{Permalink: "/r/a/comments/1/", Title: "one\ntwo, \"three\""},
+		{Permalink: "/r/b/comments/2/", Title: "four"},
+	}
+	var out bytes.Buffer
+	rows, err := writeExport(&out, "json", exportItems(items))
+	if err != nil || rows != len(items) {
+		t.Fatalf("writeExport = %d, %v, want %d rows", rows, err, len(items))
+	}
+	scanner := bufio.NewScanner(&out)
+	lines := 0
+	for i := 0; scanner.Scan(); i++ {
+		lines++
+		var item ProcessedItem
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			t.Fatalf("line %d isn't JSON: %v", i, err)
+		}
+		if i >= len(items) || item.Title != items[i].Title {
+			t.Errorf("line %d = %+v, want %+v", i, item, items[min(i, len(items)-1)])
+		}
+	}
+	if lines != len(items) {
+		t.Errorf("export has %d line(s), want one per item", lines)
+	}
+}
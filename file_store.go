@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// --- File Store ---
+
+// storeFilePath is the newline-delimited JSON file processed items are kept in instead of
+// MongoDB's processed_items collection, when set
+var storeFilePath = os.Getenv("STORE_FILE_PATH")
+
+// storeFileTTL is how long processed items stay in the file, compaction drops older ones
+var storeFileTTL = time.Duration(envInt("STORE_FILE_TTL_DAYS", 30)) * 24 * time.Hour
+
+// fileStoreCompactInterval is how often the file is rewritten without expired items
+const fileStoreCompactInterval = time.Hour
+
+// fileStoreRef is what FindDuplicate needs to know of a stored item
+type fileStoreRef struct {
+	Permalink   string
+	Subreddit   string
+	ProcessedAt time.Time
+}
+
+// FileStore is a Store appending every item as a JSON line to a file, fsynced so a crash loses
+// nothing marked. The permalinks are indexed in memory, loaded from the file at startup, so
+// lookups never touch the disk.
+type FileStore struct {
+	mu     sync.Mutex
+	path   string
+	ttl    time.Duration
+	file   *os.File // Opened for appending, nil if reopening after a compaction failed
+	closed bool
+	index  map[string]struct{}
+	hashes map[string]fileStoreRef // Earliest non-duplicate item per content hash
+	posts  map[string]fileStoreRef // Non-duplicate item per post name
+}
+
+// NewFileStore opens the store at path, creating the file if needed, and loads its index.
+// Items older than ttl are skipped, the next compaction removes them from the file.
+func NewFileStore(path string, ttl time.Duration) (*FileStore, error) {
+	s := &FileStore{path: path, ttl: ttl}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	items, _, err := s.load(time.Now().Add(-ttl))
+	if err != nil {
+		s.file.Close()
+		return nil, err
+	}
+	s.reindex(items)
+	return s, nil
+}
+
+// open opens the file for appending. A last line cut short by a crash is truncated, so the
+// next item doesn't run into it.
+func (s *FileStore) open() error {
+	file, err := os.OpenFile(s.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening store file: %w", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error reading store file: %w", err)
+	}
+	if complete := bytes.LastIndexByte(data, '\n') + 1; complete < len(data) {
+		fmt.Printf("WARN: Store file %s ends with an incomplete line, dropping its %d bytes\n", s.path, len(data)-complete)
+		if err := file.Truncate(int64(complete)); err != nil {
+			file.Close()
+			return fmt.Errorf("error truncating store file: %w", err)
+		}
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return err
+	}
+	s.file = file
+	return nil
+}
+
+// load reads the items of the file processed since cutoff, and counts the lines dropped: older
+// items and lines that don't decode.
+func (s *FileStore) load(cutoff time.Time) ([]ProcessedItem, int, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading store file: %w", err)
+	}
+	defer file.Close()
+
+	items := []ProcessedItem{}
+	dropped := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // Items carry snapshots of long posts
+	for line := 1; scanner.Scan(); line++ {
+		var item ProcessedItem
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil || item.Permalink == "" {
+			fmt.Printf("WARN: Skipping malformed line %d of store file %s\n", line, s.path)
+			dropped++
+			continue
+		}
+		if item.ProcessedAt.Before(cutoff) {
+			dropped++
+			continue
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error reading store file: %w", err)
+	}
+	return items, dropped, nil
+}
+
+// reindex replaces the in-memory indexes with those of items.
+func (s *FileStore) reindex(items []ProcessedItem) {
+	s.index = make(map[string]struct{}, len(items))
+	s.hashes = map[string]fileStoreRef{}
+	s.posts = map[string]fileStoreRef{}
+	for _, item := range items {
+		s.indexItem(item)
+	}
+}
+
+// indexItem adds item to the indexes. s.mu must be held, or s not shared yet.
+func (s *FileStore) indexItem(item ProcessedItem) {
+	s.index[item.Permalink] = struct{}{}
+	if item.DuplicateOf != "" {
+		return // Notes go to the match that alerted
+	}
+	ref := fileStoreRef{Permalink: item.Permalink, Subreddit: item.Subreddit, ProcessedAt: item.ProcessedAt}
+	if item.ContentHash != "" {
+		if earliest, ok := s.hashes[item.ContentHash]; !ok || item.ProcessedAt.Before(earliest.ProcessedAt) {
+			s.hashes[item.ContentHash] = ref
+		}
+	}
+	if item.PostName != "" {
+		if _, ok := s.posts[item.PostName]; !ok {
+			s.posts[item.PostName] = ref
+		}
+	}
+}
+
+// Has reports whether the permalink is in the index.
+func (s *FileStore) Has(ctx context.Context, permalink string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.index[permalink]
+	return ok, nil
+}
+
+// Mark appends the item to the file and syncs it before indexing it, returning
+// ErrAlreadyProcessed if the permalink is indexed.
+func (s *FileStore) Mark(ctx context.Context, item ProcessedItem) error {
+	line, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.index[item.Permalink]; ok {
+		return ErrAlreadyProcessed
+	}
+	if s.file == nil {
+		return fmt.Errorf("store file %s is not open", s.path)
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing store file: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("error syncing store file: %w", err)
+	}
+	s.indexItem(item)
+	return nil
+}
+
+// FindDuplicate looks up the content hash and post name indexes, see Store.
+func (s *FileStore) FindDuplicate(ctx context.Context, contentHash, parentName string, since time.Time) (*ProcessedItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found *fileStoreRef
+	if ref, ok := s.hashes[contentHash]; ok && contentHash != "" && !ref.ProcessedAt.Before(since) {
+		found = &ref
+	}
+	if ref, ok := s.posts[parentName]; ok && parentName != "" && (found == nil || ref.ProcessedAt.Before(found.ProcessedAt)) {
+		found = &ref
+	}
+	if found == nil {
+		return nil, nil
+	}
+	return &ProcessedItem{Permalink: found.Permalink, Subreddit: found.Subreddit, ProcessedAt: found.ProcessedAt}, nil
+}
+
+// AddAlsoPostedIn does nothing: the file is append-only and the notes are only shown by the
+// dashboard, which reads MongoDB.
+func (s *FileStore) AddAlsoPostedIn(ctx context.Context, permalink, note string) error {
+	return nil
+}
+
+// compact rewrites the file without the items older than the TTL: to a temporary file, synced,
+// then renamed over the store file.
+func (s *FileStore) compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	items, dropped, err := s.load(time.Now().Add(-s.ttl))
+	if err != nil {
+		return err
+	}
+	if dropped == 0 {
+		return nil // Nothing expired
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".compact-*")
+	if err != nil {
+		return fmt.Errorf("error creating compacted store file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // Fails once renamed
+	tmp.Chmod(0o644)
+	writer := bufio.NewWriter(tmp)
+	for _, item := range items {
+		line, err := json.Marshal(item)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		writer.Write(append(line, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing compacted store file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error syncing compacted store file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("error replacing store file: %w", err)
+	}
+	syncDir(filepath.Dir(s.path)) // Persist the rename
+
+	s.file.Close()
+	if err := s.open(); err != nil {
+		s.file = nil // Marks fail until the next compaction reopens it
+		return err
+	}
+	s.reindex(items)
+	fmt.Printf("Compacted store file %s: removed %d expired or malformed line(s), %d item(s) left\n", s.path, dropped, len(items))
+	return nil
+}
+
+// runCompaction compacts the file every fileStoreCompactInterval until ctx is done.
+func (s *FileStore) runCompaction(ctx context.Context) {
+	ticker := time.NewTicker(fileStoreCompactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.compact(); err != nil {
+				fmt.Printf("Error compacting store file %s: %v\n", s.path, err)
+			}
+		}
+	}
+}
+
+// close closes the file, on shutdown.
+func (s *FileStore) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+}
+
+// syncDir fsyncs a directory so a rename in it survives a crash. Errors are ignored, some
+// platforms can't sync directories.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --- Recorded Reddit Responses ---
+
+// Run flags for development without the network, MongoDB or an email account
+var recordDir = ""           // --record: every Reddit response is saved to a file in this directory
+var replayDir = ""           // --replay: Reddit requests are answered from the files of this directory
+var useMemoryStore = false   // --memory-store: processed items are only kept until exit, MongoDB isn't used
+var logNotifications = false // --log-notifications: notifications are printed instead of sent
+
+// redditFixture is a Reddit response saved by --record, one JSON file per response
+type redditFixture struct {
+	URL        string          `json:"url"`
+	RecordedAt time.Time       `json:"recorded_at"`
+	Status     int             `json:"status"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// fixtureNameUnsafe matches what doesn't go in a fixture file name
+var fixtureNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._+-]+`)
+
+// fixtureKey normalizes a request URL for matching, so the order of query parameters doesn't matter.
+func fixtureKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.RawQuery = u.Query().Encode()
+	return u.String()
+}
+
+// recordingDoer is an HTTPDoer saving the responses of next to dir
+type recordingDoer struct {
+	next HTTPDoer
+	dir  string
+	seq  atomic.Int64 // Orders the files of responses recorded in the same instant
+}
+
+// newRecordingDoer returns a recordingDoer writing to dir, creating it if needed.
+func newRecordingDoer(next HTTPDoer, dir string) (*recordingDoer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating record directory: %w", err)
+	}
+	return &recordingDoer{next: next, dir: dir}, nil
+}
+
+// Do sends req through next and saves the response. The body is decompressed, so the file holds
+// the listing JSON as Reddit sent it; responses that aren't JSON (block pages) aren't saved.
+func (d *recordingDoer) Do(req *http.Request) (*http.Response, error) {
+	resp, err := d.next.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return resp, nil // Nothing to replay, the previous response of the URL is
+	}
+	var reader io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("error decompressing response: %w", err)
+		}
+		reader = gz
+	}
+	body, err := io.ReadAll(reader)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = int64(len(body))
+
+	if json.Valid(body) {
+		if err := d.save(req.URL.String(), resp.StatusCode, body); err != nil {
+			fmt.Printf("WARN: Could not record response of %s: %v\n", req.URL, err)
+		}
+	}
+	return resp, nil
+}
+
+// save writes a response to a file named after the time and the URL path, so the files of a
+// directory list in the order they were recorded.
+func (d *recordingDoer) save(rawURL string, status int, body []byte) error {
+	now := time.Now().UTC()
+	data, err := json.MarshalIndent(redditFixture{URL: rawURL, RecordedAt: now, Status: status, Body: body}, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		name = strings.TrimSuffix(u.Path, ".json")
+	}
+	name = strings.Trim(fixtureNameUnsafe.ReplaceAllString(name, "_"), "_")
+	file := fmt.Sprintf("%s-%06d-%s.json", now.Format("20060102T150405.000000000Z"), d.seq.Add(1), name)
+	return os.WriteFile(filepath.Join(d.dir, file), data, 0o644)
+}
+
+// replayDoer is an HTTPDoer answering from recorded responses instead of the network. The
+// responses of a URL are served in the order they were recorded, the last one again once they
+// are used up, so polling can go on. URLs that weren't recorded get a 404.
+type replayDoer struct {
+	mu        sync.Mutex
+	responses map[string][]redditFixture
+	served    map[string]int
+}
+
+// newReplayDoer loads the responses recorded in dir.
+func newReplayDoer(dir string) (*replayDoer, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	d := &replayDoer{responses: map[string][]redditFixture{}, served: map[string]int{}}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading recorded response: %w", err)
+		}
+		var fixture redditFixture
+		if err := json.Unmarshal(data, &fixture); err != nil || fixture.URL == "" {
+			fmt.Printf("WARN: Skipping %s, not a recorded response\n", path)
+			continue
+		}
+		key := fixtureKey(fixture.URL)
+		d.responses[key] = append(d.responses[key], fixture)
+	}
+	if len(d.responses) == 0 {
+		return nil, fmt.Errorf("no recorded responses in %s", dir)
+	}
+	return d, nil
+}
+
+// Do returns the next recorded response of the request URL.
+func (d *replayDoer) Do(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	key := fixtureKey(req.URL.String())
+	d.mu.Lock()
+	responses := d.responses[key]
+	var fixture redditFixture
+	if len(responses) > 0 {
+		fixture = responses[min(d.served[key], len(responses)-1)]
+		d.served[key]++
+	}
+	d.mu.Unlock()
+
+	if len(responses) == 0 {
+		fmt.Printf("WARN: No recorded response for %s, answering 404\n", req.URL)
+		fixture = redditFixture{Status: http.StatusNotFound, Body: json.RawMessage(`{"message": "Not Found", "error": 404}`)}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		StatusCode:    fixture.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json; charset=UTF-8"}},
+		Body:          io.NopCloser(bytes.NewReader(fixture.Body)),
+		ContentLength: int64(len(fixture.Body)),
+		Request:       req,
+	}, nil
+}
+
+// setupRedditFixtures puts client in record or replay mode, as set by --record and --replay.
+func setupRedditFixtures(client *RedditClient) error {
+	switch {
+	case recordDir != "" && replayDir != "":
+		return fmt.Errorf("--record and --replay can't be used together")
+	case recordDir != "":
+		doer, err := newRecordingDoer(client.HTTP, recordDir)
+		if err != nil {
+			return err
+		}
+		client.HTTP = doer
+		fmt.Println("Recording Reddit responses to", recordDir)
+	case replayDir != "":
+		doer, err := newReplayDoer(replayDir)
+		if err != nil {
+			return err
+		}
+		client.HTTP, client.Unpaced = doer, true
+		fmt.Printf("Replaying Reddit responses recorded in %s (%d URLs), the network isn't used\n", replayDir, len(doer.responses))
+	}
+	return nil
+}
+
+// LogNotifier prints notifications instead of sending them, for --log-notifications
+type LogNotifier struct {
+	recipient string
+}
+
+// channel names the notifier after its recipient, see channelName.
+func (n LogNotifier) channel() string { return "email " + n.recipient }
+
+// Notify prints the notification.
+func (n LogNotifier) Notify(subject, body string) error {
+	to := ""
+	if n.recipient != "" {
+		to = " to " + n.recipient
+	}
+	fmt.Printf("--- Notification%s: %s ---\n%s\n---\n", to, subject, body)
+	return nil
+}
+
+// scratchStore is the store of --memory-store: a FileStore in a temporary directory, removed
+// when it is closed, so nothing is kept past the run
+type scratchStore struct {
+	*FileStore
+	dir string
+}
+
+// newScratchStore returns an empty scratchStore.
+func newScratchStore() (*scratchStore, error) {
+	dir, err := os.MkdirTemp("", "reddit_monitor-store-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating the --memory-store directory: %w", err)
+	}
+	store, err := NewFileStore(filepath.Join(dir, "processed_items.jsonl"), storeFileTTL)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &scratchStore{FileStore: store, dir: dir}, nil
+}
+
+// close closes the store and removes its directory.
+func (s *scratchStore) close() {
+	s.FileStore.close()
+	os.RemoveAll(s.dir)
+}
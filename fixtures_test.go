@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureListing is a listing of one post, titled title
+func fixtureListing(id, title string) string {
+	return `{"data": {"children": [{"kind": "t3", "data": {"title": "` + title + `", "subreddit": "forhire", "permalink": "/r/forhire/comments/` + id + `/a/", "name": "t3_` + id + `"}}]}}`
+}
+
+func TestRecordedResponsesReplayThroughProcessing(t *testing.T) {
+	oldETags := listingETags
+	listingETags = &etagCache{tags: map[string]string{}}
+	t.Cleanup(func() { listingETags = oldETags })
+
+	// Reddit answers gzipped, with a new post on the second poll
+	listings := []string{fixtureListing("1", "Hiring a VA"), fixtureListing("2", "Looking for leads")}
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		writer.Write([]byte(listings[min(polls, len(listings)-1)]))
+		writer.Close()
+		polls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+	endpoint := server.URL + "/r/forhire/new.json?limit=100&raw_json=1"
+
+	dir := t.TempDir()
+	recorder, err := newRecordingDoer(server.Client(), dir)
+	if err != nil {
+		t.Fatalf("newRecordingDoer: %v", err)
+	}
+	for range listings {
+		if posts, _, _, err := testRedditClient(recorder).getPostListing(context.Background(), endpoint); err != nil || len(posts) != 1 {
+			t.Fatalf("recorded fetch = %d post(s), %v, want the listing", len(posts), err)
+		}
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(paths) != len(listings) {
+		t.Fatalf("%d file(s) recorded, want one per response", len(paths))
+	}
+	data, _ := os.ReadFile(paths[0])
+	var fixture redditFixture
+	if err := json.Unmarshal(data, &fixture); err != nil || !strings.Contains(string(fixture.Body), "Hiring a VA") {
+		t.Fatalf("recorded file = %s, %v, want the decompressed listing", data, err)
+	}
+
+	replayer, err := newReplayDoer(dir)
+	if err != nil {
+		t.Fatalf("newReplayDoer: %v", err)
+	}
+	client := testRedditClient(replayer)
+	// The query parameters in another order are the same URL
+	reordered := server.URL + "/r/forhire/new.json?raw_json=1&limit=100"
+	wantTitles := []string{"Hiring a VA", "Looking for leads", "Looking for leads"} // The last response again once used up
+	var replayed []Post
+	for i, want := range wantTitles {
+		posts, _, _, err := client.getPostListing(context.Background(), reordered)
+		if err != nil || len(posts) != 1 || posts[0].Title != want {
+			t.Fatalf("replay %d = %+v, %v, want %q", i, posts, err, want)
+		}
+		if i == 0 {
+			replayed = posts
+		}
+	}
+	if polls != len(listings) {
+		t.Errorf("server polled %d time(s), want the replay kept off the network", polls)
+	}
+	if _, _, _, err := client.getPostListing(context.Background(), server.URL+"/r/golang/new.json"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("replay of an unrecorded URL = %v, want a 404", err)
+	}
+
+	notifier := &alertRecorder{}
+	rules := matchRules{keywords: []KeywordSpec{{Keyword: "VA"}}, minMatches: 1}
+	processPosts(NewMemoryStore(), notifier, rules, replayed)
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("alerts = %+v, want one for the replayed post", notifier.alerts)
+	}
+	alert := notifier.alerts[0]
+	if alert.Permalink != "/r/forhire/comments/1/a/" || alert.Subreddit != "forhire" || len(alert.Keywords) != 1 || alert.Keywords[0] != "VA" {
+		t.Errorf("alert = %+v, want r/forhire's post 1 matching VA", alert)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// --- Subsystem Health ---
+
+// Subsystems whose consecutive failures are tracked
+const (
+	subsystemRedditPosts    = "reddit_posts"
+	subsystemRedditComments = "reddit_comments"
+	subsystemMongo          = "mongo"
+	subsystemNotifications  = "notifications"
+)
+
+// healthEventsCollectionName stores escalation and recovery events
+const healthEventsCollectionName = "health_events"
+
+// escalationThreshold is how many consecutive failures of a subsystem trigger an escalation
+const escalationThreshold = 5
+
+// HealthEvent is an escalation or recovery of a subsystem, stored in health_events
+type HealthEvent struct {
+	Subsystem string    `bson:"subsystem"`
+	Event     string    `bson:"event"` // "escalation" or "recovery"
+	Failures  int       `bson:"failures"`
+	LastError string    `bson:"last_error,omitempty"`
+	At        time.Time `bson:"at"`
+}
+
+// subsystemHealth is the in-memory state of one subsystem
+type subsystemHealth struct {
+	failures  int
+	lastError string
+	escalated bool
+}
+
+// healthTracker counts consecutive failures per subsystem. After escalationThreshold failures it sends
+// one escalation, and a recovery notice on the next success.
+type healthTracker struct {
+	mu         sync.Mutex
+	subsystems map[string]*subsystemHealth
+	events     *mongo.Collection // nil to only log and notify
+}
+
+// health tracks the subsystems of the running monitor
+var health = &healthTracker{subsystems: map[string]*subsystemHealth{}}
+
+// record notes the outcome of one attempt of a subsystem, nil err meaning success.
+func (h *healthTracker) record(subsystem string, err error) {
+	h.mu.Lock()
+	state := h.subsystems[subsystem]
+	if state == nil {
+		state = &subsystemHealth{}
+		h.subsystems[subsystem] = state
+	}
+	var event *HealthEvent
+	if err == nil {
+		if state.escalated {
+			event = &HealthEvent{Subsystem: subsystem, Event: "recovery", Failures: state.failures, At: time.Now()}
+		}
+		*state = subsystemHealth{}
+	} else {
+		state.failures++
+		state.lastError = err.Error()
+		if state.failures >= escalationThreshold && !state.escalated {
+			state.escalated = true
+			event = &HealthEvent{Subsystem: subsystem, Event: "escalation", Failures: state.failures, LastError: state.lastError, At: time.Now()}
+		}
+	}
+	h.mu.Unlock()
+
+	if event != nil {
+		h.report(*event) // Outside the lock, notifying can be slow
+	}
+}
+
+// report logs, stores and sends an escalation or recovery.
+func (h *healthTracker) report(event HealthEvent) {
+	var subject, body string
+	if event.Event == "escalation" {
+		subject = fmt.Sprintf("Reddit Keyword Monitor: %s failing", event.Subsystem)
+		body = fmt.Sprintf("%s failed %d times in a row.\nLast error: %s\n\nYou'll get a notice when it recovers.",
+			event.Subsystem, event.Failures, event.LastError)
+		fmt.Printf("!!! ESCALATION: %s failed %d times in a row: %s !!!\n", event.Subsystem, event.Failures, event.LastError)
+	} else {
+		subject = fmt.Sprintf("Reddit Keyword Monitor: %s recovered", event.Subsystem)
+		body = fmt.Sprintf("%s is working again.", event.Subsystem)
+		fmt.Printf("Info: %s recovered\n", event.Subsystem)
+	}
+	h.deliver(event, subject, body)
+}
+
+// deliver stores the event and sends subject and body to the admin.
+func (h *healthTracker) deliver(event HealthEvent, subject, body string) {
+	if h.events != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if _, err := h.events.InsertOne(ctx, event); err != nil {
+			fmt.Printf("Error storing %s %s event: %v\n", event.Subsystem, event.Event, err)
+		}
+		cancel()
+	}
+
+	// Try every configured provider until one works, the failing subsystem may be one of them
+	for _, notifier := range adminNotifiers() {
+		if err := safeNotify(notifier, subject, body); err != nil {
+			fmt.Printf("Error sending %s %s notice: %v\n", event.Subsystem, event.Event, err)
+			continue
+		}
+		return
+	}
+	fmt.Printf("WARN: No notifier could send the %s %s notice\n", event.Subsystem, event.Event)
+}
+
+// adminNotifiers returns a notifier per configured email provider, addressed to ADMIN_EMAIL
+// (or RECIPIENT_EMAIL), preferred provider first.
+func adminNotifiers() []Notifier {
+	to := adminEmail
+	if to == "" {
+		to = recipientEmail
+	}
+	notifiers := []Notifier{}
+	if mailgun.APIKey != "" {
+		notifiers = append(notifiers, NewMailgunNotifier(to))
+	}
+	if sendgridAPIKey != "" {
+		notifiers = append(notifiers, NewSendGridNotifier(to))
+	}
+	if gmailUser != "" && gmailAppPassword != "" {
+		notifiers = append(notifiers, NewEmailNotifier(to))
+	}
+	return notifiers
+}
+
+// healthNotifier records the outcome of every notification as the notifications subsystem
+type healthNotifier struct {
+	Notifier
+}
+
+// Notify sends through the wrapped notifier and records the result.
+func (n healthNotifier) Notify(subject, body string) error {
+	err := safeNotify(n.Notifier, subject, body)
+	health.record(subsystemNotifications, err)
+	return err
+}
+
+// NotifyAlert sends the alert through the wrapped notifier and records the result.
+func (n healthNotifier) NotifyAlert(alert Alert) error {
+	err := notifyAlert(n.Notifier, alert)
+	health.record(subsystemNotifications, err)
+	return err
+}
+
+// NotifyHTML sends the report through the wrapped notifier and records the result.
+func (n healthNotifier) NotifyHTML(subject, text, htmlText string) error {
+	err := notifyHTML(n.Notifier, subject, text, htmlText)
+	health.record(subsystemNotifications, err)
+	return err
+}
+
+// NotifyBatch sends the batched alerts through the wrapped notifier and records the result.
+func (n healthNotifier) NotifyBatch(subject, body string, alerts []Alert) error {
+	err := notifyBatch(n.Notifier, subject, body, alerts)
+	health.record(subsystemNotifications, err)
+	return err
+}
+
+// checkMongoHealth pings MongoDB once per cycle and records the result.
+func checkMongoHealth() {
+	if mongoClient == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	health.record(subsystemMongo, mongoClient.Ping(ctx, nil))
+}
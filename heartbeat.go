@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// --- Heartbeat ---
+
+// heartbeatURL is pinged after every cycle that fetched anything, for dead man's switch services
+// like healthchecks.io that alert when the pings stop
+var heartbeatURL string
+
+// heartbeatEmailInterval is how often a "monitor alive" email is sent (0 disables)
+var heartbeatEmailInterval time.Duration
+
+// validateHeartbeatURL checks that the ping URL is an absolute http(s) URL.
+func validateHeartbeatURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid heartbeat_url %q, must be an http(s) URL", raw)
+	}
+	return nil
+}
+
+// pingHeartbeat GETs the heartbeat URL. Failures are logged only.
+func pingHeartbeat() {
+	if heartbeatURL == "" {
+		return
+	}
+	resp, err := httpClient.Get(heartbeatURL)
+	if err != nil {
+		fmt.Printf("WARN: Heartbeat ping failed: %v\n", err)
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		fmt.Printf("WARN: Heartbeat ping returned %s\n", resp.Status)
+		return
+	}
+	debugf("Heartbeat ping sent")
+}
+
+// runHeartbeatEmails sends a "monitor alive" email with the number of matches of the past 24 hours
+// every heartbeatEmailInterval until ctx is done.
+func runHeartbeatEmails(ctx context.Context, notifier Notifier) {
+	ticker := time.NewTicker(heartbeatEmailInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sendHeartbeatEmail(notifier)
+		}
+	}
+}
+
+// sendHeartbeatEmail sends one "monitor alive" email.
+func sendHeartbeatEmail(notifier Notifier) {
+	subject := "Reddit Keyword Monitor alive"
+	body := "The Reddit keyword monitor is running."
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if processedItemsCollection != nil { // Nil without MongoDB
+		matches, err := processedItemsCollection.CountDocuments(ctx, matchesFilter(time.Now().Add(-24*time.Hour), "", ""))
+		if err != nil {
+			fmt.Printf("Error counting matches for heartbeat: %v\n", err)
+			body += "\nCould not count matches: " + err.Error()
+		} else {
+			subject += fmt.Sprintf(", %d match(es) in last 24h", matches)
+			body += fmt.Sprintf("\n%d match(es) in the last 24 hours.", matches)
+		}
+	}
+	if err := notifier.Notify(subject, body); err != nil {
+		fmt.Println("Error sending heartbeat email:", err)
+	}
+}
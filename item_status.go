@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Match Status Checks ---
+
+// statusCheckWindow is how far back matches are rechecked for removal (0 disables status checks)
+var statusCheckWindow time.Duration
+
+// statusCheckSchedule is the cron expression status checks run on
+var statusCheckSchedule = "*/30 * * * *"
+
+// statusBatchSize is the most fullnames /api/info.json takes per request
+const statusBatchSize = 100
+
+// Match statuses: still up, removed by moderators (or Reddit), deleted by its author
+const (
+	statusLive    = "live"
+	statusRemoved = "removed"
+	statusDeleted = "deleted"
+)
+
+// InfoResponse is the part of /api/info.json used for status checks, posts and comments alike
+type InfoResponse struct {
+	Data struct {
+		Children []struct {
+			Data struct {
+				Name              string `json:"name"`
+				Author            string `json:"author"`
+				Score             int    `json:"score"`
+				Selftext          string `json:"selftext"`            // Posts
+				Body              string `json:"body"`                // Comments
+				RemovedByCategory string `json:"removed_by_category"` // Posts: "deleted" by the author, "moderator", "automod_filtered", "reddit", ...
+			} `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// itemFullname returns the fullname of a stored match: the post name for posts, t1_ plus the last
+// path segment of the permalink for comments. Returns "" if it can't be told.
+func itemFullname(item ProcessedItem) string {
+	if item.Kind != "comment" {
+		return item.PostName
+	}
+	segments := strings.Split(strings.Trim(item.Permalink, "/"), "/")
+	// /r/<sub>/comments/<post id>/<slug>/<comment id>/
+	if len(segments) < 6 || segments[2] != "comments" {
+		return ""
+	}
+	return "t1_" + segments[len(segments)-1]
+}
+
+// itemStatus tells from an info.json entry whether the item is live, removed or deleted.
+func itemStatus(author, text, removedByCategory string) string {
+	switch {
+	case removedByCategory == "deleted" || removedByCategory == "author":
+		return statusDeleted
+	case removedByCategory != "" || text == "[removed]":
+		return statusRemoved
+	case author == "[deleted]" || text == "[deleted]":
+		return statusDeleted
+	}
+	return statusLive
+}
+
+// checkMatchStatuses fetches every match of the status check window from /api/info.json, 100 per
+// request through the shared rate limiter, and stores its status and current score.
+func checkMatchStatuses(ctx context.Context, collection *mongo.Collection) {
+	if statusCheckWindow <= 0 || collection == nil {
+		return
+	}
+	ctxFind, cancelFind := context.WithTimeout(ctx, 30*time.Second)
+	defer cancelFind()
+	cursor, err := collection.Find(ctxFind, matchesFilter(time.Now().Add(-statusCheckWindow), "", ""),
+		options.Find().SetProjection(map[string]interface{}{"permalink": 1, "kind": 1, "post_name": 1}))
+	if err != nil {
+		fmt.Printf("Error querying matches for status checks: %v\n", err)
+		return
+	}
+	items := []ProcessedItem{}
+	if err := cursor.All(ctxFind, &items); err != nil {
+		fmt.Printf("Error reading matches for status checks: %v\n", err)
+		return
+	}
+
+	permalinks := map[string]string{} // Fullname to permalink
+	names := []string{}
+	for _, item := range items {
+		name := itemFullname(item)
+		if name == "" {
+			continue // Matches stored before post names were recorded
+		}
+		if _, ok := permalinks[name]; !ok {
+			names = append(names, name)
+		}
+		permalinks[name] = item.Permalink
+	}
+
+	counts := map[string]int{}
+	for start := 0; start < len(names); start += statusBatchSize {
+		if ctx.Err() != nil {
+			return
+		}
+		batch := names[start:min(start+statusBatchSize, len(names))]
+		info, err := getRedditJSON[InfoResponse](ctx, redditClient, "https://www.reddit.com/api/info.json?raw_json=1&id="+strings.Join(batch, ","))
+		if err != nil {
+			fmt.Printf("Error fetching the status of %d match(es): %v\n", len(batch), err)
+			continue
+		}
+		now := time.Now()
+		updates := []mongo.WriteModel{}
+		for _, child := range info.Data.Children {
+			item := child.Data
+			permalink, ok := permalinks[item.Name]
+			if !ok {
+				continue
+			}
+			status := itemStatus(item.Author, item.Selftext+item.Body, item.RemovedByCategory)
+			counts[status]++
+			updates = append(updates, mongo.NewUpdateManyModel().
+				SetFilter(map[string]interface{}{"permalink": permalink}). // Every profile's copy
+				SetUpdate(map[string]interface{}{"$set": map[string]interface{}{
+					"status": status, "score": item.Score, "status_checked_at": now,
+				}}))
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		ctxWrite, cancelWrite := context.WithTimeout(ctx, 30*time.Second)
+		_, err = collection.BulkWrite(ctxWrite, updates, options.BulkWrite().SetOrdered(false))
+		cancelWrite()
+		if err != nil {
+			fmt.Printf("Error storing the status of %d match(es): %v\n", len(updates), err)
+		}
+	}
+	fmt.Printf("Checked the status of %d match(es): %d live, %d removed, %d deleted\n",
+		len(names), counts[statusLive], counts[statusRemoved], counts[statusDeleted])
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// --- Kafka ---
+
+// Kafka settings from the config file, match events are produced to the topic when both are set
+var kafkaBrokers []string
+var kafkaTopic = ""
+var kafkaTLSEnabled = false
+var kafkaSASLMechanism = "" // plain, scram-sha-256 or scram-sha-512, no authentication when empty
+var kafkaSASLUsername = ""
+var kafkaSASLPassword = ""
+
+// matchEventSchemaVersion is bumped whenever MatchEvent changes incompatibly
+const matchEventSchemaVersion = 1
+
+// MatchEvent is the JSON value of every Kafka message, keyed by subreddit
+type MatchEvent struct {
+	SchemaVersion int `json:"schema_version"`
+	NotificationData
+}
+
+// matchEventID identifies the event of alert for broker-side deduplication of resends: the hex
+// SHA-256 of its permalink and keywords.
+func matchEventID(alert Alert) string {
+	hash := sha256.Sum256([]byte(alert.Permalink + "\n" + strings.Join(alert.Keywords, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// kafkaConn is the writer shared by every KafkaNotifier, closed on shutdown
+var kafkaConn = &kafkaProducer{}
+
+// KafkaNotifier produces a MatchEvent for every alert to the configured topic
+type KafkaNotifier struct {
+	producer *kafkaProducer
+}
+
+// NewKafkaNotifier returns a Notifier producing to the configured Kafka topic.
+func NewKafkaNotifier() *KafkaNotifier {
+	return &KafkaNotifier{producer: kafkaConn}
+}
+
+// Notify does nothing: the topic carries match events only, digests and summaries go to the
+// other channels.
+func (n *KafkaNotifier) Notify(subject, body string) error {
+	return nil
+}
+
+// NotifyBatch produces an event for every alert of a batched cycle.
+func (n *KafkaNotifier) NotifyBatch(subject, body string, alerts []Alert) error {
+	errs := []error{}
+	for _, alert := range alerts {
+		if err := n.NotifyAlert(alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyAlert produces the match with its subreddit as the key, so the matches of a subreddit stay
+// in order on one partition.
+func (n *KafkaNotifier) NotifyAlert(alert Alert) error {
+	value, err := json.Marshal(MatchEvent{SchemaVersion: matchEventSchemaVersion, NotificationData: notificationData(alert)})
+	if err != nil {
+		return err
+	}
+	key := []byte(strings.ToLower(alert.Subreddit))
+	err = withRetry("Kafka produce", func() error {
+		return n.producer.produce(key, value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to produce to Kafka topic %s: %w", kafkaTopic, err)
+	}
+	fmt.Printf("Match event produced to Kafka topic %s\n", kafkaTopic)
+	return nil
+}
+
+// kafkaRequestTimeout bounds producing one event, acknowledged by all in-sync replicas
+const kafkaRequestTimeout = 10 * time.Second
+
+// kafkaProducer produces to kafkaTopic through a kafka.Writer, made again when the settings
+// change (config reload). Writes wait for all in-sync replicas, so a produced event is durable once
+// produce returns.
+type kafkaProducer struct {
+	mu       sync.Mutex
+	settings string // Brokers, topic, TLS and SASL the writer was made with
+	writer   kafkaWriter
+}
+
+// kafkaWriter is the part of *kafka.Writer the producer uses
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// kafkaSettings describes the settings a writer is made with.
+func kafkaSettings() string {
+	return fmt.Sprintf("%v %s %t %s %s %s", kafkaBrokers, kafkaTopic, kafkaTLSEnabled, kafkaSASLMechanism, kafkaSASLUsername, kafkaSASLPassword)
+}
+
+// newKafkaWriter returns a writer for the current settings. The key is hashed with murmur2 like
+// the Java client does, so events of a subreddit land on the partition other producers use.
+func newKafkaWriter() (*kafka.Writer, error) {
+	transport := &kafka.Transport{DialTimeout: kafkaRequestTimeout, ClientID: "reddit-monitor"}
+	if kafkaTLSEnabled {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if kafkaSASLMechanism != "" {
+		mechanism, err := kafkaSASL(kafkaSASLMechanism, kafkaSASLUsername, kafkaSASLPassword)
+		if err != nil {
+			return nil, err
+		}
+		transport.SASL = mechanism
+	}
+	return &kafka.Writer{
+		Addr:         kafka.TCP(kafkaBrokers...),
+		Topic:        kafkaTopic,
+		Balancer:     &kafka.Murmur2Balancer{},
+		RequiredAcks: kafka.RequireAll,
+		MaxAttempts:  1,                     // withRetry retries
+		BatchTimeout: 10 * time.Millisecond, // One event per write, don't wait for more
+		WriteTimeout: kafkaRequestTimeout,
+		Transport:    transport,
+	}, nil
+}
+
+// kafkaSASL returns the SASL mechanism named name, see kafka_sasl_mechanism.
+func kafkaSASL(name, username, password string) (sasl.Mechanism, error) {
+	switch name {
+	case "plain":
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, username, password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, username, password)
+	}
+	return nil, fmt.Errorf("unknown kafka_sasl_mechanism %q", name)
+}
+
+// produce writes one message keyed by key, making the writer first if there is none or the
+// settings changed since.
+func (p *kafkaProducer) produce(key, value []byte) error {
+	p.mu.Lock()
+	if settings := kafkaSettings(); p.writer == nil || p.settings != settings {
+		if p.writer != nil {
+			p.writer.Close()
+		}
+		writer, err := newKafkaWriter()
+		if err != nil {
+			p.mu.Unlock()
+			return err
+		}
+		p.writer, p.settings = writer, settings
+	}
+	writer := p.writer
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaRequestTimeout)
+	defer cancel()
+	err := writer.WriteMessages(ctx, kafka.Message{Key: key, Value: value})
+	var writeErrs kafka.WriteErrors
+	if errors.As(err, &writeErrs) && len(writeErrs) == 1 {
+		err = writeErrs[0] // The error of the one message
+	}
+	var kafkaErr kafka.Error
+	if err == nil || (errors.As(err, &kafkaErr) && !kafkaErr.Temporary()) {
+		return err
+	}
+	return &retryableError{err: err} // Connection failures, timeouts and leadership changes
+}
+
+// close flushes and closes the writer, on shutdown.
+func (p *kafkaProducer) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.writer != nil {
+		if err := p.writer.Close(); err != nil {
+			fmt.Printf("Error closing Kafka writer: %v\n", err)
+		}
+		p.writer = nil
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestKafkaSASL(t *testing.T) {
+	for _, name := range []string{"plain", "scram-sha-256", "scram-sha-512"} {
+		mechanism, err := kafkaSASL(name, "user", "secret")
+		if err != nil {
+			t.Fatalf("kafkaSASL(%q): %v", name, err)
+		}
+		if got := mechanism.Name(); got == "" {
+			t.Errorf("kafkaSASL(%q) has no name", name)
+		}
+	}
+	if _, err := kafkaSASL("gssapi", "user", "secret"); err == nil {
+		t.Error("kafkaSASL accepted an unknown mechanism")
+	}
+}
+
+func TestNewKafkaWriter(t *testing.T) {
+	kafkaBrokers, kafkaTopic, kafkaTLSEnabled = []string{"a:9092", "b:9092"}, "matches", true
+	kafkaSASLMechanism, kafkaSASLUsername, kafkaSASLPassword = "scram-sha-512", "user", "secret"
+	t.Cleanup(func() {
+		kafkaBrokers, kafkaTopic, kafkaTLSEnabled = nil, "", false
+		kafkaSASLMechanism, kafkaSASLUsername, kafkaSASLPassword = "", "", ""
+	})
+
+	writer, err := newKafkaWriter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	if writer.Topic != "matches" || writer.Addr.String() != "a:9092,b:9092" {
+		t.Errorf("writer produces to %s on %s, want matches on a:9092,b:9092", writer.Topic, writer.Addr)
+	}
+	if writer.RequiredAcks != kafka.RequireAll {
+		t.Errorf("RequiredAcks = %v, want RequireAll", writer.RequiredAcks)
+	}
+	if _, ok := writer.Balancer.(*kafka.Murmur2Balancer); !ok {
+		t.Errorf("Balancer = %T, want murmur2 like the Java client", writer.Balancer)
+	}
+	transport := writer.Transport.(*kafka.Transport)
+	if transport.TLS == nil || transport.SASL == nil || transport.SASL.Name() != "SCRAM-SHA-512" {
+		t.Errorf("transport TLS %v, SASL %v, want TLS and SCRAM-SHA-512", transport.TLS, transport.SASL)
+	}
+}
+
+// fakeKafkaWriter keeps the messages written to it, failing the writes with errs in turn
+type fakeKafkaWriter struct {
+	messages []kafka.Message
+	errs     []error
+	writes   int
+}
+
+func (w *fakeKafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.writes++
+	if len(w.errs) > 0 {
+		err := w.errs[0]
+		w.errs = w.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error { return nil }
+
+// withFastRetries makes withRetry back off for a millisecond during the test.
+func withFastRetries(t *testing.T) {
+	old := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = old })
+}
+
+// fakeKafkaNotifier returns a KafkaNotifier producing to writer, as if made for the current settings.
+func fakeKafkaNotifier(writer *fakeKafkaWriter) *KafkaNotifier {
+	return &KafkaNotifier{producer: &kafkaProducer{writer: writer, settings: kafkaSettings()}}
+}
+
+func TestKafkaNotifyAlertMessage(t *testing.T) {
+	writer := &fakeKafkaWriter{}
+	alert := Alert{
+		Kind:            "post",
+		Subreddit:       "ForHire",
+		Permalink:       "/r/ForHire/comments/1/hiring_a_va/",
+		Snippet:         "Hiring a VA",
+		Keywords:        []string{"VA"},
+		Priority:        "high",
+		KeywordPriority: "medium",
+	}
+	if err := fakeKafkaNotifier(writer).NotifyAlert(alert); err != nil {
+		t.Fatalf("NotifyAlert: %v", err)
+	}
+	if len(writer.messages) != 1 {
+		t.Fatalf("%d message(s) produced, want 1", len(writer.messages))
+	}
+	message := writer.messages[0]
+	if string(message.Key) != "forhire" {
+		t.Errorf("key = %q, want the lowercased subreddit", message.Key)
+	}
+	var event MatchEvent
+	if err := json.Unmarshal(message.Value, &event); err != nil {
+		t.Fatalf("value isn't a JSON match event: %v", err)
+	}
+	if event.SchemaVersion != matchEventSchemaVersion || event.Subreddit != "ForHire" || event.URL != alert.URL() ||
+		!slices.Equal(event.Keywords, alert.Keywords) || event.Priority != "high" || event.KeywordPriority != "medium" {
+		t.Errorf("event = %+v, want the alert's at schema version %d", event, matchEventSchemaVersion)
+	}
+}
+
+func TestKafkaPartitionKeyIgnoresSubredditCase(t *testing.T) {
+	writer := &fakeKafkaWriter{}
+	notifier := fakeKafkaNotifier(writer)
+	for _, subreddit := range []string{"ForHire", "forhire", "FORHIRE"} {
+		if err := notifier.NotifyAlert(Alert{Subreddit: subreddit, Permalink: "/r/" + subreddit + "/comments/1/"}); err != nil {
+			t.Fatalf("NotifyAlert: %v", err)
+		}
+	}
+	partitions := []int{0, 1, 2, 3, 4, 5}
+	balancer := &kafka.Murmur2Balancer{}
+	want := balancer.Balance(writer.messages[0], partitions...)
+	for _, message := range writer.messages[1:] {
+		if got := balancer.Balance(message, partitions...); got != want || string(message.Key) != "forhire" {
+			t.Errorf("message keyed %q goes to partition %d, want forhire on %d", message.Key, got, want)
+		}
+	}
+}
+
+func TestKafkaProduceRetries(t *testing.T) {
+	withFastRetries(t)
+	tests := []struct {
+		name       string
+		errs       []error
+		wantWrites int
+		wantErr    bool
+	}{
+		{"leader election", []error{kafka.WriteErrors{kafka.LeaderNotAvailable}}, 2, false},
+		{"connection failure", []error{context.DeadlineExceeded, context.DeadlineExceeded}, 3, false},
+		{"message too large", []error{kafka.WriteErrors{kafka.MessageSizeTooLarge}}, 1, true},
+		{"topic authorization", []error{kafka.TopicAuthorizationFailed}, 1, true},
+		{"broker down", []error{kafka.NotEnoughReplicas, kafka.NotEnoughReplicas, kafka.NotEnoughReplicas, kafka.NotEnoughReplicas}, retryAttempts, true},
+	}
+	for _, tt := range tests {
+		writer := &fakeKafkaWriter{errs: tt.errs}
+		err := fakeKafkaNotifier(writer).NotifyAlert(Alert{Subreddit: "golang"})
+		if writer.writes != tt.wantWrites || (err != nil) != tt.wantErr {
+			t.Errorf("%s: %d write(s), error %v, want %d and error %v", tt.name, writer.writes, err, tt.wantWrites, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Keyword Statistics ---
+
+// keywordStatsCollectionName holds daily match counts per keyword and subreddit
+const keywordStatsCollectionName = "keyword_stats"
+
+// keywordStatsDateFormat is the layout of KeywordStat.Date
+const keywordStatsDateFormat = "2006-01-02"
+
+// keywordStatsCollection is the stats collection, nil when stats aren't recorded
+var keywordStatsCollection *mongo.Collection
+
+// KeywordStat is the number of matches of one keyword in one subreddit on one day (UTC)
+type KeywordStat struct {
+	Keyword   string `bson:"keyword" json:"keyword"`
+	Subreddit string `bson:"subreddit" json:"subreddit"`
+	Date      string `bson:"date" json:"date"` // YYYY-MM-DD
+	Count     int    `bson:"count" json:"count"`
+	Profile   string `bson:"profile,omitempty" json:"profile,omitempty"`
+}
+
+// recordKeywordStats increments today's count of every matched keyword in subreddit, per profile
+// ("" for the top-level config), and counts them for spike detection. Failures are logged only,
+// stats never block processing.
+func recordKeywordStats(profile, subreddit string, found []string) {
+	countCycleMatches(profile, subreddit, found)
+	if keywordStatsCollection == nil {
+		return
+	}
+	date := time.Now().UTC().Format(keywordStatsDateFormat)
+	for _, keyword := range found {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err := keywordStatsCollection.UpdateOne(ctx,
+			map[string]interface{}{"keyword": keyword, "subreddit": subreddit, "date": date, "profile": profileFilter(profile)},
+			map[string]interface{}{"$inc": map[string]interface{}{"count": 1}},
+			options.Update().SetUpsert(true))
+		cancel()
+		if err != nil {
+			fmt.Printf("Error updating keyword stats for %q in r/%s: %v\n", keyword, subreddit, err)
+		}
+	}
+}
+
+// QueryKeywordStats returns the daily counts of keyword since the given time, oldest day first.
+func QueryKeywordStats(keyword string, since time.Time) ([]KeywordStat, error) {
+	if keywordStatsCollection == nil {
+		return nil, fmt.Errorf("keyword stats collection is not connected")
+	}
+	filter := map[string]interface{}{
+		"keyword": keyword,
+		"date":    map[string]interface{}{"$gte": since.UTC().Format(keywordStatsDateFormat)},
+	}
+	findOptions := options.Find().SetSort(map[string]interface{}{"date": 1, "subreddit": 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cursor, err := keywordStatsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	stats := []KeywordStat{}
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- List-Processed Subcommand ---
+
+// runListProcessed queries the processed_items collection with the given filters and prints
+// the results to stdout. Returns the process exit code.
+func runListProcessed(args []string) int {
+	fs := flag.NewFlagSet("list-processed", flag.ContinueOnError)
+	since := fs.Duration("since", 24*time.Hour, "only show items processed within this duration")
+	subreddit := fs.String("subreddit", "", "only show items from this subreddit")
+	limit := fs.Int64("limit", 50, "maximum number of items to show")
+	format := fs.String("format", "table", "output format: table or json")
+	statsKeyword := fs.String("keyword-stats", "", "show daily match counts of this keyword instead of items")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Printf("Invalid --format %q, must be table or json\n", *format)
+		return 2
+	}
+	if mongoURI == "" {
+		fmt.Println("FATAL: MONGODB_URI environment variable must be set.")
+		return 1
+	}
+
+	client, err := connectMongo()
+	if err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return 1
+	}
+	defer client.Disconnect(context.Background())
+	collection := client.Database(mongoDatabaseName).Collection(processedItemsCollectionName)
+	keywordStatsCollection = client.Database(mongoDatabaseName).Collection(keywordStatsCollectionName)
+
+	if *statsKeyword != "" {
+		return printKeywordStats(*statsKeyword, time.Now().Add(-*since), *format)
+	}
+
+	// Build the query filter
+	filter := map[string]interface{}{
+		"processed_at": map[string]interface{}{"$gte": time.Now().Add(-*since)},
+	}
+	if *subreddit != "" {
+		filter["subreddit"] = *subreddit
+	}
+	findOptions := options.Find().
+		SetSort(map[string]interface{}{"processed_at": -1}). // Newest first
+		SetLimit(*limit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		fmt.Printf("Error querying processed items: %v\n", err)
+		return 1
+	}
+	var items []ProcessedItem
+	if err := cursor.All(ctx, &items); err != nil {
+		fmt.Printf("Error decoding processed items: %v\n", err)
+		return 1
+	}
+
+	return printProcessedItems(items, *format)
+}
+
+// printProcessedItems prints items as a table or as JSON. Returns the process exit code.
+func printProcessedItems(items []ProcessedItem, format string) int {
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if items == nil {
+			items = []ProcessedItem{} // Print [] rather than null
+		}
+		if err := encoder.Encode(items); err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Permalink\tSubreddit\tKeywords\tProcessedAt")
+	for _, item := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Permalink, item.Subreddit, strings.Join(item.Keywords, ","), item.ProcessedAt.Local().Format(time.RFC3339))
+	}
+	w.Flush()
+	return 0
+}
+
+// printKeywordStats prints the daily counts of keyword since the given time. Returns the process exit code.
+func printKeywordStats(keyword string, since time.Time, format string) int {
+	stats, err := QueryKeywordStats(keyword, since)
+	if err != nil {
+		fmt.Printf("Error querying keyword stats: %v\n", err)
+		return 1
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(stats); err != nil {
+			fmt.Printf("Error encoding JSON: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Date\tSubreddit\tCount")
+	total := 0
+	for _, stat := range stats {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", stat.Date, stat.Subreddit, stat.Count)
+		total += stat.Count
+	}
+	w.Flush()
+	fmt.Printf("%d match(es) of %q since %s\n", total, keyword, since.UTC().Format(keywordStatsDateFormat))
+	return 0
+}
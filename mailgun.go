@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// --- Mailgun ---
+
+// MailgunConfig holds the Mailgun settings of the config file. Alerts go through Mailgun when APIKey is set.
+type MailgunConfig struct {
+	APIKey      string
+	Domain      string
+	Region      string // "us" (default) or "eu"
+	From        string // Defaults to reddit-monitor@<domain>
+	TrackOpens  *bool  // nil keeps the domain's tracking setting
+	TrackClicks *bool
+}
+
+// mailgun is the configured Mailgun account, zero when Mailgun isn't used
+var mailgun MailgunConfig
+
+// mailgunBaseURLs are the API hosts of Mailgun's regions
+var mailgunBaseURLs = map[string]string{
+	"us": "https://api.mailgun.net",
+	"eu": "https://api.eu.mailgun.net",
+}
+
+// validate checks that a configured account has a domain and a known region.
+func (c MailgunConfig) validate() error {
+	if c.APIKey == "" {
+		return nil
+	}
+	if c.Domain == "" {
+		return fmt.Errorf("mailgun_domain must be set with mailgun_api_key")
+	}
+	if c.Region != "" && mailgunBaseURLs[c.Region] == "" {
+		return fmt.Errorf("invalid mailgun_region %q (must be us or eu)", c.Region)
+	}
+	return nil
+}
+
+// endpoint returns the messages URL of the account's domain in its region.
+func (c MailgunConfig) endpoint() string {
+	base := mailgunBaseURLs["us"]
+	if c.Region != "" {
+		base = mailgunBaseURLs[c.Region]
+	}
+	return base + "/v3/" + url.PathEscape(c.Domain) + "/messages"
+}
+
+// MailgunNotifier sends alerts through the Mailgun messages API
+type MailgunNotifier struct {
+	config    MailgunConfig
+	recipient string
+	endpoint  string
+	client    *http.Client
+}
+
+// NewMailgunNotifier returns a Notifier emailing recipient through the configured Mailgun account.
+func NewMailgunNotifier(recipient string) *MailgunNotifier {
+	return &MailgunNotifier{config: mailgun, recipient: recipient, endpoint: mailgun.endpoint(), client: httpClient}
+}
+
+// from returns the sender address, the configured one or reddit-monitor@ the sending domain.
+func (n *MailgunNotifier) from() string {
+	if n.config.From == "" {
+		return "reddit-monitor@" + n.config.Domain
+	}
+	return n.config.From
+}
+
+// form builds the form-encoded message.
+func (n *MailgunNotifier) form(message emailMessage) url.Values {
+	form := url.Values{}
+	form.Set("from", message.From)
+	form.Set("to", message.To)
+	form.Set("subject", message.Subject)
+	form.Set("text", message.Text)
+	form.Set("html", message.HTML)
+	if n.config.TrackOpens != nil {
+		form.Set("o:tracking-opens", yesNo(*n.config.TrackOpens))
+	}
+	if n.config.TrackClicks != nil {
+		form.Set("o:tracking-clicks", yesNo(*n.config.TrackClicks))
+	}
+	return form
+}
+
+// channel names the notifier after its recipient, see channelName.
+func (n *MailgunNotifier) channel() string { return "email " + n.recipient }
+
+// Notify posts the alert to Mailgun, retrying rate limits and server errors.
+func (n *MailgunNotifier) Notify(subject, body string) error {
+	return n.send(newEmailMessage(n.from(), n.recipient, subject, body))
+}
+
+// NotifyHTML posts a report with its own HTML version.
+func (n *MailgunNotifier) NotifyHTML(subject, text, htmlText string) error {
+	return n.send(emailMessage{From: n.from(), To: n.recipient, Subject: subject, Text: text, HTML: htmlText})
+}
+
+// send posts message to Mailgun.
+func (n *MailgunNotifier) send(message emailMessage) error {
+	payload := n.form(message).Encode()
+	err := withRetry("Mailgun send", func() error {
+		req, err := http.NewRequest(http.MethodPost, n.endpoint, strings.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth("api", n.config.APIKey)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := n.client.Do(req)
+		if err != nil {
+			return &retryableError{err: err}
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode >= 300 {
+			return statusError(resp, respBody)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send email through Mailgun: %w", err)
+	}
+	fmt.Println("Email sent successfully to", n.recipient, "through Mailgun")
+	return nil
+}
+
+// yesNo formats a Mailgun boolean option.
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// mailgunServer answers Mailgun sends with status, keeping the last request and its parsed form.
+func mailgunServer(t *testing.T, status int) (*httptest.Server, *http.Request, *url.Values) {
+	received, form := &http.Request{}, &url.Values{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("request body isn't form-encoded: %v", err)
+		}
+		*received, *form = *r, r.PostForm
+		w.WriteHeader(status)
+		w.Write([]byte(`{"message": "Queued. Thank you."}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, received, form
+}
+
+func TestMailgunNotifierFormBody(t *testing.T) {
+	server, received, form := mailgunServer(t, http.StatusOK)
+	trackOpens, trackClicks := true, false
+	n := &MailgunNotifier{
+		config:    MailgunConfig{APIKey: "key-123", Domain: "mg.example.com", TrackOpens: &trackOpens, TrackClicks: &trackClicks},
+		recipient: "team@example.com",
+		endpoint:  server.URL + "/v3/mg.example.com/messages",
+		client:    server.Client(),
+	}
+
+	if err := n.Notify("Match in r/forhire", "Found VA & leads: <https://reddit.com/r/forhire/1>"); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if received.Method != http.MethodPost || received.URL.Path != "/v3/mg.example.com/messages" {
+		t.Errorf("request = %s %s, want POST /v3/mg.example.com/messages", received.Method, received.URL.Path)
+	}
+	if user, password, ok := received.BasicAuth(); !ok || user != "api" || password != "key-123" {
+		t.Errorf("basic auth = %q, %q, %v, want api and the API key", user, password, ok)
+	}
+	if got := received.Header.Get("Content-Type"); got != "application/x-www-form-urlencoded" {
+		t.Errorf("Content-Type = %q, want application/x-www-form-urlencoded", got)
+	}
+	want := map[string]string{
+		"from":              "reddit-monitor@mg.example.com",
+		"to":                "team@example.com",
+		"subject":           "Match in r/forhire",
+		"text":              "Found VA & leads: <https://reddit.com/r/forhire/1>",
+		"o:tracking-opens":  "yes",
+		"o:tracking-clicks": "no",
+	}
+	for field, value := range want {
+		if got := form.Get(field); got != value {
+			t.Errorf("form field %s = %q, want %q", field, got, value)
+		}
+	}
+	if html := form.Get("html"); !strings.Contains(html, "&amp;") || strings.Contains(html, "<https://") {
+		t.Errorf("html = %q, want the body HTML-escaped", html)
+	}
+}
+
+func TestMailgunNotifierOmitsUnsetTracking(t *testing.T) {
+	server, _, form := mailgunServer(t, http.StatusOK)
+	n := &MailgunNotifier{
+		config:    MailgunConfig{APIKey: "key-123", Domain: "mg.example.com", From: "alerts@example.com"},
+		recipient: "team@example.com",
+		endpoint:  server.URL + "/v3/mg.example.com/messages",
+		client:    server.Client(),
+	}
+	if err := n.NotifyHTML("Weekly report", "plain", "<p>report</p>"); err != nil {
+		t.Fatalf("NotifyHTML: %v", err)
+	}
+	if form.Get("from") != "alerts@example.com" || form.Get("html") != "<p>report</p>" {
+		t.Errorf("form = %v, want the configured sender and the report's HTML", *form)
+	}
+	for _, field := range []string{"o:tracking-opens", "o:tracking-clicks"} {
+		if form.Has(field) {
+			t.Errorf("form has %s, want the domain's setting kept", field)
+		}
+	}
+}
+
+func TestMailgunNotifierRejected(t *testing.T) {
+	server, _, _ := mailgunServer(t, http.StatusUnauthorized)
+	n := &MailgunNotifier{
+		config:    MailgunConfig{APIKey: "wrong", Domain: "mg.example.com"},
+		recipient: "team@example.com",
+		endpoint:  server.URL + "/v3/mg.example.com/messages",
+		client:    server.Client(),
+	}
+	if err := n.Notify("subject", "body"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Notify = %v, want the 401", err)
+	}
+}
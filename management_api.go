@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// --- Management API ---
+
+// Management API Configuration (Read from Environment Variables)
+var managementAPIPort = os.Getenv("MANAGEMENT_API_PORT")                 // The API only starts when set
+var managementAPIKey = os.Getenv("MANAGEMENT_API_KEY")                   // Basic auth password, any username
+var managementAPIPersist = os.Getenv("MANAGEMENT_API_PERSIST") == "true" // Also write changes to the config file
+
+// requireBasicAuth wraps a handler with basic authentication against MANAGEMENT_API_KEY.
+func requireBasicAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(managementAPIKey)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="rmonitor"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// persistManagedField writes a change to the config file when MANAGEMENT_API_PERSIST is set,
+// otherwise it only lasts until the next restart or config reload.
+func persistManagedField(key string, value interface{}) {
+	if !managementAPIPersist {
+		return
+	}
+	if err := persistConfigField(key, value); err != nil {
+		fmt.Printf("Error persisting %s: %v\n", key, err)
+	}
+}
+
+// keywordMatchesName reports whether spec is the keyword named in a DELETE path: its keyword
+// (case-insensitively), its "re:" pattern or its label.
+func keywordMatchesName(spec KeywordSpec, name string) bool {
+	if spec.Keyword != "" && strings.EqualFold(spec.Keyword, name) {
+		return true
+	}
+	return (spec.Pattern != "" && "re:"+spec.Pattern == name) || spec.label() == name
+}
+
+// handleListKeywords serves GET /keywords.
+func handleListKeywords(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	current := keywords
+	configMu.RUnlock()
+	writeJSON(w, http.StatusOK, current)
+}
+
+// handleAddKeyword serves POST /keywords, adding one keyword ("VA", "re:..." or an object).
+func handleAddKeyword(w http.ResponseWriter, r *http.Request) {
+	var spec KeywordSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := spec.validate(); err != nil {
+		http.Error(w, fmt.Sprintf("keyword %s: %v", spec.label(), err), http.StatusBadRequest)
+		return
+	}
+
+	configMu.Lock()
+	for _, existing := range keywords {
+		if existing.label() == spec.label() {
+			configMu.Unlock()
+			http.Error(w, fmt.Sprintf("keyword %s already exists", spec.label()), http.StatusConflict)
+			return
+		}
+	}
+	before := keywords
+	keywords = append(slices.Clip(keywords), spec)
+	resetKeywordPatterns()
+	after := keywords
+	configMu.Unlock()
+
+	persistManagedField("keywords", after)
+	recordAudit(AuditEntry{Action: "POST /keywords", Before: before, After: after, RemoteAddr: r.RemoteAddr, At: time.Now()})
+	writeJSON(w, http.StatusCreated, spec)
+}
+
+// handleDeleteKeyword serves DELETE /keywords/{keyword}.
+func handleDeleteKeyword(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("keyword")
+
+	configMu.Lock()
+	i := slices.IndexFunc(keywords, func(spec KeywordSpec) bool { return keywordMatchesName(spec, name) })
+	if i < 0 {
+		configMu.Unlock()
+		http.Error(w, fmt.Sprintf("keyword %s not found", name), http.StatusNotFound)
+		return
+	}
+	if len(keywords) == 1 && len(keywordGroups) == 0 {
+		configMu.Unlock()
+		http.Error(w, "at least one keyword is required", http.StatusBadRequest)
+		return
+	}
+	before := keywords
+	keywords = slices.Delete(slices.Clone(keywords), i, i+1)
+	resetKeywordPatterns()
+	after := keywords
+	configMu.Unlock()
+
+	persistManagedField("keywords", after)
+	recordAudit(AuditEntry{Action: "DELETE /keywords/" + name, Before: before, After: after, RemoteAddr: r.RemoteAddr, At: time.Now()})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListSubreddits serves GET /subreddits.
+func handleListSubreddits(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	current := subredditConfigs
+	configMu.RUnlock()
+	writeJSON(w, http.StatusOK, current)
+}
+
+// handleAddSubreddit serves POST /subreddits, adding one subreddit ("name" or an object). The poll
+// jobs are rebuilt once their running cycles have finished.
+func handleAddSubreddit(w http.ResponseWriter, r *http.Request) {
+	var sub SubredditConfig
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if sub.Name == "" {
+		http.Error(w, "subreddit name is required", http.StatusBadRequest)
+		return
+	}
+	if err := sub.validate(); err != nil {
+		http.Error(w, fmt.Sprintf("subreddit %s: %v", sub.Name, err), http.StatusBadRequest)
+		return
+	}
+
+	configMu.Lock()
+	for _, existing := range subredditConfigs {
+		if strings.EqualFold(existing.Name, sub.Name) {
+			configMu.Unlock()
+			http.Error(w, fmt.Sprintf("subreddit %s is already monitored", sub.Name), http.StatusConflict)
+			return
+		}
+	}
+	before := subredditConfigs
+	setSubredditConfigs(append(slices.Clip(subredditConfigs), sub))
+	after := subredditConfigs
+	configMu.Unlock()
+	signalConfigReload()
+
+	persistManagedField("subreddits", after)
+	recordAudit(AuditEntry{Action: "POST /subreddits", Before: before, After: after, RemoteAddr: r.RemoteAddr, At: time.Now()})
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+// handleDeleteSubreddit serves DELETE /subreddits/{name}.
+func handleDeleteSubreddit(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.PathValue("name"), "r/")
+
+	configMu.Lock()
+	i := slices.IndexFunc(subredditConfigs, func(sub SubredditConfig) bool { return strings.EqualFold(sub.Name, name) })
+	if i < 0 {
+		configMu.Unlock()
+		http.Error(w, fmt.Sprintf("subreddit %s is not monitored", name), http.StatusNotFound)
+		return
+	}
+	if len(subredditConfigs) == 1 {
+		configMu.Unlock()
+		http.Error(w, "at least one subreddit is required", http.StatusBadRequest)
+		return
+	}
+	before := subredditConfigs
+	setSubredditConfigs(slices.Delete(slices.Clone(subredditConfigs), i, i+1))
+	after := subredditConfigs
+	configMu.Unlock()
+	signalConfigReload()
+
+	persistManagedField("subreddits", after)
+	recordAudit(AuditEntry{Action: "DELETE /subreddits/" + name, Before: before, After: after, RemoteAddr: r.RemoteAddr, At: time.Now()})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runManagementAPI serves the management API until ctx is cancelled. It does nothing without
+// MANAGEMENT_API_PORT, and refuses to start without MANAGEMENT_API_KEY.
+func runManagementAPI(ctx context.Context) {
+	if managementAPIPort == "" {
+		return
+	}
+	if managementAPIKey == "" {
+		fmt.Println("WARN: MANAGEMENT_API_PORT is set but MANAGEMENT_API_KEY isn't, not starting the management API")
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /keywords", requireBasicAuth(handleListKeywords))
+	mux.HandleFunc("POST /keywords", requireBasicAuth(handleAddKeyword))
+	mux.HandleFunc("DELETE /keywords/{keyword}", requireBasicAuth(handleDeleteKeyword))
+	mux.HandleFunc("GET /subreddits", requireBasicAuth(handleListSubreddits))
+	mux.HandleFunc("POST /subreddits", requireBasicAuth(handleAddSubreddit))
+	mux.HandleFunc("DELETE /subreddits/{name}", requireBasicAuth(handleDeleteSubreddit))
+	addr := ":" + managementAPIPort
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctxShutdown)
+	}()
+
+	fmt.Println("Management API listening on", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("Error running management API: %v\n", err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// --- Matches Subcommand ---
+
+// runMatches prints the items that triggered a notification, newest first, filtered by keyword
+// and subreddit. It only reads from Mongo. Returns the process exit code.
+func runMatches(args []string) int {
+	fs := flag.NewFlagSet("matches", flag.ContinueOnError)
+	since := fs.Duration("since", 24*time.Hour, "only show matches processed within this duration")
+	keyword := fs.String("keyword", "", "only show matches of this keyword")
+	subreddit := fs.String("subreddit", "", "only show matches from this subreddit")
+	limit := fs.Int64("limit", 50, "maximum number of matches to show")
+	page := fs.Int64("page", 1, "page of --limit matches to show, 1 is the newest")
+	search := fs.String("search", "", `text search over titles and texts, most relevant first (words, "quoted phrases", -excluded)`)
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *limit < 1 || *page < 1 {
+		fmt.Println("--limit and --page must be at least 1")
+		return 2
+	}
+	if mongoURI == "" {
+		fmt.Println("FATAL: MONGODB_URI environment variable must be set.")
+		return 1
+	}
+
+	client, err := connectMongo()
+	if err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return 1
+	}
+	defer client.Disconnect(context.Background())
+	// Reads may go to a secondary, the monitor's writes never wait on an audit
+	collection := client.Database(mongoDatabaseName).Collection(processedItemsCollectionName,
+		options.Collection().SetReadPreference(readpref.SecondaryPreferred()))
+
+	findOptions := matchesFindOptions(*search).
+		SetSkip((*page - 1) * *limit).
+		SetLimit(*limit)
+	filter := matchesFilter(time.Now().Add(-*since), *keyword, *subreddit)
+	if *search != "" {
+		filter["$text"] = map[string]interface{}{"$search": *search}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		fmt.Printf("Error querying matches: %v\n", err)
+		return 1
+	}
+	var items []ProcessedItem
+	if err := cursor.All(ctx, &items); err != nil {
+		fmt.Printf("Error decoding matches: %v\n", err)
+		return 1
+	}
+
+	if *search != "" {
+		if !*asJSON {
+			printSearchResults(items, *search)
+			return 0
+		}
+		highlightSearch(items, *search)
+	}
+	format := "table"
+	if *asJSON {
+		format = "json"
+	}
+	return printProcessedItems(items, format)
+}
+
+// matchesFilter selects notified items processed since the given time. Near misses, items skipped as
+// too old and suppressed duplicates never notified, so they are left out. keyword matches a stored
+// label with or without its options, e.g. "VA" matches "VA [title, case-sensitive]".
+func matchesFilter(since time.Time, keyword, subreddit string) map[string]interface{} {
+	filter := map[string]interface{}{
+		"processed_at": map[string]interface{}{"$gte": since},
+		"near_miss":    map[string]interface{}{"$ne": true},
+		"skipped":      map[string]interface{}{"$exists": false},
+		"duplicate_of": map[string]interface{}{"$exists": false},
+	}
+	if keyword != "" {
+		filter["keywords"] = map[string]interface{}{"$regex": "^" + regexp.QuoteMeta(keyword) + `(\s|$)`, "$options": "i"}
+	}
+	if subreddit != "" {
+		filter["subreddit"] = map[string]interface{}{"$regex": "^" + regexp.QuoteMeta(subreddit) + "$", "$options": "i"}
+	}
+	return filter
+}
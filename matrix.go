@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// --- Matrix ---
+
+// Matrix settings from the config file, alerts are sent to the room when all three are set
+var matrixHomeserverURL = ""
+var matrixAccessToken = ""
+var matrixRoomID = ""
+
+// MatrixNotifier sends alerts to a Matrix room through the client-server API
+type MatrixNotifier struct {
+	homeserver string
+	token      string
+	roomID     string
+	client     *http.Client
+}
+
+// NewMatrixNotifier returns a Notifier sending to the configured Matrix room.
+func NewMatrixNotifier() *MatrixNotifier {
+	return &MatrixNotifier{homeserver: strings.TrimSuffix(matrixHomeserverURL, "/"), token: matrixAccessToken, roomID: matrixRoomID, client: httpClient}
+}
+
+// matrixMessage is an m.room.message event with an HTML formatted body
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"` // Plain text, for clients without HTML
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// newTxnID returns a random (version 4) UUID, identifying one message across its retries.
+func newTxnID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// Notify sends the subject in bold followed by the body.
+func (n *MatrixNotifier) Notify(subject, body string) error {
+	formatted := "<b>" + html.EscapeString(subject) + "</b><br>" + strings.ReplaceAll(html.EscapeString(body), "\n", "<br>")
+	return n.send(subject+"\n\n"+body, formatted)
+}
+
+// NotifyAlert sends the subject, subreddit and keywords, the snippet with the matched keywords in
+// bold, and the permalink as a link.
+func (n *MatrixNotifier) NotifyAlert(alert Alert) error {
+	keywords := make([]string, 0, len(alert.Keywords))
+	for _, keyword := range alert.Keywords {
+		keywords = append(keywords, "<b>"+html.EscapeString(keyword)+"</b>")
+	}
+	var formatted strings.Builder
+	fmt.Fprintf(&formatted, "<b>%s</b><br>r/%s: %s", html.EscapeString(alert.Subject), html.EscapeString(alert.Subreddit), strings.Join(keywords, ", "))
+	if alert.Title != "" && alert.Kind == "comment" {
+		fmt.Fprintf(&formatted, "<br>In post: %s", html.EscapeString(alert.Title))
+	}
+	if alert.Snippet != "" {
+		fmt.Fprintf(&formatted, "<blockquote>%s</blockquote>", wrapKeywords(alert.Snippet, alert.Keywords, "b"))
+	}
+	fmt.Fprintf(&formatted, `<a href="%s">Open on Reddit</a>`, html.EscapeString(alert.URL()))
+
+	plain := fmt.Sprintf("%s\nKeywords: %s", alert.Subject, strings.Join(alert.Keywords, ", "))
+	if alert.Snippet != "" {
+		plain += "\n\n" + alert.Snippet
+	}
+	plain += "\n\n" + alert.URL()
+	return n.send(plain, formatted.String())
+}
+
+// send puts the message into the room. The transaction ID is chosen once, so a retry of a request
+// that reached the homeserver doesn't post the message twice. Rate limits (429) and server errors
+// are retried.
+func (n *MatrixNotifier) send(plain, formatted string) error {
+	payload, err := json.Marshal(matrixMessage{MsgType: "m.text", Body: plain, Format: "org.matrix.custom.html", FormattedBody: formatted})
+	if err != nil {
+		return err
+	}
+	txnID, err := newTxnID()
+	if err != nil {
+		return fmt.Errorf("error generating Matrix transaction ID: %w", err)
+	}
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", n.homeserver, url.PathEscape(n.roomID), txnID)
+
+	err = withRetry("Matrix message", func() error {
+		req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+n.token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := n.client.Do(req)
+		if err != nil {
+			return &retryableError{err: err}
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode >= 300 {
+			return statusError(resp, respBody) // 401/403 (bad token, not in the room) aren't retried
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send to Matrix: %w", err)
+	}
+	fmt.Println("Notification sent to Matrix")
+	return nil
+}
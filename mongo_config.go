@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Mongo Config ---
+
+// configCollectionName holds the shared config of every instance in Mongo config mode
+const configCollectionName = "config"
+
+// storedConfigID is the _id of the single config document
+const storedConfigID = "config"
+
+// mongoConfigEnabled reads the config from the config collection instead of a file, so
+// several instances share one config. Set CONFIG_SOURCE=mongo to enable.
+var mongoConfigEnabled = os.Getenv("CONFIG_SOURCE") == "mongo"
+
+// configCollection is the config collection handle, set in main when mongoConfigEnabled
+var configCollection *mongo.Collection
+
+// StoredConfig is the config document. The config is kept as JSON text, in the config file's format.
+type StoredConfig struct {
+	ID        string    `bson:"_id"`
+	JSON      string    `bson:"json"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// fetchStoredConfig returns the config document, nil if none has been stored yet.
+func fetchStoredConfig(ctx context.Context, coll *mongo.Collection) (*StoredConfig, error) {
+	var stored StoredConfig
+	err := coll.FindOne(ctx, map[string]interface{}{"_id": storedConfigID}).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config collection: %w", err)
+	}
+	return &stored, nil
+}
+
+// saveStoredConfig validates data and replaces the config document with it.
+func saveStoredConfig(ctx context.Context, coll *mongo.Collection, data []byte) error {
+	if _, err := parseConfig(data, "config"); err != nil {
+		return err
+	}
+	stored := StoredConfig{ID: storedConfigID, JSON: string(data), UpdatedAt: time.Now().UTC()}
+	_, err := coll.ReplaceOne(ctx, map[string]interface{}{"_id": storedConfigID}, stored, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("error writing config collection: %w", err)
+	}
+	return nil
+}
+
+// setStoredConfigField writes value under key in the stored config, keeping its other settings.
+func setStoredConfigField(key string, value json.RawMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stored, err := fetchStoredConfig(ctx, configCollection)
+	if err != nil {
+		return err
+	}
+	fields := map[string]json.RawMessage{}
+	if stored != nil {
+		if err := json.Unmarshal([]byte(stored.JSON), &fields); err != nil {
+			return fmt.Errorf("failed to parse stored config: %w", err)
+		}
+	}
+	fields[key] = value
+	data, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return err
+	}
+	return saveStoredConfig(ctx, configCollection, data)
+}
+
+// loadMongoConfig applies the stored config at startup. Without a stored config the file config is kept.
+func loadMongoConfig() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stored, err := fetchStoredConfig(ctx, configCollection)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		fmt.Println("WARN: CONFIG_SOURCE=mongo but the config collection is empty, use `config set` to store one")
+		return nil
+	}
+	cfg, err := parseConfig([]byte(stored.JSON), "stored config")
+	if err != nil {
+		return err
+	}
+	applyConfig(cfg)
+	lastStoredConfigUpdate = stored.UpdatedAt
+	fmt.Println("Loaded config from the config collection, updated", stored.UpdatedAt.Local().Format(time.RFC3339))
+	return nil
+}
+
+// lastStoredConfigUpdate is the updated_at of the config last applied, so polling only reloads on changes
+var lastStoredConfigUpdate time.Time
+
+// reloadMongoConfig re-reads the stored config and swaps it in if it changed.
+func reloadMongoConfig() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stored, err := fetchStoredConfig(ctx, configCollection)
+	if err != nil {
+		fmt.Printf("Error reloading config, keeping the current one: %v\n", err)
+		return
+	}
+	if stored == nil || stored.UpdatedAt.Equal(lastStoredConfigUpdate) {
+		return
+	}
+	lastStoredConfigUpdate = stored.UpdatedAt
+	cfg, err := parseConfig([]byte(stored.JSON), "stored config")
+	if err != nil {
+		fmt.Printf("Error reloading config, keeping the current one: %v\n", err)
+		return
+	}
+	swapConfig(cfg, "stored config")
+}
+
+// watchMongoConfig reloads the stored config whenever it changes until ctx is done. Change streams
+// need a replica set, other deployments fall back to polling every minPollInterval, which is shorter
+// than any poll job's interval.
+func watchMongoConfig(ctx context.Context) {
+	stream, err := configCollection.Watch(ctx, mongo.Pipeline{})
+	if err == nil {
+		fmt.Println("Watching the config collection for changes")
+		for stream.Next(ctx) {
+			reloadMongoConfig()
+		}
+		err = stream.Err()
+		stream.Close(context.Background())
+		if ctx.Err() != nil {
+			return
+		}
+	}
+	fmt.Printf("WARN: Config change stream unavailable (%v), polling the config collection every %v\n", err, minPollInterval)
+
+	ticker := time.NewTicker(minPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloadMongoConfig()
+		}
+	}
+}
+
+// --- Config Subcommand ---
+
+// runConfigCommand gets or sets the stored config:
+//
+//	config get         print the stored config
+//	config set FILE    validate FILE (- for stdin) and store it
+//
+// Returns the process exit code.
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || (args[0] == "set" && len(args) != 2) || (args[0] != "get" && args[0] != "set") {
+		fmt.Println("Usage: config get | config set FILE (- reads stdin)")
+		return 2
+	}
+	if mongoURI == "" {
+		fmt.Println("FATAL: MONGODB_URI environment variable must be set.")
+		return 1
+	}
+
+	client, err := connectMongo()
+	if err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return 1
+	}
+	defer client.Disconnect(context.Background())
+	coll := client.Database(mongoDatabaseName).Collection(configCollectionName)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if args[0] == "get" {
+		stored, err := fetchStoredConfig(ctx, coll)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		if stored == nil {
+			fmt.Println("No config stored.")
+			return 1
+		}
+		fmt.Println(stored.JSON)
+		return 0
+	}
+
+	var data []byte
+	if args[1] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[1])
+	}
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", args[1], err)
+		return 1
+	}
+	if err := saveStoredConfig(ctx, coll, bytes.TrimSpace(data)); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Println("Stored config, running instances reload it within", minPollInterval)
+	return 0
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// --- NATS JetStream ---
+
+// NATS settings from the config file, match events are published to JetStream when natsURL is set
+var natsURL = ""
+var natsSubjectPrefix = "reddit.match"
+
+// natsStreamName is the JetStream stream created at startup for natsSubjectPrefix.>, unless it
+// exists already
+const natsStreamName = "REDDIT_MATCHES"
+
+// natsStreamMaxAge is how long the stream keeps match events
+const natsStreamMaxAge = 7 * 24 * time.Hour
+
+// natsDuplicateWindow is how long JetStream dedupes a resent event by its Nats-Msg-Id
+const natsDuplicateWindow = 2 * time.Minute
+
+const (
+	natsTimeout           = 10 * time.Second
+	natsMaxReconnectDelay = time.Minute
+)
+
+// natsConn is the publisher shared by every NATSNotifier, connected at startup and flushed and
+// closed on shutdown
+var natsConn = &natsPublisher{}
+
+// NATSNotifier publishes a MatchEvent for every alert to JetStream
+type NATSNotifier struct {
+	publisher *natsPublisher
+}
+
+// NewNATSNotifier returns a Notifier publishing to the configured NATS server.
+func NewNATSNotifier() *NATSNotifier {
+	return &NATSNotifier{publisher: natsConn}
+}
+
+// Notify does nothing: the stream carries match events only, digests and summaries go to the
+// other channels.
+func (n *NATSNotifier) Notify(subject, body string) error {
+	return nil
+}
+
+// NotifyBatch publishes an event for every alert of a batched cycle.
+func (n *NATSNotifier) NotifyBatch(subject, body string, alerts []Alert) error {
+	errs := []error{}
+	for _, alert := range alerts {
+		if err := n.NotifyAlert(alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyAlert publishes the match to {prefix}.{subreddit}.{keyword} and waits for JetStream to
+// store it. Resends carry the same Nats-Msg-Id, so a retry after a lost ack isn't stored twice.
+func (n *NATSNotifier) NotifyAlert(alert Alert) error {
+	body, err := json.Marshal(MatchEvent{SchemaVersion: matchEventSchemaVersion, NotificationData: notificationData(alert)})
+	if err != nil {
+		return err
+	}
+	subject := natsSubject(natsSubjectPrefix, alert)
+	var seq uint64
+	err = withRetry("NATS publish", func() error {
+		var err error
+		seq, err = n.publisher.publish(subject, matchEventID(alert), body)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to NATS subject %s: %w", subject, err)
+	}
+	fmt.Printf("Match event published to NATS subject %s (stream sequence %d)\n", subject, seq)
+	return nil
+}
+
+// natsSubject returns prefix.subreddit.keyword for alert. A match of several keywords is
+// published once, under its first keyword.
+func natsSubject(prefix string, alert Alert) string {
+	keyword := ""
+	if len(alert.Keywords) > 0 {
+		keyword = keywordLabelName(alert.Keywords[0])
+	}
+	token := func(s string) string {
+		if word := routingKeyWord(s); word != "" {
+			return word
+		}
+		return "_" // Subjects can't have empty tokens
+	}
+	return prefix + "." + token(alert.Subreddit) + "." + token(keyword)
+}
+
+// validNATSSubjectPrefix reports whether prefix is a subject without wildcards or empty tokens.
+func validNATSSubjectPrefix(prefix string) bool {
+	for _, token := range strings.Split(prefix, ".") {
+		if token == "" || token == "*" || token == ">" || strings.ContainsAny(token, " \t\r\n") {
+			return false
+		}
+	}
+	return true
+}
+
+// natsPublisher publishes to JetStream over one connection, waiting for every publish to be
+// acknowledged. The client reconnects a dropped connection in the background, backing off from 1s
+// to natsMaxReconnectDelay between attempts.
+type natsPublisher struct {
+	mu       sync.Mutex
+	conn     *nats.Conn
+	js       jetstream.JetStream
+	settings string // URL and prefix the connection was opened with
+	streamOK bool   // The stream was found or created on this connection
+	closed   bool
+}
+
+// start connects and creates the stream in the background, so startup doesn't wait on the server.
+func (p *natsPublisher) start() {
+	go func() {
+		if _, err := p.jetStream(); err != nil {
+			fmt.Printf("WARN: NATS JetStream setup failed, retrying on the next publish: %v\n", err)
+		}
+	}()
+}
+
+// natsSettings identifies the configured server and subject prefix.
+func natsSettings() string {
+	return natsURL + " " + natsSubjectPrefix
+}
+
+// jetStream returns the JetStream context, connecting first if there is no connection or the
+// settings changed (config reload), and creating the stream if it wasn't found yet.
+func (p *natsPublisher) jetStream() (jetstream.JetStream, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, errors.New("NATS connection is closed")
+	}
+	if settings := natsSettings(); p.conn == nil || p.settings != settings {
+		if p.conn != nil {
+			p.conn.Close()
+			p.conn, p.js = nil, nil
+		}
+		// The URL carries the credentials (user:pass@ or token@), tls:// turns on TLS
+		conn, err := nats.Connect(natsURL,
+			nats.Name("reddit_monitor"),
+			nats.Timeout(natsTimeout),
+			nats.RetryOnFailedConnect(true), // Connect returns at once and keeps trying in the background
+			nats.MaxReconnects(-1),
+			nats.CustomReconnectDelay(func(attempts int) time.Duration {
+				return min(time.Second<<min(attempts, 6), natsMaxReconnectDelay)
+			}),
+			nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+				if err != nil {
+					fmt.Printf("WARN: NATS connection lost, reconnecting: %v\n", err)
+				}
+			}),
+			nats.ReconnectHandler(func(*nats.Conn) {
+				fmt.Println("Reconnected to NATS.")
+			}),
+		)
+		if err != nil {
+			return nil, err
+		}
+		js, err := jetstream.New(conn)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		p.conn, p.js, p.settings, p.streamOK = conn, js, settings, false
+	}
+	if !p.conn.IsConnected() {
+		return nil, errors.New("not connected to NATS, reconnecting")
+	}
+	if !p.streamOK {
+		if err := p.ensureStream(); err != nil {
+			return nil, err
+		}
+		p.streamOK = true
+		fmt.Printf("Connected to NATS, publishing to JetStream under %s.>\n", natsSubjectPrefix)
+	}
+	return p.js, nil
+}
+
+// ensureStream creates natsStreamName capturing natsSubjectPrefix.> with natsStreamMaxAge
+// retention, unless a stream of that name exists. An existing stream is left as configured.
+// p.mu must be held.
+func (p *natsPublisher) ensureStream() error {
+	ctx, cancel := context.WithTimeout(context.Background(), natsTimeout)
+	defer cancel()
+	_, err := p.js.Stream(ctx, natsStreamName)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		_, err = p.js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:       natsStreamName,
+			Subjects:   []string{natsSubjectPrefix + ".>"},
+			Retention:  jetstream.LimitsPolicy,
+			MaxAge:     natsStreamMaxAge,
+			Storage:    jetstream.FileStorage,
+			Discard:    jetstream.DiscardOld,
+			Duplicates: natsDuplicateWindow,
+			Replicas:   1,
+		})
+		if err != nil {
+			return fmt.Errorf("creating stream %s: %w", natsStreamName, err)
+		}
+		fmt.Printf("Created NATS JetStream stream %s for %s.> (events kept %v)\n", natsStreamName, natsSubjectPrefix, natsStreamMaxAge)
+		return nil
+	}
+	if errors.Is(err, jetstream.ErrJetStreamNotEnabled) {
+		return errors.New("JetStream is not enabled on the NATS server")
+	}
+	if err != nil {
+		return fmt.Errorf("looking up stream %s: %w", natsStreamName, err)
+	}
+	return nil
+}
+
+// publish publishes body to subject with msgID as its Nats-Msg-Id and returns the stream sequence
+// JetStream stored it at. Without a connection it fails at once with a retryable error, the client
+// reconnecting in the background.
+func (p *natsPublisher) publish(subject, msgID string, body []byte) (uint64, error) {
+	js, err := p.jetStream()
+	if err != nil {
+		return 0, &retryableError{err: err}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), natsTimeout)
+	defer cancel()
+	ack, err := js.Publish(ctx, subject, body, jetstream.WithMsgID(msgID))
+	if errors.Is(err, jetstream.ErrNoStreamResponse) {
+		return 0, fmt.Errorf("no JetStream stream captures %s (is JetStream enabled?)", subject)
+	}
+	var apiErr *jetstream.APIError
+	if errors.As(err, &apiErr) && apiErr.Code != 503 {
+		return 0, err
+	}
+	if err != nil {
+		return 0, &retryableError{err: err}
+	}
+	return ack.Sequence, nil
+}
+
+// close flushes the connection and closes it, on shutdown.
+func (p *natsPublisher) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.conn == nil {
+		return
+	}
+	if p.conn.IsConnected() {
+		if err := p.conn.FlushTimeout(5 * time.Second); err != nil {
+			fmt.Printf("WARN: Could not flush the NATS connection: %v\n", err)
+		}
+	}
+	p.conn.Close()
+	p.conn, p.js = nil, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// --- Text Normalization ---
+
+// Markdown patterns stripped before keyword matching. Go's RE2 has no backreferences,
+// so each emphasis marker gets its own pattern.
+var (
+	markdownCodeFence  = regexp.MustCompile("(?m)^\\s*(```|~~~)[^\\n]*$")            // Fence lines, content is kept
+	markdownInlineCode = regexp.MustCompile("`([^`\\n]*)`")                          // `code`
+	markdownImageLink  = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)               // [text](url) and ![alt](url)
+	markdownBold       = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)                     // **bold**
+	markdownBoldUnder  = regexp.MustCompile(`(^|\W)__([^_\n]+)__(\W|$)`)             // __bold__
+	markdownItalic     = regexp.MustCompile(`\*([^*\n]+)\*`)                         // *italic*
+	markdownItalicUnd  = regexp.MustCompile(`(^|\W)_([^_\n]+)_(\W|$)`)               // _italic_, but not snake_case
+	markdownStrike     = regexp.MustCompile(`~~([^~\n]+)~~`)                         // ~~strike~~
+	markdownSpoiler    = regexp.MustCompile(`>!([^\n]*?)!<`)                         // >!spoiler!<
+	markdownLinePrefix = regexp.MustCompile(`(?m)^\s*(#{1,6}|>+|[*+-]|\d+\.)[ \t]+`) // Headings, quotes, list bullets
+	markdownSuperEsc   = regexp.MustCompile(`\^|\\([\\*_~#>\[\]()!])`)               // ^superscript and \-escaped characters
+)
+
+// normalizeText prepares Reddit text for keyword matching: HTML entities are unescaped,
+// markdown syntax is stripped (keeping link text) and whitespace is collapsed.
+// The result is only used for matching, notifications keep the original text.
+func normalizeText(text string) string {
+	text = html.UnescapeString(text)
+	text = markdownCodeFence.ReplaceAllString(text, "")
+	text = markdownInlineCode.ReplaceAllString(text, "$1")
+	text = markdownImageLink.ReplaceAllString(text, "$1")
+	text = markdownSpoiler.ReplaceAllString(text, "$1")
+	text = markdownBold.ReplaceAllString(text, "$1")
+	text = markdownBoldUnder.ReplaceAllString(text, "${1}${2}${3}")
+	text = markdownItalic.ReplaceAllString(text, "$1")
+	text = markdownItalicUnd.ReplaceAllString(text, "${1}${2}${3}")
+	text = markdownStrike.ReplaceAllString(text, "$1")
+	text = markdownLinePrefix.ReplaceAllString(text, "")
+	text = markdownSuperEsc.ReplaceAllString(text, "$1")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// foldDiacritics decomposes text (NFKD), drops combining marks and recomposes it,
+// so "café" becomes "cafe" and compatibility forms like ligatures become plain letters.
+var foldDiacritics = transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// foldQuotes turns curly quotes and apostrophes into straight ones, so "don't" matches "don’t"
+var foldQuotes = strings.NewReplacer("‘", "'", "’", "'", "‚", "'", "‛", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`)
+
+// foldText strips diacritics and straightens quotes in text when unicode normalization is enabled.
+func foldText(text string) string {
+	if !normalizeUnicode {
+		return text
+	}
+	folded, _, err := transform.String(foldDiacritics, foldQuotes.Replace(text))
+	if err != nil {
+		return text
+	}
+	return folded
+}
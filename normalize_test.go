@@ -0,0 +1,96 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"entities", "Q&amp;A &gt; 5 &lt;3 &quot;VA&quot;", `Q&A > 5 <3 "VA"`},
+		{"link keeps its text", "Hiring: [VA leads](https://example.com/jobs?a=1&b=2) today", "Hiring: VA leads today"},
+		{"image alt text", "![VA badge](https://i.redd.it/x.png)", "VA badge"},
+		{"bold and italic", "**VA** needed, *urgent*, __remote__, _part time_", "VA needed, urgent, remote, part time"},
+		{"snake_case is kept", "set max_post_age in config_file", "set max_post_age in config_file"},
+		{"strike and spoiler", "~~closed~~ >!still open!<", "closed still open"},
+		{"inline code", "run `go test` first", "run go test first"},
+		{"code fence keeps its content", "before\n```go\nfmt.Println(\"VA\")\n```\nafter", `before fmt.Println("VA") after`},
+		{"headings, quotes and bullets", "# Hiring\n> quoted VA\n- one\n* two\n1. three", "Hiring quoted VA one two three"},
+		{"escapes and superscript", `\#1 \[VA\] x^2`, "#1 [VA] x2"},
+		{"whitespace collapsed", "  VA \n\n\t leads  ", "VA leads"},
+		{"plain text unchanged", "Looking for a VA", "Looking for a VA"},
+	}
+	for _, tt := range tests {
+		if got := normalizeText(tt.text); got != tt.want {
+			t.Errorf("%s: normalizeText(%q) = %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFindKeywordsInRedditMarkup(t *testing.T) {
+	keywords := []KeywordSpec{{Keyword: "VA"}, {Keyword: "VA leads"}}
+	tests := []struct {
+		name, body string
+		want       []string
+	}{
+		{"markdown link", "Selling [VA leads](https://example.com/leads)", []string{"VA", "VA leads"}},
+		{"entities around the keyword", "&amp;VA&amp;", []string{"VA"}},
+		{"bold keyword", "Need a **VA** asap", []string{"VA"}},
+		{"keyword split by emphasis", "**VA** *leads* for sale", []string{"VA", "VA leads"}},
+		{"keyword split by a newline", "VA\nleads", []string{"VA", "VA leads"}},
+		{"inside a word", "Java developer", []string{}},
+	}
+	for _, tt := range tests {
+		if got := findKeywords("", tt.body, keywords); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: findKeywords(%q) = %q, want %q", tt.name, tt.body, got, tt.want)
+		}
+	}
+}
+
+// withNormalizeUnicode sets normalizeUnicode for the test, dropping the patterns compiled with the other setting.
+func withNormalizeUnicode(t *testing.T, enabled bool) {
+	old := normalizeUnicode
+	normalizeUnicode = enabled
+	resetKeywordPatterns()
+	t.Cleanup(func() {
+		normalizeUnicode = old
+		resetKeywordPatterns()
+	})
+}
+
+func TestFindKeywordsAccentsAndQuotes(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyword   string
+		body      string
+		want      bool // With normalization, the default
+		wantExact bool // With normalize_unicode off
+	}{
+		{"accented text, plain keyword", "cafe", "Opening a café downtown", true, false},
+		{"plain text, accented keyword", "café", "Opening a cafe downtown", true, false},
+		{"accents on both sides", "ingénieur", "Poste d'ingénieur logiciel", true, true},
+		{"ligature", "office", "New ofﬁce space", true, false},
+		{"keyword in smart quotes", "VA", "Looking for a “VA” to help", true, true},
+		{"keyword in single smart quotes", "VA", "Looking for a ‘VA’ to help", true, true},
+		{"keyword after an em-dash", "VA", "Hiring—VA—remote", true, true},
+		{"straight apostrophe keyword, curly text", "don't", "I don’t need a VA", true, false},
+		{"curly apostrophe keyword, straight text", "don’t", "I don't need a VA", true, false},
+		{"straight quotes keyword, curly text", `"VA leads"`, "Selling “VA leads” cheap", true, false},
+		{"accent doesn't join words", "cafe", "cafeteria", false, false},
+	}
+	for _, enabled := range []bool{true, false} {
+		withNormalizeUnicode(t, enabled)
+		for _, tt := range tests {
+			want := tt.want
+			if !enabled {
+				want = tt.wantExact
+			}
+			got := len(findKeywords("", tt.body, []KeywordSpec{{Keyword: tt.keyword}})) > 0
+			if got != want {
+				t.Errorf("%s (normalize_unicode %v): %q in %q matched %v, want %v", tt.name, enabled, tt.keyword, tt.body, got, want)
+			}
+		}
+	}
+}
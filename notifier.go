@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// --- Notifiers ---
+
+// Notifier delivers match alerts
+type Notifier interface {
+	Notify(subject, body string) error
+}
+
+// HTMLNotifier is a Notifier that can send a report with its own HTML version, such as a table,
+// instead of one rendered from the plain text
+type HTMLNotifier interface {
+	NotifyHTML(subject, text, htmlText string) error
+}
+
+// notifyHTML sends the report through notifier, as plain text if it doesn't send HTML.
+func notifyHTML(notifier Notifier, subject, text, htmlText string) (err error) {
+	defer recoverPanic(fmt.Sprintf("notifier %T", notifier), &err)
+	if htmlNotifier, ok := notifier.(HTMLNotifier); ok {
+		return htmlNotifier.NotifyHTML(subject, text, htmlText)
+	}
+	return notifier.Notify(subject, text)
+}
+
+// EmailNotifier sends alerts by email through the configured Gmail account
+type EmailNotifier struct {
+	recipient string
+}
+
+// newNotifier returns the Notifier emailing recipient through the active email provider, see activeEmailProvider.
+// With --log-notifications it is a LogNotifier.
+func newNotifier(recipient string) Notifier {
+	if logNotifications {
+		return LogNotifier{recipient: recipient}
+	}
+	switch activeEmailProvider() {
+	case "mailgun":
+		return NewMailgunNotifier(recipient)
+	case "sendgrid":
+		return NewSendGridNotifier(recipient)
+	case "ses":
+		return NewSESNotifier(recipient)
+	}
+	return NewEmailNotifier(recipient)
+}
+
+// NewEmailNotifier returns a Notifier emailing recipient.
+func NewEmailNotifier(recipient string) *EmailNotifier {
+	return &EmailNotifier{recipient: recipient}
+}
+
+// channel names the notifier after its recipient, see channelName.
+func (n *EmailNotifier) channel() string { return "email " + n.recipient }
+
+// Notify emails the alert to the recipient.
+func (n *EmailNotifier) Notify(subject, body string) error {
+	return sendEmailTo(n.recipient, subject, body)
+}
+
+// NotifyHTML emails a report as plain text with its own HTML alternative.
+func (n *EmailNotifier) NotifyHTML(subject, text, htmlText string) error {
+	message := emailMessage{From: gmailUser, To: n.recipient, Subject: subject, Text: text, HTML: htmlText}
+	return sendSMTPMessage(n.recipient, message.mimeData())
+}
+
+// safeNotify calls notifier.Notify, turning a panic into an error.
+func safeNotify(notifier Notifier, subject, body string) (err error) {
+	defer recoverPanic(fmt.Sprintf("notifier %T", notifier), &err)
+	return notifier.Notify(subject, body)
+}
+
+// namedChannel is a Notifier that names its channel itself, such as an email notifier per recipient
+type namedChannel interface {
+	channel() string
+}
+
+// channelName names the channel notifier delivers to, e.g. "ntfy" or "email alice@example.com".
+// Failed alerts are retried on their failed channels only, by name.
+func channelName(notifier Notifier) string {
+	if named, ok := notifier.(namedChannel); ok {
+		return named.channel()
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(fmt.Sprintf("%T", notifier), "*"), "main.")
+	return strings.ToLower(strings.TrimSuffix(name, "Notifier"))
+}
+
+// channelError is the failure of one channel of a MultiNotifier
+type channelError struct {
+	channel string
+	err     error
+}
+
+func (e *channelError) Error() string { return e.channel + ": " + e.err.Error() }
+func (e *channelError) Unwrap() error { return e.err }
+
+// MultiNotifier sends every alert through each of its notifiers. Failures are returned per channel,
+// see channelError.
+type MultiNotifier []Notifier
+
+// channelErr names the channel of a failed notifier, nested MultiNotifiers name their own.
+func channelErr(notifier Notifier, err error) error {
+	if _, ok := notifier.(MultiNotifier); ok || err == nil {
+		return err
+	}
+	return &channelError{channel: channelName(notifier), err: err}
+}
+
+// Notify sends the alert through every notifier, returning the errors of those that failed.
+func (m MultiNotifier) Notify(subject, body string) error {
+	errs := []error{}
+	for _, notifier := range m {
+		if err := safeNotify(notifier, subject, body); err != nil {
+			errs = append(errs, channelErr(notifier, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyAlert sends the alert through every notifier, returning the errors of those that failed.
+func (m MultiNotifier) NotifyAlert(alert Alert) error {
+	return m.notifyChannels(alert, nil)
+}
+
+// notifyChannels sends the alert through the notifiers named in channels, or all of them if
+// channels is empty, returning the errors of those that failed.
+func (m MultiNotifier) notifyChannels(alert Alert, channels []string) error {
+	errs := []error{}
+	for _, notifier := range m {
+		if nested, ok := notifier.(MultiNotifier); ok {
+			if err := nested.notifyChannels(alert, channels); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if len(channels) > 0 && !slices.Contains(channels, channelName(notifier)) {
+			continue
+		}
+		if err := notifyAlert(notifier, alert); err != nil {
+			errs = append(errs, channelErr(notifier, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyHTML sends the report through every notifier, returning the errors of those that failed.
+func (m MultiNotifier) NotifyHTML(subject, text, htmlText string) error {
+	errs := []error{}
+	for _, notifier := range m {
+		if err := notifyHTML(notifier, subject, text, htmlText); err != nil {
+			errs = append(errs, channelErr(notifier, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyBatch sends the batched alerts through every notifier and joins their errors.
+func (m MultiNotifier) NotifyBatch(subject, body string, alerts []Alert) error {
+	errs := []error{}
+	for _, notifier := range m {
+		if err := notifyBatch(notifier, subject, body, alerts); err != nil {
+			errs = append(errs, channelErr(notifier, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notifyAlertChannels sends alert through the channels of notifier named in channels, or all of
+// them if channels is empty. A notifier that isn't a MultiNotifier is a single channel and always sends.
+func notifyAlertChannels(notifier Notifier, alert Alert, channels []string) error {
+	switch n := notifier.(type) {
+	case healthNotifier:
+		err := notifyAlertChannels(n.Notifier, alert, channels)
+		health.record(subsystemNotifications, err)
+		return err
+	case MultiNotifier:
+		return n.notifyChannels(alert, channels)
+	}
+	return notifyAlert(notifier, alert)
+}
+
+// channelFailure is a failed alert's failure on some channels, every channel if channels is empty
+type channelFailure struct {
+	channels []string
+	err      error
+}
+
+// splitFailure splits the error of a failed alert into the channels worth retrying and those
+// failing with a permanentError, nil if none failed that way. An error that doesn't name its
+// channels, from a single notifier, is a failure of every channel.
+func splitFailure(err error) (retry, permanent *channelFailure) {
+	if err == nil {
+		return nil, nil
+	}
+	var failed []*channelError
+	var walk func(error)
+	walk = func(err error) {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				walk(e)
+			}
+			return
+		}
+		var ce *channelError
+		if errors.As(err, &ce) {
+			failed = append(failed, ce)
+		}
+	}
+	walk(err)
+	if len(failed) == 0 {
+		if errors.As(err, new(*permanentError)) {
+			return nil, &channelFailure{err: err}
+		}
+		return &channelFailure{err: err}, nil
+	}
+
+	var retryErrs, permanentErrs []error
+	for _, ce := range failed {
+		if errors.As(ce.err, new(*permanentError)) {
+			permanent = addFailure(permanent, ce)
+			permanentErrs = append(permanentErrs, ce)
+		} else {
+			retry = addFailure(retry, ce)
+			retryErrs = append(retryErrs, ce)
+		}
+	}
+	if retry != nil {
+		retry.err = errors.Join(retryErrs...)
+	}
+	if permanent != nil {
+		permanent.err = errors.Join(permanentErrs...)
+	}
+	return retry, permanent
+}
+
+func addFailure(failure *channelFailure, ce *channelError) *channelFailure {
+	if failure == nil {
+		failure = &channelFailure{}
+	}
+	if !slices.Contains(failure.channels, ce.channel) {
+		failure.channels = append(failure.channels, ce.channel)
+	}
+	return failure
+}
+
+// mergeFailures combines two failures of the same alert, either may be nil.
+func mergeFailures(a, b *channelFailure) *channelFailure {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+	merged := &channelFailure{err: errors.Join(a.err, b.err)}
+	if len(a.channels) > 0 && len(b.channels) > 0 {
+		merged.channels = append(slices.Clone(a.channels), b.channels...)
+	}
+	return merged
+}
+
+// channelList describes channels for logs, "all channels" if empty.
+func channelList(channels []string) string {
+	if len(channels) == 0 {
+		return "all channels"
+	}
+	return strings.Join(channels, ", ")
+}
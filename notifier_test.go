@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// fakeChannel is a notifier named name that fails with err, counting its sends
+type fakeChannel struct {
+	name  string
+	err   error
+	sends int
+}
+
+func (f *fakeChannel) channel() string { return f.name }
+
+func (f *fakeChannel) Notify(subject, body string) error {
+	f.sends++
+	return f.err
+}
+
+func TestNotifyAlertChannelsRetriesFailedChannelsOnly(t *testing.T) {
+	email := &fakeChannel{name: "email a@example.com"}
+	ntfy := &fakeChannel{name: "ntfy", err: errors.New("ntfy down")}
+	sms := &fakeChannel{name: "sms", err: &permanentError{err: errors.New("invalid number")}}
+	notifier := healthNotifier{MultiNotifier{MultiNotifier{email}, ntfy, sms}}
+
+	err := notifyAlertChannels(notifier, Alert{Subject: "match"}, nil)
+	retry, permanent := splitFailure(err)
+	if retry == nil || !slices.Equal(retry.channels, []string{"ntfy"}) {
+		t.Fatalf("retry = %+v, want ntfy", retry)
+	}
+	if permanent == nil || !slices.Equal(permanent.channels, []string{"sms"}) {
+		t.Fatalf("permanent = %+v, want sms", permanent)
+	}
+
+	if err := notifyAlertChannels(notifier, Alert{Subject: "match"}, retry.channels); err == nil {
+		t.Error("retrying ntfy succeeded, want its error")
+	}
+	if email.sends != 1 || ntfy.sends != 2 || sms.sends != 1 {
+		t.Errorf("sends: email %d, ntfy %d, sms %d, want 1, 2, 1", email.sends, ntfy.sends, sms.sends)
+	}
+}
+
+func TestSplitFailureOfSingleNotifier(t *testing.T) {
+	retry, permanent := splitFailure(errors.New("smtp down"))
+	if retry == nil || len(retry.channels) != 0 || permanent != nil {
+		t.Errorf("splitFailure = %+v, %+v, want a retry of every channel", retry, permanent)
+	}
+	retry, permanent = splitFailure(&permanentError{err: errors.New("rejected")})
+	if retry != nil || permanent == nil || len(permanent.channels) != 0 {
+		t.Errorf("splitFailure = %+v, %+v, want every channel failing permanently", retry, permanent)
+	}
+	if retry, permanent := splitFailure(nil); retry != nil || permanent != nil {
+		t.Errorf("splitFailure(nil) = %+v, %+v, want nil", retry, permanent)
+	}
+}
+
+func TestChannelName(t *testing.T) {
+	tests := []struct {
+		notifier Notifier
+		want     string
+	}{
+		{NewEmailNotifier("a@example.com"), "email a@example.com"},
+		{&NtfyNotifier{}, "ntfy"},
+		{&SMSNotifier{}, "sms"},
+	}
+	for _, tt := range tests {
+		if got := channelName(tt.notifier); got != tt.want {
+			t.Errorf("channelName(%T) = %q, want %q", tt.notifier, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// --- ntfy ---
+
+// ntfy settings, alerts are published to NTFY_TOPIC when it is set
+var ntfyServer = os.Getenv("NTFY_URL")  // Self-hosted server, defaults to https://ntfy.sh
+var ntfyTopic = os.Getenv("NTFY_TOPIC") // Topic alerts are published to
+var ntfyToken = os.Getenv("NTFY_TOKEN") // Access token for protected topics
+
+// ntfyPriorities maps keyword group priorities to ntfy's 1 (min) to 5 (max)
+var ntfyPriorities = map[string]string{"low": "2", "normal": "3", "high": "4", "emergency": "5"}
+
+// NtfyNotifier publishes alerts to an ntfy topic, for phone push notifications
+type NtfyNotifier struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// NewNtfyNotifier returns a Notifier publishing to topic on NTFY_URL.
+func NewNtfyNotifier(topic string) *NtfyNotifier {
+	server := ntfyServer
+	if server == "" {
+		server = "https://ntfy.sh"
+	}
+	endpoint := strings.TrimSuffix(server, "/") + "/" + topic
+	return &NtfyNotifier{endpoint: endpoint, token: ntfyToken, client: httpClient}
+}
+
+// Notify publishes a plain message, e.g. a health or heartbeat notification.
+func (n *NtfyNotifier) Notify(subject, body string) error {
+	return n.publish(subject, body, "", "")
+}
+
+// NotifyAlert publishes a match titled with the subreddit, with the snippet and keywords as the
+// message, opening the permalink when tapped.
+func (n *NtfyNotifier) NotifyAlert(alert Alert) error {
+	message := fmt.Sprintf("%s\n\nKeywords: %s", alert.Snippet, strings.Join(alert.Keywords, ", "))
+	if alert.Snippet == "" {
+		message = "Keywords: " + strings.Join(alert.Keywords, ", ")
+	}
+	return n.publish("r/"+alert.Subreddit, message, alert.URL(), ntfyPriorities[alert.Priority])
+}
+
+// publish posts the message to the topic, retrying rate limits and server errors.
+func (n *NtfyNotifier) publish(title, message, click, priority string) error {
+	err := withRetry("ntfy publish", func() error {
+		req, err := http.NewRequest(http.MethodPost, n.endpoint, strings.NewReader(message))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Title", mime.QEncoding.Encode("utf-8", title)) // Headers must be ASCII, ntfy decodes RFC 2047
+		if click != "" {
+			req.Header.Set("Click", click)
+		}
+		if priority != "" {
+			req.Header.Set("Priority", priority)
+		}
+		if n.token != "" {
+			req.Header.Set("Authorization", "Bearer "+n.token)
+		}
+		resp, err := n.client.Do(req)
+		if err != nil {
+			return &retryableError{err: err}
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode >= 300 {
+			return statusError(resp, respBody)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to ntfy: %w", err)
+	}
+	fmt.Println("Notification published to ntfy", n.endpoint)
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ntfyRequest is what the test server received
+type ntfyRequest struct {
+	path   string
+	header http.Header
+	body   string
+}
+
+// newNtfyTestServer answers publishes with 200, keeping the last one.
+func newNtfyTestServer(t *testing.T) (*httptest.Server, *ntfyRequest) {
+	received := &ntfyRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*received = ntfyRequest{path: r.URL.Path, header: r.Header.Clone(), body: string(body)}
+		w.Write([]byte(`{"id": "abc"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func TestNtfyAlertHeaders(t *testing.T) {
+	server, received := newNtfyTestServer(t)
+	n := &NtfyNotifier{endpoint: server.URL + "/reddit-alerts", token: "tk_secret", client: server.Client()}
+
+	alert := Alert{
+		Subreddit: "forhire",
+		Permalink: "/r/forhire/comments/1/hiring_a_va/",
+		Snippet:   "Hiring a VA for leads",
+		Keywords:  []string{"VA", "leads"},
+		Priority:  "high",
+	}
+	if err := n.NotifyAlert(alert); err != nil {
+		t.Fatalf("NotifyAlert: %v", err)
+	}
+	if received.path != "/reddit-alerts" {
+		t.Errorf("path = %q, want the topic", received.path)
+	}
+	want := map[string]string{
+		"Title":         "r/forhire",
+		"Click":         "https://www.reddit.com/r/forhire/comments/1/hiring_a_va/",
+		"Priority":      "4",
+		"Authorization": "Bearer tk_secret",
+	}
+	for name, value := range want {
+		if got := received.header.Get(name); got != value {
+			t.Errorf("%s header = %q, want %q", name, got, value)
+		}
+	}
+	if want := "Hiring a VA for leads\n\nKeywords: VA, leads"; received.body != want {
+		t.Errorf("body = %q, want %q", received.body, want)
+	}
+}
+
+func TestNtfyPlainNotificationHeaders(t *testing.T) {
+	server, received := newNtfyTestServer(t)
+	n := &NtfyNotifier{endpoint: server.URL + "/reddit-alerts", client: server.Client()}
+
+	if err := n.Notify("Reddit monitor: déjà vu — store recovered", "All good"); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	title := received.header.Get("Title")
+	decoded, err := new(mime.WordDecoder).DecodeHeader(title)
+	if err != nil || decoded != "Reddit monitor: déjà vu — store recovered" {
+		t.Errorf("Title header %q decodes to %q, %v, want the RFC 2047 encoded subject", title, decoded, err)
+	}
+	for i := 0; i < len(title); i++ {
+		if title[i] >= 0x80 {
+			t.Errorf("Title header %q isn't ASCII", title)
+			break
+		}
+	}
+	for _, name := range []string{"Click", "Priority", "Authorization"} {
+		if received.header.Get(name) != "" {
+			t.Errorf("%s header = %q, want none", name, received.header.Get(name))
+		}
+	}
+	if received.body != "All good" {
+		t.Errorf("body = %q, want the message", received.body)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --- Panic Recovery ---
+
+// panicCount counts recovered panics since startup, reported by GET /api/status
+var panicCount atomic.Int64
+
+// A panic escalation is sent when panicEscalationThreshold panics are recovered within
+// panicEscalationWindow, at most once per window
+const panicEscalationThreshold = 3
+const panicEscalationWindow = time.Hour
+
+// subsystemPanics names recovered panics in escalations
+const subsystemPanics = "panics"
+
+// panicTracker keeps the times of recent panics to tell whether they repeat
+type panicTracker struct {
+	mu          sync.Mutex
+	recent      []time.Time
+	escalatedAt time.Time
+}
+
+// panics tracks the panics recovered in the running monitor
+var panics = &panicTracker{}
+
+// record notes a panic in where and returns the escalation to send if panics repeat, nil otherwise.
+func (t *panicTracker) record(where string, value interface{}, now time.Time) *HealthEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	recent := t.recent[:0]
+	for _, at := range t.recent {
+		if now.Sub(at) < panicEscalationWindow {
+			recent = append(recent, at)
+		}
+	}
+	t.recent = append(recent, now)
+	if len(t.recent) < panicEscalationThreshold || now.Sub(t.escalatedAt) < panicEscalationWindow {
+		return nil
+	}
+	t.escalatedAt = now
+	return &HealthEvent{Subsystem: subsystemPanics, Event: "escalation", Failures: len(t.recent),
+		LastError: fmt.Sprintf("panic in %s: %v", where, value), At: now}
+}
+
+// recoverPanic recovers a panic of the calling function: it logs it with its stack trace, counts
+// it and escalates through the admin notifiers if panics repeat. It must be deferred directly.
+// A recovered panic is returned as an error through err, if not nil.
+func recoverPanic(where string, err *error) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	panicCount.Add(1)
+	fmt.Printf("Error: Recovered from a panic in %s, continuing: %v\n%s", where, value, debug.Stack())
+	if err != nil {
+		*err = fmt.Errorf("panic in %s: %v", where, value)
+	}
+	if event := panics.record(where, value, time.Now()); event != nil {
+		go reportPanics(*event) // Sending may be slow, and may be what panicked
+	}
+}
+
+// reportPanics logs, stores and sends a panic escalation to the admin.
+func reportPanics(event HealthEvent) {
+	fmt.Printf("!!! ESCALATION: %d panics within %v, last %s !!!\n", event.Failures, panicEscalationWindow, event.LastError)
+	subject := "Reddit Keyword Monitor: repeated panics"
+	body := fmt.Sprintf("The monitor recovered from %d panics within %v and keeps running, but something is wrong.\n"+
+		"Last: %s\n\nThe stack traces are in the log. Total since startup: %d.",
+		event.Failures, panicEscalationWindow, event.LastError, panicCount.Load())
+	health.deliver(event, subject, body)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --- Profiles ---
+
+// Profile is an independent monitor with its own subreddits, keywords, recipients and poll interval.
+// Profiles run alongside the top-level config and share its HTTP client, rate limiting and Mongo connection.
+type Profile struct {
+	Name                string            `json:"name"`
+	Subreddits          []SubredditConfig `json:"subreddits"`
+	Keywords            []KeywordSpec     `json:"keywords,omitempty"`
+	KeywordGroups       []KeywordGroup    `json:"keyword_groups,omitempty"`
+	MinKeywordMatches   int               `json:"min_keyword_matches,omitempty"`
+	Recipients          []string          `json:"recipients,omitempty"`            // Defaults to RECIPIENT_EMAIL
+	PollIntervalSeconds int               `json:"poll_interval_seconds,omitempty"` // Default interval of the profile's subreddits
+
+	// Alert channels of the profile. Setting any replaces the top-level channels, so profiles
+	// (e.g. different clients) only get their own alerts.
+	NtfyTopic       string `json:"ntfy_topic,omitempty"`
+	TeamsWebhookURL string `json:"teams_webhook_url,omitempty"`
+	PushoverUserKey string `json:"pushover_user_key,omitempty"` // Pushed with the top-level pushover_app_token
+
+	// Store settings, defaulting to the top-level store_full_text and store_full_content
+	StoreFullText    *bool `json:"store_full_text,omitempty"`
+	StoreFullContent *bool `json:"store_full_content,omitempty"`
+}
+
+// profiles are the configured profiles, run in addition to the top-level subreddits
+var profiles = []Profile{}
+
+// onlyProfile limits the monitor to one profile, set with --profile. The top-level subreddits don't run then.
+var onlyProfile = ""
+
+// profileDedupe is "global" (an item alerts once across all profiles) or "profile" (once per profile)
+var profileDedupe = "global"
+
+// validate checks the profile's subreddits, keywords and interval.
+func (p Profile) validate() error {
+	if len(p.Subreddits) == 0 {
+		return fmt.Errorf("no subreddits")
+	}
+	for i, sub := range p.Subreddits {
+		if sub.Name == "" {
+			return fmt.Errorf("subreddits[%d] has an empty name", i)
+		}
+		if err := sub.validate(); err != nil {
+			return fmt.Errorf("subreddit %s: %w", sub.Name, err)
+		}
+	}
+	if len(p.Keywords) == 0 && len(p.KeywordGroups) == 0 {
+		return fmt.Errorf("no keywords")
+	}
+	if err := validateKeywords(p.Keywords, p.KeywordGroups); err != nil {
+		return err
+	}
+	if p.MinKeywordMatches < 0 {
+		return fmt.Errorf("min_keyword_matches must not be negative")
+	}
+	if p.PollIntervalSeconds != 0 && p.pollInterval() < minPollInterval {
+		return fmt.Errorf("poll_interval_seconds must be at least %d", int(minPollInterval.Seconds()))
+	}
+	for _, recipient := range p.Recipients {
+		if !strings.Contains(recipient, "@") {
+			return fmt.Errorf("invalid recipient %q", recipient)
+		}
+	}
+	if p.TeamsWebhookURL != "" && !strings.HasPrefix(p.TeamsWebhookURL, "https://") {
+		return fmt.Errorf("teams_webhook_url must be an https URL")
+	}
+	return nil
+}
+
+// pollInterval returns the profile's default polling interval.
+func (p Profile) pollInterval() time.Duration {
+	if p.PollIntervalSeconds == 0 {
+		return defaultPollInterval
+	}
+	return time.Duration(p.PollIntervalSeconds) * time.Second
+}
+
+// rules returns what the profile's items are matched against.
+func (p *Profile) rules() matchRules {
+	minMatches := 1
+	if p.MinKeywordMatches > 0 {
+		minMatches = p.MinKeywordMatches
+	}
+	rules := matchRules{profile: p.Name, keywords: p.Keywords, groups: p.KeywordGroups, minMatches: minMatches, subreddits: p.Subreddits,
+		storeFullText: storeFullText, storeFullContent: storeFullContent}
+	if p.StoreFullText != nil {
+		rules.storeFullText = *p.StoreFullText
+	}
+	if p.StoreFullContent != nil {
+		rules.storeFullContent = *p.StoreFullContent
+	}
+	return rules
+}
+
+// notifier returns a Notifier emailing every recipient of the profile, plus its alert channels.
+func (p *Profile) notifier() Notifier {
+	channels := alertChannels(p.NtfyTopic, p.TeamsWebhookURL, p.PushoverUserKey)
+	if len(channels) == 0 {
+		channels = topLevelAlertChannels()
+	}
+	if len(p.Recipients) == 0 {
+		return healthNotifier{withAlertChannels(newNotifier(recipientEmail), channels)}
+	}
+	notifiers := MultiNotifier{}
+	for _, recipient := range p.Recipients {
+		notifiers = append(notifiers, newNotifier(recipient))
+	}
+	return healthNotifier{withAlertChannels(notifiers, channels)}
+}
+
+// findProfile returns the profile named name, nil if there is none.
+func findProfile(name string) *Profile {
+	for i := range profiles {
+		if profiles[i].Name == name {
+			return &profiles[i]
+		}
+	}
+	return nil
+}
+
+// allPollJobs builds the poll jobs of the top-level config and every profile, or only those of
+// onlyProfile when set. Callers hold configMu for reading.
+func allPollJobs() []pollJob {
+	if onlyProfile != "" {
+		if p := findProfile(onlyProfile); p != nil {
+			return buildProfileJobs(p, subredditChunkSize)
+		}
+		fmt.Printf("WARN: Profile %s (--profile) is no longer configured, nothing to poll\n", onlyProfile)
+		return nil
+	}
+	jobs := buildPollJobs(subredditConfigs, subredditChunkSize)
+	if len(jobs) > 0 {
+		jobs[0].primary = true
+	}
+	for i := range profiles {
+		jobs = append(jobs, buildProfileJobs(&profiles[i], subredditChunkSize)...)
+	}
+	return jobs
+}
+
+// buildProfileJobs builds the poll jobs of a profile, named after it. Subreddits without their own
+// poll_interval_seconds use the profile's interval. Profiles don't poll search monitors.
+func buildProfileJobs(p *Profile, size int) []pollJob {
+	jobs := []pollJob{}
+	for _, job := range buildPollJobs(p.Subreddits, size) {
+		if len(job.listingChunks) == 0 && len(job.commentChunks) == 0 {
+			continue // The shared job only exists for the top-level search monitors
+		}
+		if job.name == "default" {
+			job.interval = p.pollInterval()
+		}
+		job.name = p.Name + "/" + job.name
+		job.searchMonitors = false
+		job.profile = p
+		jobs = append(jobs, job)
+	}
+	if len(jobs) > 0 {
+		jobs[0].primary = true
+	}
+	return jobs
+}
+
+// --- Match Rules ---
+
+// matchRules is what items are matched against: the top-level config or a profile's.
+type matchRules struct {
+	profile    string // "" for the top-level config
+	keywords   []KeywordSpec
+	groups     []KeywordGroup
+	minMatches int
+	subreddits []SubredditConfig
+
+	storeFullText    bool // Store the matched text with processed items
+	storeFullContent bool // Store the whole post or comment with processed items
+}
+
+// currentMatchRules returns the top-level config's rules. Callers hold configMu for reading.
+func currentMatchRules() matchRules {
+	return matchRules{keywords: keywords, groups: keywordGroups, minMatches: minKeywordMatches, subreddits: subredditConfigs,
+		storeFullText: storeFullText, storeFullContent: storeFullContent}
+}
+
+// subredditConfig returns the config of a subreddit in the rules, or defaults for others (e.g. search results).
+func (r matchRules) subredditConfig(name string) SubredditConfig {
+	for _, cfg := range r.subreddits {
+		if strings.EqualFold(cfg.Name, name) {
+			return cfg
+		}
+	}
+	return SubredditConfig{Name: name}
+}
+
+// logTag returns " [profile]" for a profile's rules, so its log lines can be told apart.
+func (r matchRules) logTag() string {
+	if r.profile == "" {
+		return ""
+	}
+	return " [" + r.profile + "]"
+}
+
+// alertSubject builds the notification subject of a match, led by "[profile] " for a profile's rules
+// and by "[HIGH PRIORITY] " for high priority keywords.
+func (r matchRules) alertSubject(kind, subreddit string, groups []string, keywordPriority string) string {
+	subject := alertSubject(kind, subreddit, groups)
+	if r.profile != "" {
+		subject = "[" + r.profile + "] " + subject
+	}
+	if keywordPriority == "high" {
+		subject = highPriorityTag + subject
+	}
+	return subject
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// --- Proximity Matching ---
+
+// defaultProximityDistance is used when a near spec doesn't set distance
+const defaultProximityDistance = 10
+
+// token is a word in a text with its byte offsets, so a matched window can be cut from the original.
+type token struct {
+	text       string
+	start, end int
+}
+
+// tokenize splits text into words: runs of letters and digits. Punctuation, newlines, markdown
+// leftovers and emoji all act as separators, apostrophes included ("VA's" is "VA", "s").
+func tokenize(text string) []token {
+	tokens := []token{}
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			tokens = append(tokens, token{text: text[start:i], start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{text: text[start:], start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// termOccurrence is where one near term (possibly several words) occurs in the token list.
+type termOccurrence struct {
+	term       int // Index into the spec's terms
+	first, end int // Token range [first, end)
+}
+
+// findProximityWindow returns the smallest stretch of text containing every term, in any order,
+// with at most distance words between the first and the last term's start. ok is false if none fits.
+func findProximityWindow(text string, terms []string, distance int, caseSensitive bool) (window string, ok bool) {
+	tokens := tokenize(text)
+	equal := strings.EqualFold
+	if caseSensitive {
+		equal = func(a, b string) bool { return a == b }
+	}
+
+	// Find every occurrence of every term, multi-word terms must appear as consecutive words
+	occurrences := []termOccurrence{}
+	for termIndex, term := range terms {
+		termTokens := tokenize(term)
+		if len(termTokens) == 0 {
+			return "", false
+		}
+		for i := 0; i+len(termTokens) <= len(tokens); i++ {
+			matched := true
+			for j, termToken := range termTokens {
+				if !equal(tokens[i+j].text, termToken.text) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				occurrences = append(occurrences, termOccurrence{term: termIndex, first: i, end: i + len(termTokens)})
+			}
+		}
+	}
+	sort.Slice(occurrences, func(a, b int) bool { return occurrences[a].first < occurrences[b].first })
+
+	// Sliding window over occurrences that covers all terms with the smallest span
+	counts := make([]int, len(terms))
+	covered := 0
+	bestSpan, bestFirst, bestEnd := -1, 0, 0
+	left := 0
+	for right, occ := range occurrences {
+		if counts[occ.term] == 0 {
+			covered++
+		}
+		counts[occ.term]++
+
+		for covered == len(terms) {
+			span := occ.first - occurrences[left].first
+			if bestSpan < 0 || span < bestSpan {
+				bestSpan, bestFirst = span, occurrences[left].first
+				bestEnd = 0
+				for _, o := range occurrences[left : right+1] {
+					if o.end > bestEnd {
+						bestEnd = o.end
+					}
+				}
+			}
+			counts[occurrences[left].term]--
+			if counts[occurrences[left].term] == 0 {
+				covered--
+			}
+			left++
+		}
+	}
+
+	if bestSpan < 0 || bestSpan > distance {
+		return "", false
+	}
+	return text[tokens[bestFirst].start:tokens[bestEnd-1].end], true
+}
+
+// nearTermsOverlap reports whether near terms a and b are the same words or one is within the
+// other, as in "VA" and "VA leads": the same occurrence would count for both.
+func nearTermsOverlap(a, b string, caseSensitive bool) bool {
+	short, long := tokenize(a), tokenize(b)
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+	if len(short) == 0 {
+		return false
+	}
+	for i := 0; i+len(short) <= len(long); i++ {
+		matched := true
+		for j, tok := range short {
+			if caseSensitive && tok.text != long[i+j].text || !caseSensitive && !strings.EqualFold(tok.text, long[i+j].text) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// foldTerms applies accent folding to near terms, matching how the text is folded.
+func foldTerms(terms []string) []string {
+	folded := make([]string, len(terms))
+	for i, term := range terms {
+		folded[i] = foldText(term)
+	}
+	return folded
+}
+
+// fieldTexts returns the texts a spec applies to, title first.
+func (k KeywordSpec) fieldTexts(title, body string) []string {
+	texts := []string{}
+	if k.matchesTitle() {
+		texts = append(texts, title)
+	}
+	if k.matchesBody() {
+		texts = append(texts, body)
+	}
+	return texts
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"empty", "", []string{}},
+		{"spaces", "  VA  leads ", []string{"VA", "leads"}},
+		{"punctuation", "VA, leads. (cheap!) 100%", []string{"VA", "leads", "cheap", "100"}},
+		{"newlines and tabs", "VA\nleads\r\n\tnow", []string{"VA", "leads", "now"}},
+		{"apostrophes split words", "VA's leads don’t", []string{"VA", "s", "leads", "don", "t"}},
+		{"hyphens and slashes", "e-mail VA/leads", []string{"e", "mail", "VA", "leads"}},
+		{"markdown leftovers", "**VA** [leads](https://x.io)", []string{"VA", "leads", "https", "x", "io"}},
+		{"emoji", "VA🔥leads 🚀", []string{"VA", "leads"}},
+		{"accents and digits", "café 2024 ingénieur", []string{"café", "2024", "ingénieur"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, tok := range tokenize(tt.text) {
+			got = append(got, tok.text)
+			if tt.text[tok.start:tok.end] != tok.text {
+				t.Errorf("%s: token %q has offsets %d-%d, which cut %q", tt.name, tok.text, tok.start, tok.end, tt.text[tok.start:tok.end])
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: tokenize(%q) = %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFindProximityWindow(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		terms         []string
+		distance      int
+		caseSensitive bool
+		wantWindow    string
+		wantOK        bool
+	}{
+		{"adjacent", "Selling VA leads today", []string{"VA", "leads"}, 10, false, "VA leads", true},
+		{"any order", "leads for a VA", []string{"VA", "leads"}, 10, false, "leads for a VA", true},
+		{"at the distance", "VA one two three leads", []string{"VA", "leads"}, 4, false, "VA one two three leads", true},
+		{"past the distance", "VA one two three four leads", []string{"VA", "leads"}, 4, false, "", false},
+		{"smallest window", "VA x x x x x leads x VA", []string{"VA", "leads"}, 10, false, "leads x VA", true},
+		{"across punctuation and newlines", "Need a VA.\n\n- leads, please!", []string{"VA", "leads"}, 3, false, "VA.\n\n- leads", true},
+		{"multi-word term", "cold email leads from a VA", []string{"cold email", "VA"}, 10, false, "cold email leads from a VA", true},
+		{"multi-word term split", "cold leads email from a VA", []string{"cold email", "VA"}, 10, false, "", false},
+		{"missing term", "VA VA VA", []string{"VA", "leads"}, 10, false, "", false},
+		{"case-insensitive", "va LEADS", []string{"VA", "leads"}, 10, false, "va LEADS", true},
+		{"case-sensitive", "va LEADS", []string{"VA", "leads"}, 10, true, "", false},
+		{"whole words only", "VAT leadsheet", []string{"VA", "leads"}, 10, false, "", false},
+		{"empty term", "VA leads", []string{"VA", "!!"}, 10, false, "", false},
+	}
+	for _, tt := range tests {
+		window, ok := findProximityWindow(tt.text, tt.terms, tt.distance, tt.caseSensitive)
+		if window != tt.wantWindow || ok != tt.wantOK {
+			t.Errorf("%s: findProximityWindow(%q, %q, %d) = %q, %v, want %q, %v", tt.name, tt.text, tt.terms, tt.distance, window, ok, tt.wantWindow, tt.wantOK)
+		}
+	}
+}
+
+func TestNearTermsOverlapRejected(t *testing.T) {
+	tests := []struct {
+		name          string
+		near          []string
+		caseSensitive bool
+		wantErr       bool
+	}{
+		{"distinct terms", []string{"VA", "leads"}, false, false},
+		{"duplicate term", []string{"deal", "deal"}, false, true},
+		{"duplicate in another case", []string{"deal", "DEAL"}, false, true},
+		{"case-sensitive terms differing in case", []string{"deal", "DEAL"}, true, false},
+		{"term within another", []string{"VA", "VA leads"}, false, true},
+		{"term at the end of another", []string{"cold email", "email"}, false, true},
+		{"shared word only", []string{"cold email", "email list"}, false, false},
+		{"substring of a word", []string{"VA", "VAT"}, false, false},
+		{"third term repeats the first", []string{"VA", "leads", "va"}, false, true},
+	}
+	for _, tt := range tests {
+		err := KeywordSpec{Near: tt.near, CaseSensitive: tt.caseSensitive}.validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validate(near %q) = %v, want error %v", tt.name, tt.near, err, tt.wantErr)
+		}
+	}
+}
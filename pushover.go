@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// --- Pushover ---
+
+// Pushover credentials from the config file, alerts are pushed when both are set
+var pushoverAppToken = ""
+var pushoverUserKey = ""
+
+// pushoverEndpoint is Pushover's message API
+const pushoverEndpoint = "https://api.pushover.net/1/messages.json"
+
+// Pushover limits, in characters
+const (
+	pushoverMaxMessage = 1024
+	pushoverMaxTitle   = 250
+)
+
+// Emergency pushes repeat every pushoverEmergencyRetry seconds until acknowledged, for at most pushoverEmergencyExpire seconds
+const (
+	pushoverEmergencyRetry  = 60
+	pushoverEmergencyExpire = 3600
+)
+
+// pushoverPriorities maps keyword group priorities to Pushover's -2 (silent) to 2 (emergency)
+var pushoverPriorities = map[string]int{"low": -1, "normal": 0, "high": 1, "emergency": 2}
+
+// pushoverDefaultPriority is the priority of pushes without a group priority, from pushover_priority
+var pushoverDefaultPriority = 0
+
+// PushoverNotifier pushes alerts to a phone through the Pushover message API
+type PushoverNotifier struct {
+	token    string
+	user     string
+	endpoint string
+	client   *http.Client
+}
+
+// NewPushoverNotifier returns a Notifier pushing to a Pushover user or group key with the configured app token.
+func NewPushoverNotifier(userKey string) *PushoverNotifier {
+	return &PushoverNotifier{token: pushoverAppToken, user: userKey, endpoint: pushoverEndpoint, client: httpClient}
+}
+
+// Notify pushes a plain message at the default priority.
+func (n *PushoverNotifier) Notify(subject, body string) error {
+	return n.push(subject, body, "", pushoverDefaultPriority)
+}
+
+// alertPushoverPriority returns the Pushover priority of an alert: that of its keyword groups, or
+// pushover_priority for normal ones. High priority keywords raise it to at least 1 (high).
+func alertPushoverPriority(alert Alert) int {
+	priority := pushoverDefaultPriority
+	if p, ok := pushoverPriorities[alert.Priority]; ok && alert.Priority != "normal" {
+		priority = p
+	}
+	if alert.KeywordPriority == "high" && priority < 1 {
+		priority = 1
+	}
+	return priority
+}
+
+// NotifyAlert pushes the snippet and keywords titled with the subject, at alertPushoverPriority,
+// with the permalink as supplementary URL.
+func (n *PushoverNotifier) NotifyAlert(alert Alert) error {
+	keywordsLine := "Keywords: " + strings.Join(alert.Keywords, ", ")
+	message := keywordsLine
+	if room := pushoverMaxMessage - len([]rune(keywordsLine)) - 2; alert.Snippet != "" && room > 3 {
+		// Keep the keywords line whole, the snippet gets what's left
+		message = truncate(alert.Snippet, room) + "\n\n" + keywordsLine
+	}
+	return n.push(alert.Subject, message, alert.URL(), alertPushoverPriority(alert))
+}
+
+// push sends a message, retrying rate limits and server errors.
+func (n *PushoverNotifier) push(title, message, link string, priority int) error {
+	form := url.Values{}
+	form.Set("token", n.token)
+	form.Set("user", n.user)
+	form.Set("title", truncate(title, pushoverMaxTitle))
+	form.Set("message", truncate(message, pushoverMaxMessage))
+	if link != "" {
+		form.Set("url", link)
+		form.Set("url_title", "Open on Reddit")
+	}
+	if priority != 0 {
+		form.Set("priority", strconv.Itoa(priority))
+	}
+	if priority == 2 {
+		form.Set("retry", strconv.Itoa(pushoverEmergencyRetry))
+		form.Set("expire", strconv.Itoa(pushoverEmergencyExpire))
+	}
+	payload := form.Encode()
+
+	err := withRetry("Pushover push", func() error {
+		resp, err := n.client.Post(n.endpoint, "application/x-www-form-urlencoded", strings.NewReader(payload))
+		if err != nil {
+			return &retryableError{err: err}
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode >= 300 {
+			return statusError(resp, respBody) // 4xx (bad token or user) isn't retried, 429 is the monthly quota
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to push through Pushover: %w", err)
+	}
+	fmt.Println("Notification pushed through Pushover")
+	return nil
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Quiet Backfill ---
+
+// quietBackfillsCollectionName holds the state of quiet backfills, one document per subreddit
+const quietBackfillsCollectionName = "quiet_backfills"
+
+// quietBackfillPageGap spaces the pages of a quiet backfill, so it takes a small share of the
+// rate limiter's slots and polling goes on at its usual pace
+const quietBackfillPageGap = 30 * time.Second
+
+// quietBackfillAttempts is how many times a page is tried before the backfill stops until the next start
+const quietBackfillAttempts = 3
+
+// maxQuietBackfillMatches bounds the matches listed in the digest, the count covers all of them
+const maxQuietBackfillMatches = 200
+
+// defaultQuietBackfillDays is how far back a backfill goes when no duration is given
+const defaultQuietBackfillDays = 7
+
+// subredditNamePattern matches valid subreddit names
+var subredditNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_]{1,20}$`)
+
+// Errors of quietBackfiller.start the admin API reports as client errors
+var (
+	errInvalidBackfill    = errors.New("invalid backfill")
+	errBackfillInProgress = errors.New("backfill already in progress")
+)
+
+// Run flags starting quiet backfills, see quietBackfiller
+var backfillSubredditsFlag = "" // --backfill-subreddits: comma-separated subreddits to backfill
+var backfillDaysFlag = defaultQuietBackfillDays
+
+// QuietBackfill is the state of a quiet backfill of a subreddit's new listing, saved after every
+// page so an interrupted backfill resumes where it stopped
+type QuietBackfill struct {
+	Subreddit   string          `bson:"subreddit" json:"subreddit"` // Lowercased, subreddit names are case-insensitive
+	Name        string          `bson:"name" json:"name"`           // As requested
+	Since       time.Time       `bson:"since" json:"since"`         // Posts created earlier are not scanned
+	After       string          `bson:"after" json:"after"`         // Cursor of the next page, "" before the first one
+	Pages       int             `bson:"pages" json:"pages"`
+	MatchCount  int             `bson:"match_count" json:"match_count"`
+	Matches     []BackfillMatch `bson:"matches" json:"matches"` // The first maxQuietBackfillMatches
+	StartedAt   time.Time       `bson:"started_at" json:"started_at"`
+	UpdatedAt   time.Time       `bson:"updated_at" json:"updated_at"`
+	Done        bool            `bson:"done" json:"done"`
+	LastError   string          `bson:"last_error,omitempty" json:"last_error,omitempty"` // Why it stopped before the end, until resumed
+	DigestError string          `bson:"digest_error,omitempty" json:"digest_error,omitempty"`
+}
+
+// BackfillMatch is a match of a quiet backfill, as listed in its digest
+type BackfillMatch struct {
+	Subreddit string   `bson:"subreddit" json:"subreddit"`
+	Permalink string   `bson:"permalink" json:"permalink"`
+	Title     string   `bson:"title" json:"title"`
+	Keywords  []string `bson:"keywords" json:"keywords"`
+}
+
+// backfillStore marks every item it records as backfilled
+type backfillStore struct {
+	Store
+}
+
+// Mark records the item with Backfilled set.
+func (s backfillStore) Mark(ctx context.Context, item ProcessedItem) error {
+	item.Backfilled = true
+	return s.Store.Mark(ctx, item)
+}
+
+// backfillCollector is the Notifier of a quiet backfill: it keeps the alerts for the digest instead
+// of sending them
+type backfillCollector struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+// Notify keeps the message as an alert.
+func (c *backfillCollector) Notify(subject, body string) error {
+	return c.NotifyAlert(Alert{Subject: subject, Body: body})
+}
+
+// NotifyAlert keeps the alert.
+func (c *backfillCollector) NotifyAlert(alert Alert) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.alerts = append(c.alerts, alert)
+	return nil
+}
+
+// quietBackfiller runs quiet backfills next to polling, one goroutine per subreddit
+type quietBackfiller struct {
+	ctx        context.Context
+	collection *mongo.Collection
+	store      Store
+	notifier   Notifier // Sends the digests
+	mu         sync.Mutex
+	running    map[string]bool
+	wg         sync.WaitGroup
+}
+
+// quietBackfills runs the backfills of the monitor, nil without MongoDB
+var quietBackfills *quietBackfiller
+
+// newQuietBackfiller returns a quietBackfiller saving its state to collection. Its backfills stop
+// when ctx is done.
+func newQuietBackfiller(ctx context.Context, collection *mongo.Collection, store Store, notifier Notifier) *quietBackfiller {
+	return &quietBackfiller{ctx: ctx, collection: collection, store: backfillStore{store}, notifier: notifier, running: map[string]bool{}}
+}
+
+// start begins a backfill of name's posts of the last days days. It fails with errBackfillInProgress
+// if one of the subreddit is unfinished, a finished one is replaced.
+func (b *quietBackfiller) start(name string, days int) (QuietBackfill, error) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "r/")
+	if !subredditNamePattern.MatchString(name) {
+		return QuietBackfill{}, fmt.Errorf("%w: invalid subreddit name %q", errInvalidBackfill, name)
+	}
+	if days <= 0 {
+		return QuietBackfill{}, fmt.Errorf("%w: it must go back at least one day", errInvalidBackfill)
+	}
+	now := time.Now()
+	job := QuietBackfill{Subreddit: strings.ToLower(name), Name: name, Since: now.AddDate(0, 0, -days), Matches: []BackfillMatch{}, StartedAt: now, UpdatedAt: now}
+
+	ctx, cancel := context.WithTimeout(b.ctx, 5*time.Second)
+	defer cancel()
+	var existing QuietBackfill
+	err := b.collection.FindOne(ctx, map[string]interface{}{"subreddit": job.Subreddit}).Decode(&existing)
+	if err == nil && !existing.Done {
+		return existing, fmt.Errorf("%w: r/%s, %d page(s) so far", errBackfillInProgress, existing.Name, existing.Pages)
+	}
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return QuietBackfill{}, err
+	}
+	_, err = b.collection.ReplaceOne(ctx, map[string]interface{}{"subreddit": job.Subreddit}, job, options.Replace().SetUpsert(true))
+	if err != nil {
+		return QuietBackfill{}, fmt.Errorf("error saving backfill: %w", err)
+	}
+	fmt.Printf("Quiet backfill of r/%s started, going back to %s\n", name, job.Since.Format("2006-01-02 15:04"))
+	b.launch(job)
+	return job, nil
+}
+
+// resume relaunches the backfills left unfinished by the last run.
+func (b *quietBackfiller) resume() {
+	ctx, cancel := context.WithTimeout(b.ctx, 10*time.Second)
+	defer cancel()
+	cursor, err := b.collection.Find(ctx, map[string]interface{}{"done": false})
+	if err != nil {
+		fmt.Printf("Error loading unfinished backfills: %v\n", err)
+		return
+	}
+	var jobs []QuietBackfill
+	if err := cursor.All(ctx, &jobs); err != nil {
+		fmt.Printf("Error loading unfinished backfills: %v\n", err)
+		return
+	}
+	for _, job := range jobs {
+		fmt.Printf("Resuming quiet backfill of r/%s after %d page(s)\n", job.Name, job.Pages)
+		b.launch(job)
+	}
+}
+
+// list returns the state of every backfill, the latest first.
+func (b *quietBackfiller) list(ctx context.Context) ([]QuietBackfill, error) {
+	cursor, err := b.collection.Find(ctx, map[string]interface{}{}, options.Find().SetSort(map[string]interface{}{"started_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	jobs := []QuietBackfill{}
+	err = cursor.All(ctx, &jobs)
+	return jobs, err
+}
+
+// launch runs job in a goroutine unless the subreddit's backfill is already running.
+func (b *quietBackfiller) launch(job QuietBackfill) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.running[job.Subreddit] {
+		return
+	}
+	b.running[job.Subreddit] = true
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer func() {
+			b.mu.Lock()
+			delete(b.running, job.Subreddit)
+			b.mu.Unlock()
+		}()
+		defer recoverPanic("quiet backfill of r/"+job.Name, nil)
+		b.run(job)
+	}()
+}
+
+// wait blocks until the running backfills have stopped, after ctx is done.
+func (b *quietBackfiller) wait() {
+	b.wg.Wait()
+}
+
+// run pages through the new listing from job.After until a post older than job.Since or the end of
+// the listing, matching every page without alerting, then sends the digest.
+func (b *quietBackfiller) run(job QuietBackfill) {
+	configMu.RLock()
+	rules := currentMatchRules()
+	configMu.RUnlock()
+	cfg := rules.subredditConfig(job.Name)
+	for {
+		endpoint := listingEndpoint(job.Name, "new")
+		if cfg.isMultireddit() {
+			endpoint = cfg.MultiredditURL
+		}
+		if job.After != "" {
+			endpoint = withQueryParam(endpoint, "after", job.After)
+		}
+		posts, next, err := b.fetchPage(endpoint)
+		if err != nil {
+			if b.ctx.Err() == nil {
+				fmt.Printf("Error backfilling r/%s after %d page(s), it resumes on the next start: %v\n", job.Name, job.Pages, err)
+				job.LastError = err.Error()
+				b.save(job)
+			}
+			return
+		}
+
+		reached := next == ""
+		fresh := []Post{}
+		for _, post := range posts {
+			if created := createdTime(post.CreatedUtc); !created.IsZero() && created.Before(job.Since) {
+				reached = true // The listing is newest first, the rest is older too
+				continue
+			}
+			post.Listing = "backfill"
+			if cfg.isMultireddit() {
+				post.Subreddit = cfg.Name
+			}
+			fresh = append(fresh, post)
+		}
+		collector := &backfillCollector{}
+		processPosts(b.store, collector, rules, fresh)
+		for _, alert := range collector.alerts {
+			job.MatchCount++
+			if len(job.Matches) < maxQuietBackfillMatches {
+				job.Matches = append(job.Matches, BackfillMatch{Subreddit: alert.Subreddit, Permalink: alert.Permalink, Title: alert.Title, Keywords: alert.Keywords})
+			}
+		}
+		job.Pages++
+		job.After, job.Done, job.LastError = next, reached, ""
+		if !b.save(job) {
+			return // Resumes from the last saved page, its items are already recorded
+		}
+		if job.Done {
+			break
+		}
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-time.After(quietBackfillPageGap):
+		}
+	}
+	fmt.Printf("Quiet backfill of r/%s done: %d page(s), %d match(es)\n", job.Name, job.Pages, job.MatchCount)
+	b.sendDigest(job)
+}
+
+// fetchPage fetches a page of the listing, trying quietBackfillAttempts times.
+func (b *quietBackfiller) fetchPage(endpoint string) ([]Post, string, error) {
+	var err error
+	for attempt := 1; attempt <= quietBackfillAttempts; attempt++ {
+		var posts []Post
+		var next string
+		if posts, next, err = redditClient.fetchPostsPage(b.ctx, endpoint); err == nil {
+			return posts, next, nil
+		}
+		if attempt < quietBackfillAttempts {
+			select {
+			case <-b.ctx.Done():
+				return nil, "", b.ctx.Err()
+			case <-time.After(time.Duration(attempt) * quietBackfillPageGap):
+			}
+		}
+	}
+	return nil, "", err
+}
+
+// save writes the state of job, reporting whether it worked.
+func (b *quietBackfiller) save(job QuietBackfill) bool {
+	job.UpdatedAt = time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second) // Also saved while shutting down
+	defer cancel()
+	if _, err := b.collection.ReplaceOne(ctx, map[string]interface{}{"subreddit": job.Subreddit}, job); err != nil {
+		fmt.Printf("Error saving backfill state of r/%s: %v\n", job.Name, err)
+		return false
+	}
+	return true
+}
+
+// sendDigest sends the one message summarizing the matches of a finished backfill, nothing when
+// there are none.
+func (b *quietBackfiller) sendDigest(job QuietBackfill) {
+	if job.MatchCount == 0 {
+		return
+	}
+	subject := fmt.Sprintf("Reddit Keyword Monitor: backfill of r/%s found %d match(es)", job.Name, job.MatchCount)
+	var body strings.Builder
+	fmt.Fprintf(&body, "A backfill scanned %d page(s) of r/%s, back to %s. These matches were recorded without alerting:\n",
+		job.Pages, job.Name, job.Since.Format("2006-01-02"))
+	for i, match := range job.Matches {
+		fmt.Fprintf(&body, "\n%d. %s\n   %s\n   https://www.reddit.com%s\n", i+1, match.Title, describeMatches(match.Keywords), match.Permalink)
+	}
+	if more := job.MatchCount - len(job.Matches); more > 0 {
+		fmt.Fprintf(&body, "\n...and %d more, marked backfilled in processed_items.\n", more)
+	}
+	if err := b.notifier.Notify(subject, body.String()); err != nil {
+		fmt.Printf("Error sending backfill digest of r/%s: %v\n", job.Name, err)
+		job.DigestError = err.Error()
+		b.save(job)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// --- Reddit Rate Limiting ---
+
+// redditRequestsPerMinute is the rate of the shared Reddit limiter: Reddit allows 60 requests a
+// minute with OAuth (see redditOAuthEnabled) and ~10 to unauthenticated clients
+var redditRequestsPerMinute = envInt("REDDIT_REQUESTS_PER_MINUTE", defaultRedditRate())
+
+// redditLimiter paces every Reddit request, see RedditClient.redditGet
+var redditLimiter = newRedditLimiter(redditRequestsPerMinute)
+
+// defaultRedditRate returns the requests a minute Reddit allows the configured client.
+func defaultRedditRate() int {
+	if redditOAuthEnabled() {
+		return 60
+	}
+	return 10
+}
+
+// newRedditLimiter returns a limiter spacing requests evenly, perMinute a minute, without bursts.
+func newRedditLimiter(perMinute int) *rate.Limiter {
+	return rate.NewLimiter(rate.Every(time.Minute/time.Duration(perMinute)), 1)
+}
+
+// waitRedditLimiter blocks until redditLimiter allows a request or ctx is done, and returns how
+// long it waited.
+func waitRedditLimiter(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	err := redditLimiter.Wait(ctx)
+	return time.Since(start), err
+}
+
+// limiterWait sums the time a poll cycle spent waiting on redditLimiter
+type limiterWait struct {
+	nanos atomic.Int64
+}
+
+type limiterWaitKey struct{}
+
+// withLimiterWait returns a context recording limiter waits into the returned limiterWait.
+func withLimiterWait(ctx context.Context) (context.Context, *limiterWait) {
+	wait := &limiterWait{}
+	return context.WithValue(ctx, limiterWaitKey{}, wait), wait
+}
+
+// addLimiterWait adds d to the limiterWait of ctx, if any.
+func addLimiterWait(ctx context.Context, d time.Duration) {
+	if wait, ok := ctx.Value(limiterWaitKey{}).(*limiterWait); ok {
+		wait.nanos.Add(int64(d))
+	}
+}
+
+// total returns the time waited so far.
+func (w *limiterWait) total() time.Duration {
+	return time.Duration(w.nanos.Load())
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Edited Post Recheck ---
+
+// pendingRecheckCollectionName holds recent posts that matched nothing, rechecked when edited
+const pendingRecheckCollectionName = "pending_recheck"
+
+// pendingRecheckCollection is the recheck set, nil when not connected
+var pendingRecheckCollection *mongo.Collection
+
+// recheckWindow is how long after posting an edit can still make a post match (0 disables rechecks)
+var recheckWindow time.Duration
+
+// recheckBatchSize is the most fullnames /api/info.json takes per request
+const recheckBatchSize = 100
+
+// redditEdited is Reddit's "edited": false, or when the item was last edited (Unix seconds)
+type redditEdited float64
+
+// UnmarshalJSON accepts false, true (very old edits, without a time) or a timestamp.
+func (e *redditEdited) UnmarshalJSON(data []byte) error {
+	var at float64
+	if err := json.Unmarshal(data, &at); err == nil {
+		*e = redditEdited(at)
+		return nil
+	}
+	*e = 0
+	return nil
+}
+
+// PendingRecheck is a post that matched nothing, rechecked when edited until RecheckUntil
+type PendingRecheck struct {
+	Name         string    `bson:"name"` // Fullname, e.g. t3_abc123
+	Permalink    string    `bson:"permalink"`
+	Subreddit    string    `bson:"subreddit"`
+	Profile      string    `bson:"profile"` // Profile whose rules it didn't match, "" for the top-level config
+	CreatedUTC   time.Time `bson:"created_utc"`
+	Edited       float64   `bson:"edited"`        // Edit time last matched against, 0 before any edit
+	RecheckUntil time.Time `bson:"recheck_until"` // created_utc plus the recheck window
+}
+
+// setupPendingRecheck ensures the indexes of the recheck set: one entry per post and profile, and
+// a TTL index dropping posts once their window has passed.
+func setupPendingRecheck(collection *mongo.Collection) {
+	pendingRecheckCollection = collection
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}, {Key: "profile", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    map[string]interface{}{"recheck_until": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		fmt.Printf("WARN: Could not create indexes on %s: %v\n", pendingRecheckCollectionName, err)
+	}
+}
+
+// rememberForRecheck adds a post that matched nothing to the recheck set, if it is still within
+// the window. Search results, backfilled and rechecked posts aren't added.
+func rememberForRecheck(profile string, post Post) {
+	if recheckWindow <= 0 || pendingRecheckCollection == nil || post.Name == "" {
+		return
+	}
+	if post.Listing == "search" || post.Listing == "backfill" || post.Listing == "edited" {
+		return
+	}
+	created := createdTime(post.CreatedUtc)
+	until := created.Add(recheckWindow)
+	if !until.After(time.Now()) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := pendingRecheckCollection.UpdateOne(ctx,
+		map[string]interface{}{"name": post.Name, "profile": profile},
+		map[string]interface{}{"$setOnInsert": PendingRecheck{
+			Name: post.Name, Permalink: post.Permalink, Subreddit: post.Subreddit, Profile: profile,
+			CreatedUTC: created, Edited: float64(post.Edited), RecheckUntil: until,
+		}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		fmt.Printf("Error adding %s to the recheck set: %v\n", post.Permalink, err)
+	}
+}
+
+// recheckEditedPosts re-fetches the posts in the recheck set of rules, 100 per request, and
+// matches again those edited since they were last matched. Posts recorded by that (matches and
+// near misses) leave the set, the rest stay until their window expires.
+func recheckEditedPosts(ctx context.Context, store Store, notifier Notifier, rules matchRules) {
+	if recheckWindow <= 0 || pendingRecheckCollection == nil {
+		return
+	}
+	ctxFind, cancelFind := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelFind()
+	cursor, err := pendingRecheckCollection.Find(ctxFind, map[string]interface{}{
+		"profile":       rules.profile,
+		"recheck_until": map[string]interface{}{"$gt": time.Now()}, // The TTL monitor only runs every minute
+	})
+	if err != nil {
+		fmt.Printf("Error querying the recheck set%s: %v\n", rules.logTag(), err)
+		return
+	}
+	pending := []PendingRecheck{}
+	if err := cursor.All(ctxFind, &pending); err != nil {
+		fmt.Printf("Error reading the recheck set%s: %v\n", rules.logTag(), err)
+		return
+	}
+
+	edited := []Post{}
+	for start := 0; start < len(pending); start += recheckBatchSize {
+		if ctx.Err() != nil {
+			return
+		}
+		batch := pending[start:min(start+recheckBatchSize, len(pending))]
+		byName := make(map[string]PendingRecheck, len(batch))
+		names := make([]string, 0, len(batch))
+		for _, p := range batch {
+			byName[p.Name] = p
+			names = append(names, p.Name)
+		}
+		posts, _, _, err := redditClient.fetchPostListing(ctx, "https://www.reddit.com/api/info.json?id="+strings.Join(names, ","))
+		if err != nil {
+			fmt.Printf("Error re-fetching %d post(s) of the recheck set%s: %v\n", len(batch), rules.logTag(), err)
+			continue
+		}
+		for _, post := range posts {
+			p, ok := byName[post.Name]
+			if !ok || float64(post.Edited) <= p.Edited {
+				continue // Deleted posts are missing, unedited ones have nothing new
+			}
+			ctxUpdate, cancelUpdate := context.WithTimeout(context.Background(), 5*time.Second)
+			_, err := pendingRecheckCollection.UpdateOne(ctxUpdate,
+				map[string]interface{}{"name": p.Name, "profile": p.Profile},
+				map[string]interface{}{"$set": map[string]interface{}{"edited": float64(post.Edited)}})
+			cancelUpdate()
+			if err != nil {
+				fmt.Printf("Error updating %s in the recheck set: %v\n", p.Permalink, err)
+			}
+			post.Listing = "edited"
+			post.Subreddit = p.Subreddit // Keep the configured name of multireddit posts
+			edited = append(edited, post)
+		}
+	}
+	if len(edited) == 0 {
+		return
+	}
+
+	fmt.Printf("Rechecking %d edited post(s)%s\n", len(edited), rules.logTag())
+	processPosts(store, notifier, rules, edited)
+	for _, post := range edited {
+		ctxDone, cancelDone := context.WithTimeout(context.Background(), 5*time.Second)
+		if processed, err := store.Has(ctxDone, post.Permalink); err == nil && processed {
+			_, err = pendingRecheckCollection.DeleteOne(ctxDone, map[string]interface{}{"name": post.Name, "profile": rules.profile})
+			if err != nil {
+				fmt.Printf("Error removing %s from the recheck set: %v\n", post.Permalink, err)
+			}
+		}
+		cancelDone()
+	}
+}
@@ -1,16 +1,30 @@
 package main
 
 import (
+	"compress/gzip"
 	"context" // Needed for MongoDB operations
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/http"
-	"net/smtp" // Added for sending email
-	"os"       // Added for file operations and env vars
-	"regexp"   // Added for regex matching
+	"net/url"
+	"os" // Added for file operations and env vars
+	"os/signal"
+	"regexp" // Added for regex matching
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref" // For pinging
@@ -18,42 +32,255 @@ import (
 
 // Post represents a Reddit post's relevant fields
 type Post struct {
-	Title      string  `json:"title"`
-	Selftext   string  `json:"selftext"`
-	Permalink  string  `json:"permalink"`
-	CreatedUtc float64 `json:"created_utc"`
-	Subreddit  string  `json:"subreddit"`
+	Name        string  `json:"name"` // Fullname, e.g. t3_abc123
+	Title       string  `json:"title"`
+	Selftext    string  `json:"selftext"`
+	URL         string  `json:"url"`    // Link target for link posts, the post itself for self posts
+	Domain      string  `json:"domain"` // Link domain, "self.<subreddit>" for self posts
+	Permalink   string  `json:"permalink"`
+	CreatedUtc  float64 `json:"created_utc"`
+	Subreddit   string  `json:"subreddit"`
+	Author      string  `json:"author"`
+	UpvoteRatio float64 `json:"upvote_ratio"`
+	IsSelf      bool    `json:"is_self"`
+	NumComments int     `json:"num_comments"`
+	Listing     string  `json:"-"` // Listing that surfaced the post (set by the fetcher, not Reddit)
+	Held        bool    `json:"-"` // Held during a store outage and replayed, its age no longer matters
+
+	Edited redditEdited `json:"edited"` // When the post was last edited, 0 if never
+	Score  int          `json:"score"`
+
+	CrosspostParent   string `json:"crosspost_parent"` // Fullname of the original post if this is a crosspost, e.g. t3_abc123
+	CrosspostParentID string `json:"-"`                // ID of the original post if this is a crosspost, from crosspost_parent_list
+}
+
+// UnmarshalJSON decodes a post and flattens crosspost_parent_list into CrosspostParentID.
+func (p *Post) UnmarshalJSON(data []byte) error {
+	type plain Post // Avoid recursing into this method
+	var raw struct {
+		plain
+		CrosspostParentList []struct {
+			ID string `json:"id"`
+		} `json:"crosspost_parent_list"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*p = Post(raw.plain)
+	if len(raw.CrosspostParentList) > 0 {
+		p.CrosspostParentID = raw.CrosspostParentList[0].ID
+	} else if p.CrosspostParent != "" {
+		p.CrosspostParentID = strings.TrimPrefix(p.CrosspostParent, "t3_")
+	}
+	return nil
 }
 
 // Comment represents a Reddit comment's relevant fields
 type Comment struct {
-	Body       string  `json:"body"`
-	Permalink  string  `json:"permalink"`
-	CreatedUtc float64 `json:"created_utc"`
-	Subreddit  string  `json:"subreddit"`
+	ID            string  `json:"id"`
+	LinkID        string  `json:"link_id"` // Fullname of the post, e.g. t3_abc123
+	Body          string  `json:"body"`
+	Permalink     string  `json:"permalink"`
+	CreatedUtc    float64 `json:"created_utc"`
+	Subreddit     string  `json:"subreddit"`
+	Author        string  `json:"author"`
+	LinkTitle     string  `json:"link_title"`     // Title of the post, present in comment listings
+	LinkPermalink string  `json:"link_permalink"` // Full URL of the post, present in comment listings
+	Score         int     `json:"score"`
+	Held          bool    `json:"-"` // Held during a store outage and replayed, its age no longer matters
+}
+
+// buildPermalink constructs /r/<sub>/comments/<post id>/_/<id>/ for listings that omit permalink,
+// or returns "" if the IDs needed are missing too.
+func (c Comment) buildPermalink() string {
+	postID := strings.TrimPrefix(c.LinkID, "t3_")
+	if c.Subreddit == "" || postID == "" || c.ID == "" {
+		return ""
+	}
+	return fmt.Sprintf("/r/%s/comments/%s/_/%s/", c.Subreddit, postID, c.ID)
+}
+
+// postPermalink returns the path of the comment's post from link_permalink, or "" if absent.
+func (c Comment) postPermalink() string {
+	if c.LinkPermalink == "" {
+		return ""
+	}
+	if parsed, err := url.Parse(c.LinkPermalink); err == nil && parsed.Path != "" {
+		return parsed.Path
+	}
+	return c.LinkPermalink
 }
 
 // PostResponse matches the Reddit API's post listing structure
 type PostResponse struct {
 	Data struct {
-		Children []struct {
-			Data Post `json:"data"`
-		} `json:"children"`
+		Children []json.RawMessage `json:"children"` // Decoded one by one, see decodeChildren
+		After    string            `json:"after"`    // Fullname to pass as after= for the next page, empty on the last page
 	} `json:"data"`
 }
 
 // CommentResponse matches the Reddit API's comment listing structure
 type CommentResponse struct {
 	Data struct {
-		Children []struct {
-			Data Comment `json:"data"`
-		} `json:"children"`
+		Children []json.RawMessage `json:"children"` // Decoded one by one, see decodeChildren
 	} `json:"data"`
 }
 
+// Kinds of listing children: posts are t3, comments t1
+const listingKindPost = "t3"
+const listingKindComment = "t1"
+
+// listingChild is one child of a listing, its data is decoded once the kind is known to match
+type listingChild struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+// decodeChildren decodes the children of a listing one by one, so a malformed child doesn't fail
+// the whole listing. Children of another kind, that don't decode or that check rejects (missing
+// required fields) are logged and skipped. It returns the decoded items and the number skipped.
+func decodeChildren[T any](children []json.RawMessage, kind, endpoint string, check func(*T) error) ([]T, int) {
+	items := make([]T, 0, len(children))
+	skipped := 0
+	for i, raw := range children {
+		var child listingChild
+		var item T
+		err := json.Unmarshal(raw, &child)
+		switch {
+		case err != nil:
+		case child.Kind != kind:
+			err = fmt.Errorf("unexpected kind %q, want %s", child.Kind, kind)
+		case len(child.Data) == 0 || string(child.Data) == "null":
+			err = errors.New("child without data")
+		default:
+			if err = json.Unmarshal(child.Data, &item); err == nil {
+				err = check(&item)
+			}
+		}
+		if err != nil {
+			fmt.Printf("WARN: Skipping child %d of %s: %v\n", i, endpoint, err)
+			skipped++
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, skipped
+}
+
+// checkPost rejects a post without permalink, which would collide on the unique index.
+func checkPost(post *Post) error {
+	if post.Permalink == "" {
+		return fmt.Errorf("post %q in r/%s without permalink", post.Name, post.Subreddit)
+	}
+	return nil
+}
+
+// checkComment builds the permalink of a comment listed without one, rejecting the comment if
+// it lacks the IDs to build it.
+func checkComment(comment *Comment) error {
+	if comment.Permalink == "" {
+		comment.Permalink = comment.buildPermalink()
+	}
+	if comment.Permalink == "" {
+		return fmt.Errorf("comment %q in r/%s without permalink or IDs to build one", comment.ID, comment.Subreddit)
+	}
+	return nil
+}
+
+// ProcessedItem is the document stored in MongoDB for every notified post or comment
+type ProcessedItem struct {
+	Permalink   string    `bson:"permalink" json:"permalink"`
+	Subreddit   string    `bson:"subreddit" json:"subreddit"`
+	Kind        string    `bson:"kind,omitempty" json:"kind,omitempty"`       // "post" or "comment"
+	Listing     string    `bson:"listing,omitempty" json:"listing,omitempty"` // Listing that surfaced a post, e.g. "new" or "hot"
+	Keywords    []string  `bson:"keywords" json:"keywords"`
+	Groups      []string  `bson:"groups,omitempty" json:"groups,omitempty"`       // Keyword groups that matched
+	NearMiss    bool      `bson:"near_miss,omitempty" json:"near_miss,omitempty"` // Matched too few keywords to alert
+	Skipped     string    `bson:"skipped,omitempty" json:"skipped,omitempty"`     // Why the item was recorded without alerting, e.g. "too old" or "new account"
+	FullText    string    `bson:"full_text,omitempty" json:"full_text,omitempty"` // Matched text, only stored when store_full_text is enabled
+	ProcessedAt time.Time `bson:"processed_at" json:"processed_at"`
+
+	PostName     string   `bson:"post_name,omitempty" json:"post_name,omitempty"`           // Fullname of a post, so crossposts can find their original
+	ContentHash  string   `bson:"content_hash,omitempty" json:"content_hash,omitempty"`     // Hash of the normalized title and selftext of a post
+	DuplicateOf  string   `bson:"duplicate_of,omitempty" json:"duplicate_of,omitempty"`     // Permalink of the earlier match this post duplicates, no alert was sent
+	AlsoPostedIn []string `bson:"also_posted_in,omitempty" json:"also_posted_in,omitempty"` // Subreddits where suppressed duplicates of this match were posted
+
+	Profile string `bson:"profile,omitempty" json:"profile,omitempty"` // Profile that processed the item, empty for the top-level config
+	Title   string `bson:"title,omitempty" json:"title,omitempty"`     // Post title, or the parent post's title for comments
+	Author  string `bson:"author,omitempty" json:"author,omitempty"`
+
+	CreatedUTC time.Time `bson:"created_utc,omitempty" json:"created_utc,omitzero"` // When the post or comment was created on Reddit
+
+	DeadLettered bool `bson:"dead_lettered,omitempty" json:"dead_lettered,omitempty"` // Notification failed max_retries times, its alert is in notification_dlq
+
+	Backfilled bool `bson:"backfilled,omitempty" json:"backfilled,omitempty"` // Found by a quiet backfill, summed up in its digest instead of alerted
+
+	SheetSynced *bool `bson:"sheet_synced,omitempty" json:"sheet_synced,omitempty"` // Alerted match appended to the Google Sheet, unset without the integration
+
+	KeywordPriority string `bson:"keyword_priority,omitempty" json:"keyword_priority,omitempty"` // Highest priority of the matched keywords, low ones were left to the daily digest
+
+	Snippet string `bson:"snippet,omitempty" json:"snippet,omitempty"` // Start of the matched text, as quoted in alerts
+
+	Snapshot *MatchSnapshot `bson:"snapshot,omitempty" json:"snapshot,omitempty"` // The item as it matched, unless snapshot_matches is off
+
+	// Text search results only, never stored
+	TextScore float64 `bson:"text_score,omitempty" json:"text_score,omitempty"` // Relevance to the search
+	Highlight string  `bson:"-" json:"highlight,omitempty"`                     // HTML excerpt with the search terms in <mark>
+
+	Status          string    `bson:"status,omitempty" json:"status,omitempty"`                      // live, removed or deleted, as of the last status check
+	Score           *int      `bson:"score,omitempty" json:"score,omitempty"`                        // Score at the last status check
+	StatusCheckedAt time.Time `bson:"status_checked_at,omitempty" json:"status_checked_at,omitzero"` // Unset until status_check_days covers the match
+
+	// The whole Post or Comment under Reddit's field names, only stored when store_full_content is enabled
+	Content map[string]interface{} `bson:"content,omitempty" json:"content,omitempty"`
+}
+
 // --- Configuration ---
 var subreddits = []string{"WholesaleRealestate", "WholesalingHouses", "realestateinvesting", "RealEstateTechnology"}
-var keywords = []string{"VA","leads"}
+var keywords = plainKeywords([]string{"VA", "leads"})
+var minKeywordMatches = 1 // Distinct keywords an item needs to trigger an alert
+
+// Named keyword sets, matched in addition to keywords
+var keywordGroups = []KeywordGroup{}
+
+var watchedDomains = []string{} // Alert on posts linking to these domains (or subdomains) regardless of keywords
+var matchLinkURLs = false       // Also match keywords against link post URLs and domains
+var blockDomains = []string{}   // Silently skip posts linking to these domains ("self" matches self posts)
+var skipCrossposts = false      // Skip crossposts so the same content isn't notified once per subreddit
+var storeFullText = false       // Store the matched text with processed items so they can be replayed
+var storeFullContent = false    // Store the whole post or comment with processed items, in their content field
+var searchMode = false          // Fetch posts through Reddit search per subreddit and keyword instead of the listings
+var normalizeUnicode = true     // Match accent-insensitively ("cafe" matches "café"), disable for exact matching
+
+// Comment count bounds of posts. Skipped posts aren't recorded, so a post below the minimum is
+// checked again each cycle while it's in the listing (max_post_age_minutes still applies to the new listing).
+var minNumComments = 0
+var maxNumComments = -1 // -1 is unlimited
+
+// Comment length bounds in characters, shorter or longer comments are skipped before anything else (0 max is unlimited)
+var minCommentLength = 10
+var maxCommentLength = 0
+
+// Matches with the same content within this window only alert once
+var duplicatePostWindow = 48 * time.Hour
+
+// Posts of new listings and comments older than this are recorded without alerting, 0 disables
+// the check. Other listings (hot, top, search...) surface older posts on purpose.
+var maxItemAge = 60 * time.Minute
+
+// Items created more than this before startup are recorded the same way, so the first cycles
+// after a restart or downtime don't alert on the backlog of the listings. 0 disables the check.
+var startupLookback = 60 * time.Minute
+
+// startedAt is when the monitor started, see startupLookback
+var startedAt = time.Now()
+
+// backfillMode (--backfill) turns off maxItemAge and startupLookback, to alert on historical matches
+var backfillMode = false
+
+const domainMatchPrefix = "domain:" // Marks watched domain hits among matched keywords
+
+const searchMatchPrefix = "search: " // Marks search monitor results that matched no keyword
 
 // Email Configuration (Read from Environment Variables)
 var gmailUser = os.Getenv("GMAIL_USER")
@@ -62,25 +289,256 @@ var recipientEmail = os.Getenv("RECIPIENT_EMAIL")
 
 var mongoURI = os.Getenv("MONGODB_URI") // MongoDB Connection String
 
+// Fetch Configuration (Read from Environment Variables)
+var subredditChunkSize = envInt("SUBREDDIT_CHUNK_SIZE", 25) // Max subreddits combined into one listing URL
+var fetchConcurrency = envInt("FETCH_CONCURRENCY", 2)       // Max chunk requests in flight at once
+
+var debugLogging = os.Getenv("DEBUG") == "true" // Log why individual items are skipped
+
+// debugf prints a DEBUG log line when DEBUG=true.
+func debugf(format string, args ...interface{}) {
+	if debugLogging {
+		fmt.Printf("DEBUG: "+format+"\n", args...)
+	}
+}
+
 // --- Internal Setup ---
-var combinedSubreddits = strings.Join(subreddits, "+") // Keep this dynamic based on subreddits var
-var postEndpoint = fmt.Sprintf("https://www.reddit.com/r/%s/new/.json?limit=100", combinedSubreddits)
-var commentEndpoint = fmt.Sprintf("https://www.reddit.com/r/%s/comments/.json?limit=100", combinedSubreddits)
+// subredditChunk is a group of subreddits fetched together through one combined listing URL.
+type subredditChunk struct {
+	subreddits []string
+	listing    string // Listing that surfaced the items, e.g. "new", "top:week", "multireddit/new" or "comments"
+	endpoint   string
+	label      string // Subreddit name reported for every item, set for multireddits whose posts come from many subreddits
+}
+
+// Keep these dynamic based on the subreddits var
+var subredditConfigs = defaultSubredditConfigs(subreddits)
 
 // Processed Item Tracking (MongoDB)
+const mongoDatabaseName = "reddit_monitor"
+const processedItemsCollectionName = "processed_items"
+
 var mongoClient *mongo.Client
 var processedItemsCollection *mongo.Collection
 
 // Note: Persistence now handled by MongoDB
 
+// httpTransport honors HTTPS_PROXY/HTTP_PROXY explicitly, used by notifiers and heartbeats (see configureProxy)
+var httpTransport = newHTTPTransport()
+
 // HTTP Client with custom User-Agent
-var httpClient = &http.Client{Timeout: 10 * time.Second} // Add a timeout
-var userAgent = "GoKeywordMonitor/1.1 (by /u/Fawaazharden)" // Updated with actual Reddit username
+var httpClient = &http.Client{Timeout: 10 * time.Second, Transport: httpTransport} // Add a timeout
+
+// redditTransport carries Reddit requests, through reddit_proxy or REDDIT_PROXY_URL if set (see configureProxy)
+var redditTransport = newHTTPTransport()
+
+// redditHTTPClient is the HTTP client of redditClient
+var redditHTTPClient = &http.Client{Timeout: 10 * time.Second, Transport: redditTransport}
+
+// userAgent identifies the monitor to Reddit, REDDIT_USER_AGENT overrides it (Reddit asks for "<app>/<version> (by /u/<username>)")
+var userAgent = defaultUserAgent()
+
+// defaultUserAgent returns REDDIT_USER_AGENT, or the built-in User-Agent when unset.
+func defaultUserAgent() string {
+	if agent := os.Getenv("REDDIT_USER_AGENT"); agent != "" {
+		return agent
+	}
+	return "GoKeywordMonitor/1.1 (by /u/Fawaazharden)"
+}
+
+// newHTTPTransport returns the default transport's settings with the proxy taken from the environment.
+func newHTTPTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	return transport
+}
+
+// redditProxyURL is a static proxy for Reddit requests, overriding the proxy environment variables.
+// The reddit_proxy config field overrides it in turn.
+var redditProxyURL = os.Getenv("REDDIT_PROXY_URL")
+
+// HTTP transport settings from the config, applied once at startup by configureProxy
+var (
+	redditProxy      string        // reddit_proxy, overrides REDDIT_PROXY_URL
+	proxyNotifiers   bool          // Also send notifier and heartbeat requests through the Reddit proxy
+	httpDialTimeout  time.Duration // 0 keeps the default (30s)
+	httpTLSTimeout   time.Duration // 0 keeps the default (10s)
+	httpMaxIdleConns int           // 0 keeps the default (100)
+)
+
+// parseProxyURL parses a proxy URL named name, which must be http, https or socks5 with a host.
+func parseProxyURL(name, raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid %s %q: scheme must be http, https or socks5", name, proxyURL.Redacted())
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid %s %q: missing host", name, proxyURL.Redacted())
+	}
+	return proxyURL, nil
+}
+
+// configureProxy applies the transport settings, and routes Reddit requests (and notifier requests
+// with proxy_notifiers) through reddit_proxy or REDDIT_PROXY_URL if set. Without either, the proxy
+// environment variables apply.
+func configureProxy() error {
+	for _, transport := range []*http.Transport{redditTransport, httpTransport} {
+		if httpDialTimeout > 0 {
+			transport.DialContext = (&net.Dialer{Timeout: httpDialTimeout, KeepAlive: 30 * time.Second}).DialContext
+		}
+		if httpTLSTimeout > 0 {
+			transport.TLSHandshakeTimeout = httpTLSTimeout
+		}
+		if httpMaxIdleConns > 0 {
+			transport.MaxIdleConns = httpMaxIdleConns
+		}
+	}
+
+	name, raw := "reddit_proxy", redditProxy
+	if raw == "" {
+		name, raw = "REDDIT_PROXY_URL", redditProxyURL
+	}
+	if raw == "" {
+		return nil
+	}
+	proxyURL, err := parseProxyURL(name, raw)
+	if err != nil {
+		return err
+	}
+	redditTransport.Proxy = http.ProxyURL(proxyURL)
+	if proxyNotifiers {
+		httpTransport.Proxy = http.ProxyURL(proxyURL)
+	}
+	fmt.Println("Using proxy", proxyURL.Redacted(), "for Reddit requests")
+	return nil
+}
+
+// redditConnectivityURL is fetched by the startup connectivity check
+const redditConnectivityURL = "https://www.reddit.com/r/popular/about.json"
+
+// checkRedditConnectivity makes one request to Reddit through the configured transport, so a
+// blocked IP or a broken proxy shows up at startup rather than as failing cycles.
+func checkRedditConnectivity() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	resp, err := redditClient.redditGet(ctx, redditConnectivityURL)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d %s", resp.StatusCode, resp.Status)
+	}
+	return nil
+}
+
+// HTTPDoer is the part of *http.Client used for Reddit requests, so tests can inject canned responses
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// RedditClient fetches listings from the Reddit API through an injectable HTTPDoer
+type RedditClient struct {
+	HTTP      HTTPDoer
+	UserAgent string
+	Unpaced   bool // Skip redditLimiter, for responses that don't come from Reddit (--replay)
+}
+
+// NewRedditClient returns a RedditClient sending requests through doer with the default User-Agent.
+func NewRedditClient(doer HTTPDoer) *RedditClient {
+	return &RedditClient{HTTP: doer, UserAgent: userAgent}
+}
+
+// Default client used by main, backed by redditHTTPClient
+var redditClient = NewRedditClient(redditHTTPClient)
+
+// envInt reads a positive integer from an environment variable, falling back to def when unset or invalid.
+func envInt(name string, def int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		fmt.Printf("WARN: Invalid value %q for %s, using default %d\n", raw, name, def)
+		return def
+	}
+	return value
+}
+
+// buildSubredditChunks splits the subreddit list into groups of at most size names
+// and builds the combined endpoint of the given listing for each group.
+func buildSubredditChunks(subs []string, listing string, size int) []subredditChunk {
+	chunks := []subredditChunk{}
+	for start := 0; start < len(subs); start += size {
+		end := start + size
+		if end > len(subs) {
+			end = len(subs)
+		}
+		chunks = append(chunks, subredditChunk{
+			subreddits: subs[start:end],
+			listing:    listing,
+			endpoint:   listingEndpoint(strings.Join(subs[start:end], "+"), listing),
+		})
+	}
+	return chunks
+}
+
+// buildListingChunks groups subreddits by listing sort and chunks each group, so every sort
+// configured for a subreddit is fetched as its own listing.
+func buildListingChunks(configs []SubredditConfig, size int) []subredditChunk {
+	listingOrder := []string{}
+	bySort := map[string][]string{}
+	multireddits := []subredditChunk{}
+	for _, cfg := range configs {
+		if cfg.isMultireddit() {
+			// Fetched on its own from the configured URL, which can't be combined with others
+			multireddits = append(multireddits, subredditChunk{
+				subreddits: []string{cfg.Name},
+				listing:    "multireddit/" + multiredditSort(cfg.MultiredditURL),
+				endpoint:   cfg.MultiredditURL,
+				label:      cfg.Name,
+			})
+			continue
+		}
+		for _, listing := range cfg.listings() {
+			if _, ok := bySort[listing]; !ok {
+				listingOrder = append(listingOrder, listing)
+			}
+			bySort[listing] = append(bySort[listing], cfg.Name)
+		}
+	}
+
+	chunks := []subredditChunk{}
+	for _, listing := range listingOrder {
+		chunks = append(chunks, buildSubredditChunks(bySort[listing], listing, size)...)
+	}
+	return append(chunks, multireddits...)
+}
+
+// listingEndpoint builds the JSON listing URL for combined subreddits, e.g. "top:week" becomes /top/.json?t=week.
+func listingEndpoint(combined, listing string) string {
+	sort, window, _ := strings.Cut(listing, ":")
+	endpoint := fmt.Sprintf("https://www.reddit.com/r/%s/%s/.json?limit=100", combined, sort)
+	if window != "" {
+		endpoint += "&t=" + window
+	}
+	return endpoint
+}
 
 // --- MongoDB Setup ---
 
-// setupMongoIndex ensures a unique index exists on the permalink field for efficient lookups.
-// Run this in a goroutine from main to avoid blocking startup.
+// setupMongoIndex ensures the processed_items indexes exist:
+//   - permalink, unique (permalink and profile with per-profile dedupe) for the processed lookups
+//   - content_hash and post_name for duplicate post lookups
+//   - subreddit ascending and created_utc descending for date range queries, newest first
+//
+// Run this in a goroutine from main to avoid blocking startup. Failures are logged as warnings.
 func setupMongoIndex() {
 	if processedItemsCollection == nil {
 		fmt.Println("WARN: Cannot setup index, MongoDB collection is nil.")
@@ -93,6 +551,13 @@ func setupMongoIndex() {
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
+	if profileDedupe == "profile" {
+		// Each profile records its own copy of an item, so permalinks are only unique per profile
+		if _, err := processedItemsCollection.Indexes().DropOne(ctx, "permalink_1"); err == nil {
+			fmt.Println("Dropped unique index 'permalink_1' for per-profile dedupe.")
+		}
+		indexModel.Keys = bson.D{{Key: "permalink", Value: 1}, {Key: "profile", Value: 1}}
+	}
 	indexName, err := processedItemsCollection.Indexes().CreateOne(ctx, indexModel)
 	if err != nil {
 		// Log error, but maybe don't make it fatal? Index might exist or other issues.
@@ -100,351 +565,1507 @@ func setupMongoIndex() {
 	} else {
 		fmt.Printf("MongoDB index '%s' on 'permalink' ensured.\n", indexName)
 	}
+
+	// Duplicate post lookups go by content hash or by the original's fullname
+	for _, field := range []string{"content_hash", "post_name"} {
+		indexModel := mongo.IndexModel{Keys: map[string]interface{}{field: 1}}
+		if _, err := processedItemsCollection.Indexes().CreateOne(ctx, indexModel); err != nil {
+			fmt.Printf("WARN: Could not create/verify MongoDB index on '%s': %v\n", field, err)
+		}
+	}
+
+	// Date range queries per subreddit, newest first. Built in the background on servers before 4.2.
+	dateIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "subreddit", Value: 1}, {Key: "created_utc", Value: -1}},
+		Options: options.Index().SetBackground(true),
+	}
+	if _, err := processedItemsCollection.Indexes().CreateOne(ctx, dateIndex); err != nil {
+		fmt.Printf("WARN: Could not create/verify MongoDB index on 'subreddit, created_utc': %v\n", err)
+	}
+
+	// Matches still to append to the Google Sheet, only the few unsynced ones are indexed
+	sheetIndex := mongo.IndexModel{
+		Keys:    map[string]interface{}{"sheet_synced": 1},
+		Options: options.Index().SetPartialFilterExpression(map[string]interface{}{"sheet_synced": false}),
+	}
+	if _, err := processedItemsCollection.Indexes().CreateOne(ctx, sheetIndex); err != nil {
+		fmt.Printf("WARN: Could not create/verify MongoDB index on 'sheet_synced': %v\n", err)
+	}
+
+	// Text search over the stored titles and texts (matches --search, /api/matches?q=)
+	if err := ensureTextIndex(ctx, processedItemsCollection); err != nil {
+		fmt.Printf("WARN: Could not create/verify MongoDB text index '%s': %v\n", textIndexName, err)
+	}
 }
 
 // --- Email Sending ---
 
-// sendEmail sends an email notification using configured Gmail credentials.
-func sendEmail(subject, body string) error {
-	// Validation happens in main() now to check env vars at startup
+// SMTP server configuration.
+const smtpHost = "smtp.gmail.com"
+const smtpPort = "587" // Standard TLS port for Gmail SMTP
 
-	// Set up authentication information.
-	auth := smtp.PlainAuth("", gmailUser, gmailAppPassword, "smtp.gmail.com")
+// sendEmailTo sends an email to a specific recipient using configured Gmail credentials,
+// over the shared SMTP connection.
+func sendEmailTo(recipient, subject, body string) error {
+	// Validation happens in main() now to check env vars at startup
 
-	// SMTP server configuration.
-	smtpHost := "smtp.gmail.com"
-	smtpPort := "587" // Standard TLS port for Gmail SMTP
+	// Message formatting, shared with the HTTP API providers
+	return sendSMTPMessage(recipient, newEmailMessage(gmailUser, recipient, subject, body).smtpData())
+}
 
-	// Message formatting (RFC 822 style).
-	to := []string{recipientEmail}
-	// Note: Ensure correct line endings (\r\n) for email headers/body separation.
-	msg := []byte("To: " + recipientEmail + "\r\n" +
-		"Subject: " + subject + "\r\n" +
-		"\r\n" + // Empty line separates headers from body
-		body + "\r\n")
+// sendSMTPMessage sends the formatted message msg to recipient over the shared SMTP connection.
+func sendSMTPMessage(recipient string, msg []byte) error {
+	to := []string{recipient}
 
 	// Send the email.
-	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, gmailUser, to, msg)
+	err := smtpConn.send(gmailUser, to, msg)
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
-	fmt.Println("Email sent successfully to", recipientEmail)
+	fmt.Println("Email sent successfully to", recipient)
 	return nil
 }
 
-
 // --- Reddit API Fetching ---
 
-// fetchPosts retrieves the latest posts from the Reddit API using a custom User-Agent
-func fetchPosts(endpoint string) ([]Post, error) {
-	req, err := http.NewRequest("GET", endpoint, nil)
+// redditGet sends a GET request for endpoint with the client's User-Agent, after waiting for a
+// slot of the shared redditLimiter. Every Reddit request goes through it so none bypass the limit.
+func (c *RedditClient) redditGet(ctx context.Context, endpoint string) (*http.Response, error) {
+	return c.redditGetIfNoneMatch(ctx, endpoint, "")
+}
+
+// redditGetIfNoneMatch is redditGet sending If-None-Match with etag, unless it is "".
+func (c *RedditClient) redditGetIfNoneMatch(ctx context.Context, endpoint, etag string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
-	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set("Accept-Encoding", "gzip")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
 
-	resp, err := httpClient.Do(req)
+	if !c.Unpaced {
+		waited, err := waitRedditLimiter(ctx)
+		addLimiterWait(ctx, waited)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTP.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error executing request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d %s", resp.StatusCode, resp.Status)
+	if resp.StatusCode == http.StatusUnauthorized && req.Header.Get("Authorization") != "" {
+		redditToken.reset() // Revoked or expired early, the next request gets a new one
 	}
-
-	var response PostResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
-	if err != nil {
-		// Consider logging the raw body here for debugging if JSON parsing fails
-		return nil, fmt.Errorf("error decoding JSON response: %w", err)
+	// Asking for gzip ourselves turns off the transport's transparent decompression
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("error decompressing response: %w", err)
+		}
+		resp.Body = gzipBody{Reader: reader, body: resp.Body}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
 	}
+	return resp, nil
+}
 
-	posts := make([]Post, 0, len(response.Data.Children))
-	for _, child := range response.Data.Children {
-		posts = append(posts, child.Data)
-	}
-	return posts, nil
+// gzipBody decompresses a response body and closes the underlying one.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
 }
 
-// fetchComments retrieves the latest comments from the Reddit API using a custom User-Agent
-func fetchComments(endpoint string) ([]Comment, error) {
-	req, err := http.NewRequest("GET", endpoint, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-	req.Header.Set("User-Agent", userAgent)
+// Close closes the gzip reader and the response body.
+func (b gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+// maxLoggedBody is how much of an undecodable response body is logged
+const maxLoggedBody = 512
 
-	resp, err := httpClient.Do(req)
+// getRedditJSON fetches endpoint through redditGet and decodes its JSON body into a T. A non-200
+// status is an error. A body that isn't valid JSON (e.g. an HTML block page) is logged with its
+// status code, content type and first bytes.
+func getRedditJSON[T any](ctx context.Context, c *RedditClient, endpoint string) (T, error) {
+	var result T
+	resp, err := c.redditGet(ctx, endpoint)
 	if err != nil {
-		return nil, fmt.Errorf("error executing request: %w", err)
+		return result, err
 	}
 	defer resp.Body.Close()
+	return decodeRedditJSON[T](resp, endpoint)
+}
 
+// decodeRedditJSON decodes the body of a response to endpoint as getRedditJSON does.
+func decodeRedditJSON[T any](resp *http.Response, endpoint string) (T, error) {
+	var result T
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d %s", resp.StatusCode, resp.Status)
+		return result, fmt.Errorf("unexpected status code: %d %s", resp.StatusCode, resp.Status)
 	}
-
-	var response CommentResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding JSON response: %w", err)
-	}
-
-	comments := make([]Comment, 0, len(response.Data.Children))
-	for _, child := range response.Data.Children {
-		comments = append(comments, child.Data)
+		return result, fmt.Errorf("error reading response: %w", err)
 	}
-	return comments, nil
-}
-
-// findKeywords checks for whole word keyword matches in text (case-insensitive)
-func findKeywords(text string, keywords []string) []string {
-	found := []string{}
-	// Convert text to lower case once for case-insensitive matching
-	textLower := strings.ToLower(text)
-
-	for _, keyword := range keywords {
-		// Escape regex special characters in the keyword
-		escapedKeyword := regexp.QuoteMeta(keyword)
-		// Create a case-insensitive regex pattern with word boundaries
-		// (?i) makes it case-insensitive, \b ensures whole word matching
-		pattern := fmt.Sprintf(`(?i)\b%s\b`, escapedKeyword)
-		re, err := regexp.Compile(pattern)
-		if err != nil {
-			// Handle regex compilation error, e.g., log it
-			fmt.Printf("Error compiling regex for keyword '%s': %v\n", keyword, err)
-			continue // Skip this keyword if regex is invalid
-		}
-
-		// Check if the pattern matches the lowercased text
-		if re.MatchString(textLower) {
-			found = append(found, keyword) // Add the original keyword (not lowercased)
+	if err := json.Unmarshal(body, &result); err != nil {
+		snippet := body
+		if len(snippet) > maxLoggedBody {
+			snippet = snippet[:maxLoggedBody]
 		}
+		fmt.Printf("Error decoding JSON from %s: status %d, content type %q, body starts with %q\n",
+			endpoint, resp.StatusCode, resp.Header.Get("Content-Type"), snippet)
+		return result, fmt.Errorf("error decoding JSON response: %w", err)
 	}
-	return found
+	return result, nil
 }
 
-// processPosts checks posts for keywords, sends email for new matches, and tracks processed IDs.
-func processPosts(posts []Post) {
-	// newMatchesFound variable is less relevant now, DB handles state.
-	for _, post := range posts {
+// fetchPosts retrieves the latest posts from the Reddit API using a custom User-Agent. It also
+// returns the number of listing children skipped as malformed.
+func (c *RedditClient) fetchPosts(ctx context.Context, endpoint string) ([]Post, int, error) {
+	posts, _, skipped, err := c.fetchPostListing(ctx, endpoint)
+	return posts, skipped, err
+}
 
-		// --- Check if already processed (MongoDB Query) ---
-		var result struct{} // We only care if a document is found, not its content
-		ctxFind, cancelFind := context.WithTimeout(context.Background(), 5*time.Second)
-		// FindOne returns ErrNoDocuments if not found
-		err := processedItemsCollection.FindOne(ctxFind, map[string]interface{}{"permalink": post.Permalink}).Decode(&result)
-		cancelFind() // Release context resources
+// fetchPostsPage retrieves one page of a post listing and the after token of the next page ("" on the last one).
+func (c *RedditClient) fetchPostsPage(ctx context.Context, endpoint string) ([]Post, string, error) {
+	posts, after, _, err := c.fetchPostListing(ctx, endpoint)
+	return posts, after, err
+}
 
-		if err == nil {
-			// Found the document, already processed
-			continue
-		} else if err != mongo.ErrNoDocuments {
-			// An actual error occurred during the query
-			fmt.Printf("Error checking MongoDB for post permalink %s: %v\n", post.Permalink, err)
-			continue // Skip this post on DB error
-		}
-		// If err == mongo.ErrNoDocuments, it means it's NOT processed, so proceed.
-		// --- End Check ---
+// fetchSearch retrieves the newest posts in subreddit matching a Reddit search query for keyword.
+func (c *RedditClient) fetchSearch(ctx context.Context, subreddit, keyword string) ([]Post, error) {
+	endpoint := SearchMonitor{Query: keyword, Subreddit: subreddit, Sort: "new"}.endpoint()
+	posts, _, _, err := c.fetchPostListing(ctx, endpoint)
+	return posts, err
+}
 
-		// Check for keywords (same as before)
-		text := post.Title + " " + post.Selftext
-		found := findKeywords(text, keywords)
+// fetchPostListing retrieves a post listing (subreddit, search...), its after token and the
+// number of children skipped as malformed, unless the posts circuit is open.
+func (c *RedditClient) fetchPostListing(ctx context.Context, endpoint string) ([]Post, string, int, error) {
+	if !postsBreaker.allow() {
+		return nil, "", 0, ErrCircuitOpen
+	}
+	posts, after, skipped, err := c.getPostListing(ctx, endpoint)
+	if ctx.Err() == nil {
+		postsBreaker.record(err) // Cancelled requests say nothing about Reddit
+	}
+	return posts, after, skipped, err
+}
 
-		if len(found) > 0 {
-			// New match found!
-			fmt.Printf("Found keywords %v in NEW post from r/%s: https://www.reddit.com%s\n",
-				found, post.Subreddit, post.Permalink)
+// getPostListing sends the request of fetchPostListing.
+func (c *RedditClient) getPostListing(ctx context.Context, endpoint string) ([]Post, string, int, error) {
+	response, modified, err := getListingJSON[PostResponse](ctx, c, endpoint)
+	if err != nil || !modified {
+		return nil, "", 0, err
+	}
 
-			// Format email content (link only)
-			subject := fmt.Sprintf("Reddit Keyword Alert: Post in r/%s", post.Subreddit)
-			body := fmt.Sprintf("Keywords %v found in post:\nhttps://www.reddit.com%s", found, post.Permalink)
+	posts, skipped := decodeChildren(response.Data.Children, listingKindPost, endpoint, checkPost)
+	return posts, response.Data.After, skipped, nil
+}
 
-			// Send email
-			err := sendEmail(subject, body)
-			if err != nil {
-				fmt.Println("Error sending post notification email:", err)
-				// Decide if you want to stop processing or just log the error
-				// continue // Optional: Continue processing other posts even if email fails
-			} else {
-				// --- Mark as processed (MongoDB Insert) ---
-				ctxInsert, cancelInsert := context.WithTimeout(context.Background(), 5*time.Second)
-				_, insertErr := processedItemsCollection.InsertOne(ctxInsert, map[string]interface{}{
-					"permalink":    post.Permalink,
-					"processed_at": time.Now(), // Store processing time
-				})
-				cancelInsert()
-
-				if insertErr != nil {
-					// Handle potential duplicate key error gracefully if index exists
-					// but the check somehow missed it (less likely with FindOne)
-					// Or other insertion errors
-					// If it's a duplicate key error (code 11000), we can often ignore it.
-					if mongo.IsDuplicateKeyError(insertErr) {
-						fmt.Printf("Info: Attempted to insert duplicate permalink %s, already processed.\n", post.Permalink)
-					} else {
-						fmt.Printf("Error inserting processed post permalink %s into MongoDB: %v\n", post.Permalink, insertErr)
-					}
-				}
-				// --- End Insert ---
-			}
-		}
-		// No need to add to a map or save a file here
+// fetchComments retrieves the latest comments from the Reddit API using a custom User-Agent,
+// unless the comments circuit is open. It also returns the number of listing children skipped
+// as malformed.
+func (c *RedditClient) fetchComments(ctx context.Context, endpoint string) ([]Comment, int, error) {
+	if !commentsBreaker.allow() {
+		return nil, 0, ErrCircuitOpen
 	}
-	// No need for the final saveProcessedIDs call here
+	comments, skipped, err := c.getComments(ctx, endpoint)
+	if ctx.Err() == nil {
+		commentsBreaker.record(err)
+	}
+	return comments, skipped, err
 }
 
-// processComments checks comments for keywords, sends email for new matches, and tracks processed IDs.
-func processComments(comments []Comment) {
-	// newMatchesFound variable is less relevant now, DB handles state.
-	for _, comment := range comments {
-
-		// --- Check if already processed (MongoDB Query) ---
-		var result struct{}
-		ctxFind, cancelFind := context.WithTimeout(context.Background(), 5*time.Second)
-		err := processedItemsCollection.FindOne(ctxFind, map[string]interface{}{"permalink": comment.Permalink}).Decode(&result)
-		cancelFind()
+// getComments sends the request of fetchComments.
+func (c *RedditClient) getComments(ctx context.Context, endpoint string) ([]Comment, int, error) {
+	response, modified, err := getListingJSON[CommentResponse](ctx, c, endpoint)
+	if err != nil || !modified {
+		return nil, 0, err
+	}
 
-		if err == nil {
-			continue // Already processed
-		} else if err != mongo.ErrNoDocuments {
-			fmt.Printf("Error checking MongoDB for comment permalink %s: %v\n", comment.Permalink, err)
-			continue // Skip on DB error
-		}
-		// Not processed, continue
-		// --- End Check ---
+	comments, skipped := decodeChildren(response.Data.Children, listingKindComment, endpoint, checkComment)
+	return comments, skipped, nil
+}
 
-		// Check for keywords (same as before)
-		found := findKeywords(comment.Body, keywords)
+// postIDFromPermalink extracts the subreddit and post ID from a permalink like /r/<sub>/comments/<id>/<slug>/<comment>/
+func postIDFromPermalink(permalink string) (subreddit, postID string, ok bool) {
+	parts := strings.Split(strings.Trim(permalink, "/"), "/")
+	if len(parts) < 4 || parts[0] != "r" || parts[2] != "comments" || parts[3] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[3], true
+}
 
-		if len(found) > 0 {
-			// New match found!
-			fmt.Printf("Found keywords %v in NEW comment from r/%s: https://www.reddit.com%s\n",
-				found, comment.Subreddit, comment.Permalink)
+// fetchParentPost retrieves the post a comment belongs to, using the comment's permalink
+func (c *RedditClient) fetchParentPost(ctx context.Context, commentPermalink string) (*Post, error) {
+	subreddit, postID, ok := postIDFromPermalink(commentPermalink)
+	if !ok {
+		return nil, fmt.Errorf("cannot derive post ID from permalink %q", commentPermalink)
+	}
+	// limit=1 keeps the comment tree in the response small, we only need the post
+	endpoint := fmt.Sprintf("https://www.reddit.com/r/%s/comments/%s.json?limit=1", subreddit, postID)
 
-			// Format email content (link only)
-			subject := fmt.Sprintf("Reddit Keyword Alert: Comment in r/%s", comment.Subreddit)
-			body := fmt.Sprintf("Keywords %v found in comment:\nhttps://www.reddit.com%s", found, comment.Permalink)
+	// The response is [post listing, comment listing]
+	listings, err := getRedditJSON[[]json.RawMessage](ctx, c, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if len(listings) == 0 {
+		return nil, fmt.Errorf("empty response for post %s", postID)
+	}
+	var postListing PostResponse
+	if err := json.Unmarshal(listings[0], &postListing); err != nil {
+		return nil, fmt.Errorf("error decoding post listing: %w", err)
+	}
+	posts, skipped := decodeChildren(postListing.Data.Children, listingKindPost, endpoint, checkPost)
+	if len(posts) == 0 {
+		if skipped > 0 {
+			return nil, fmt.Errorf("post %s is malformed", postID)
+		}
+		return nil, fmt.Errorf("post %s not found", postID)
+	}
+	return &posts[0], nil
+}
 
-			// Send email
-			err := sendEmail(subject, body)
+// fetchChunked runs fetch against every chunk with bounded concurrency and merges the results.
+// A failing chunk only loses its own subreddits; the error is logged and the count of failed chunks returned.
+func fetchChunked[T any](chunks []subredditChunk, kind string, fetch func(subredditChunk) ([]T, error)) ([]T, int) {
+	results := make([][]T, len(chunks))
+	failed := make([]bool, len(chunks))
+	sem := make(chan struct{}, fetchConcurrency)
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk subredditChunk) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			items, err := fetch(chunk)
 			if err != nil {
-				fmt.Println("Error sending comment notification email:", err)
-				// continue // Optional: Continue processing other comments even if email fails
-			} else {
-				// --- Mark as processed (MongoDB Insert) ---
-				ctxInsert, cancelInsert := context.WithTimeout(context.Background(), 5*time.Second)
-				_, insertErr := processedItemsCollection.InsertOne(ctxInsert, map[string]interface{}{
-					"permalink":    comment.Permalink,
-					"processed_at": time.Now(),
-				})
-				cancelInsert()
-
-				if insertErr != nil {
-					if mongo.IsDuplicateKeyError(insertErr) {
-						fmt.Printf("Info: Attempted to insert duplicate permalink %s, already processed.\n", comment.Permalink)
-					} else {
-						fmt.Printf("Error inserting processed comment permalink %s into MongoDB: %v\n", comment.Permalink, insertErr)
-					}
-				}
-				// --- End Insert ---
+				fmt.Printf("Error fetching %s (%s) for subreddits %v: %v\n", kind, chunk.listing, chunk.subreddits, err)
+				failed[i] = true
+				return
 			}
+			results[i] = items
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	merged := []T{}
+	failedCount := 0
+	for i := range chunks {
+		if failed[i] {
+			failedCount++
+			continue
 		}
+		// Overlap between chunks is harmless, the processed-items check dedupes by permalink
+		merged = append(merged, results[i]...)
 	}
-	// No need for the final saveProcessedIDs call here
+	return merged, failedCount
 }
 
-func main() {
-	fmt.Println("Starting Reddit keyword monitor...")
+// keywordPatternCache holds compiled patterns per keyword spec label, cleared when the config is loaded
+var keywordPatternMu sync.Mutex
+var keywordPatternCache = map[string]*regexp.Regexp{}
+
+// compileKeywordPattern builds the whole word regex used to match a keyword,
+// case-insensitive unless the spec says otherwise.
+func compileKeywordPattern(spec KeywordSpec) (*regexp.Regexp, error) {
+	keywordPatternMu.Lock()
+	defer keywordPatternMu.Unlock()
+	if re, ok := keywordPatternCache[spec.label()]; ok {
+		return re, nil
+	}
 
-	// --- Configuration Validation ---
-	if gmailUser == "" || gmailAppPassword == "" || recipientEmail == "" {
-		fmt.Println("FATAL: Email environment variables (GMAIL_USER, GMAIL_APP_PASSWORD, RECIPIENT_EMAIL) must be set.")
-		os.Exit(1)
+	// Escape regex special characters in the (accent-folded) keyword
+	pattern := regexp.QuoteMeta(foldText(spec.Keyword))
+	if spec.Pattern != "" {
+		// User-supplied regex is used as-is, Go's RE2 engine keeps it linear-time
+		pattern = "(?:" + spec.Pattern + ")"
+	} else if spec.wholeWord() {
+		// \b is ASCII-only in Go, so boundaries are any non-letter/digit (or text edge),
+		// which also handles em-dashes and curly quotes.
+		pattern = `(?:^|[^\p{L}\p{N}_])` + pattern + `(?:[^\p{L}\p{N}_]|$)`
 	}
-	if mongoURI == "" {
-		fmt.Println("FATAL: MONGODB_URI environment variable must be set.")
-		os.Exit(1)
+	if !spec.CaseSensitive {
+		// (?i) makes it case-insensitive
+		pattern = "(?i)" + pattern
 	}
-
-	// --- Connect to MongoDB ---
-	var err error
-	clientOptions := options.Client().ApplyURI(mongoURI)
-	ctxConnect, cancelConnect := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancelConnect()
-	mongoClient, err = mongo.Connect(ctxConnect, clientOptions)
+	re, err := regexp.Compile(pattern)
 	if err != nil {
-		fmt.Printf("FATAL: Unable to connect to MongoDB: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
+	keywordPatternCache[spec.label()] = re
+	return re, nil
+}
 
-	// Ping the primary node to verify connection
-	ctxPing, cancelPing := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancelPing()
-	err = mongoClient.Ping(ctxPing, readpref.Primary())
-	if err != nil {
-		fmt.Printf("FATAL: MongoDB ping failed: %v\n", err)
-		// Attempt to disconnect before exiting
-		_ = mongoClient.Disconnect(context.Background())
+// resetKeywordPatterns drops compiled patterns, e.g. after normalization settings change.
+func resetKeywordPatterns() {
+	keywordPatternMu.Lock()
+	keywordPatternCache = map[string]*regexp.Regexp{}
+	keywordPatternMu.Unlock()
+}
+
+// findKeywords checks for whole word keyword matches in a title and body, honoring each
+// keyword's case sensitivity, substring and field options. Pass "" as title for comments.
+func findKeywords(title, body string, keywords []KeywordSpec) []string {
+	found := []string{}
+	// Unescape HTML entities and strip markdown so markers don't break word boundaries
+	title = foldText(normalizeText(title))
+	body = foldText(normalizeText(body))
+
+	for _, spec := range keywords {
+		if len(spec.Near) > 0 {
+			// Proximity specs match on words, report the smallest window so the email can show it
+			for _, field := range spec.fieldTexts(title, body) {
+				if window, ok := findProximityWindow(field, foldTerms(spec.Near), spec.proximityDistance(), spec.CaseSensitive); ok {
+					found = append(found, fmt.Sprintf("%s = %q", spec.label(), window))
+					break
+				}
+			}
+			continue
+		}
+
+		re, err := compileKeywordPattern(spec)
+		if err != nil {
+			// Handle regex compilation error, e.g., log it
+			fmt.Printf("Error compiling regex for keyword '%s': %v\n", spec.Keyword, err)
+			continue // Skip this keyword if regex is invalid
+		}
+
+		// Check the pattern against the fields this keyword applies to
+		match := ""
+		if spec.matchesTitle() {
+			match = re.FindString(title)
+		}
+		if match == "" && spec.matchesBody() {
+			match = re.FindString(body)
+		}
+		if match == "" {
+			continue
+		}
+		if spec.Pattern != "" {
+			// Show what a raw pattern actually matched (a phone number, a price...)
+			found = append(found, fmt.Sprintf("%s = %q", spec.label(), match))
+		} else {
+			found = append(found, spec.label()) // Report the spec that fired, not the matched text
+		}
+	}
+	return found
+}
+
+// matchText matches title and body against the top-level keywords and keyword groups, see matchRules.matchText.
+func matchText(title, body string) (found []string, groups []string, alert bool) {
+	return currentMatchRules().matchText(title, body)
+}
+
+// matchText finds the ungrouped keywords and every keyword group's keywords in title and body.
+// A keyword listed in several places is reported once and groups are returned in config order.
+// alert reports whether the match reached its threshold, see meetsMatchThreshold; a match
+// below it is still returned so it can be recorded as a near miss.
+func (r matchRules) matchText(title, body string) (found []string, groups []string, alert bool) {
+	found = []string{}
+	seen := map[string]bool{}
+	for _, label := range findKeywords(title, body, r.keywords) {
+		if !seen[label] {
+			seen[label] = true
+			found = append(found, label)
+		}
+	}
+	alert = meetsMatchThreshold(found, r.minMatches)
+	for _, group := range r.groups {
+		groupFound := findKeywords(title, body, group.Keywords)
+		if len(groupFound) == 0 {
+			continue
+		}
+		if meetsMatchThreshold(groupFound, group.minMatches()) {
+			groups = append(groups, group.Name) // Only groups that reached their threshold are reported
+			alert = true
+		}
+		for _, label := range groupFound {
+			if !seen[label] {
+				seen[label] = true
+				found = append(found, label)
+			}
+		}
+	}
+	return found, groups, alert
+}
+
+// applyKeywordLogic applies the subreddit's keyword_logic to a match's alert. In AND mode an item
+// only alerts if every ungrouped keyword was found; a keyword group reaching its threshold, a
+// watched domain or a search monitor hit still alerts on its own. Matches that no longer alert
+// are recorded as near misses.
+func (r matchRules) applyKeywordLogic(subreddit string, found, groups []string, alert bool) bool {
+	if !alert || len(groups) > 0 || r.subredditConfig(subreddit).keywordLogic() != "AND" {
+		return alert
+	}
+	for _, match := range found {
+		if strings.HasPrefix(match, domainMatchPrefix) || strings.HasPrefix(match, searchMatchPrefix) {
+			return true
+		}
+	}
+	for _, spec := range r.keywords {
+		if !containsKeyword(found, spec.label()) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsKeyword reports whether found holds label, either as is or with the text a raw pattern matched.
+func containsKeyword(found []string, label string) bool {
+	for _, match := range found {
+		if match == label || strings.HasPrefix(match, label+" = ") {
+			return true
+		}
+	}
+	return false
+}
+
+// meetsMatchThreshold reports whether found holds at least min distinct keywords,
+// so a keyword that occurs several times still counts once.
+func meetsMatchThreshold(found []string, min int) bool {
+	distinct := map[string]bool{}
+	for _, label := range found {
+		distinct[label] = true
+	}
+	return len(distinct) > 0 && len(distinct) >= min
+}
+
+// truncate shortens s to at most limit runes, ending in "..." when cut.
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-3]) + "..."
+}
+
+// alertSubject builds the notification subject, leading with the matched groups if any,
+// e.g. "[hiring, lead-gen] match in r/WholesalingHouses". One item gets one subject for all its groups.
+func alertSubject(kind, subreddit string, groups []string) string {
+	if len(groups) > 0 {
+		return fmt.Sprintf("[%s] match in r/%s", strings.Join(groups, ", "), subreddit)
+	}
+	return fmt.Sprintf("Reddit Keyword Alert: %s in r/%s", kind, subreddit)
+}
+
+// linkDomain returns the lowercased domain a post links to without "www.", or "" for self posts.
+func (p Post) linkDomain() string {
+	if p.IsSelf {
+		return ""
+	}
+	domain := strings.ToLower(p.Domain)
+	if domain == "" && p.URL != "" {
+		// Search results and older listings may omit domain, fall back to the URL host
+		if parsed, err := url.Parse(p.URL); err == nil {
+			domain = strings.ToLower(parsed.Hostname())
+		}
+	}
+	if strings.HasPrefix(domain, "self.") {
+		return ""
+	}
+	return strings.TrimPrefix(domain, "www.")
+}
+
+// domainKey returns the domain used for block_domains, with "self" standing in for self posts.
+func (p Post) domainKey() string {
+	if domain := p.linkDomain(); domain != "" {
+		return domain
+	}
+	return "self"
+}
+
+// matchDomainList returns the listed domain that domain equals or is a subdomain of, or "" if none.
+func matchDomainList(domain string, list []string) string {
+	if domain == "" {
+		return ""
+	}
+	for _, entry := range list {
+		entry = strings.TrimPrefix(strings.ToLower(entry), "www.")
+		if domain == entry || strings.HasSuffix(domain, "."+entry) {
+			return entry
+		}
+	}
+	return ""
+}
+
+// matchPost returns the keywords found in a post, plus a "domain:" entry if it links to a watched domain,
+// the keyword groups that matched and whether the match should alert (see matchText). Only the fields
+// in the subreddit's match_scope are matched, so matched text never comes from the other field.
+func (r matchRules) matchPost(post Post) (found []string, groups []string, alert bool) {
+	title, body := post.Title, post.Selftext
+	if matchLinkURLs {
+		body += " " + post.URL + " " + post.Domain
+	}
+	switch r.subredditConfig(post.Subreddit).MatchScope {
+	case "title":
+		body = ""
+	case "body":
+		title = ""
+	}
+	found, groups, alert = r.matchText(title, body)
+	if domain := matchDomainList(post.linkDomain(), watchedDomains); domain != "" {
+		found = append(found, domainMatchPrefix+domain)
+		alert = true // Watched domains alert regardless of keywords
+	}
+	return found, groups, alert
+}
+
+// describeMatches formats matches for notifications, separating keyword hits from watched domain hits.
+func describeMatches(found []string) string {
+	keywordHits := []string{}
+	domainHits := []string{}
+	for _, match := range found {
+		if domain, ok := strings.CutPrefix(match, domainMatchPrefix); ok {
+			domainHits = append(domainHits, domain)
+		} else {
+			keywordHits = append(keywordHits, match)
+		}
+	}
+	parts := []string{}
+	if len(keywordHits) > 0 {
+		parts = append(parts, fmt.Sprintf("Keywords %v", keywordHits))
+	}
+	if len(domainHits) > 0 {
+		parts = append(parts, fmt.Sprintf("Watched domain %s", strings.Join(domainHits, ", ")))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// processPosts checks posts for keywords, sends email for new matches, and tracks processed IDs.
+func processPosts(store Store, notifier Notifier, rules matchRules, posts []Post) {
+	processPostsWith(store, notifier, rules, posts, nil)
+}
+
+// processSearchResults runs a search monitor's results through the post pipeline with rules.
+// Reddit already matched the query, so results with no keyword hit are reported under the query itself.
+func processSearchResults(store Store, notifier Notifier, rules matchRules, monitor SearchMonitor, posts []Post) {
+	processPostsWith(store, notifier, rules, posts, &monitor)
+}
+
+// processPostsWith checks posts, the results of monitor if not nil, sends email for new matches,
+// and tracks processed IDs.
+func processPostsWith(store Store, notifier Notifier, rules matchRules, posts []Post, monitor *SearchMonitor) {
+	match := rules.matchPost
+	if monitor != nil {
+		match = func(post Post) ([]string, []string, bool) {
+			found, groups, alert := rules.matchPost(post)
+			if len(found) == 0 {
+				found, alert = []string{searchMatchPrefix + monitor.Query}, true
+			}
+			return found, groups, alert
+		}
+	}
+	// newMatchesFound variable is less relevant now, DB handles state.
+	for _, post := range posts {
+		if reason := rules.subredditConfig(post.Subreddit).skipPostReason(post); reason != "" {
+			continue // Filtered by settings (author lists, upvote ratio, self_only, blocked domains, crossposts, comment counts)
+		}
+		if reason := tooOldReason(post.CreatedUtc); post.ageChecked() && reason != "" {
+			// Recorded without alerting, so it never alerts later
+			debugf("Skipping post %s from r/%s: created %s ago, %s", post.Permalink, post.Subreddit, itemAge(post.CreatedUtc).Round(time.Minute), reason)
+			markSkippedItem(store, ProcessedItem{Permalink: post.Permalink, Subreddit: post.Subreddit, Kind: "post", Listing: post.Listing, Skipped: "too old", ProcessedAt: time.Now()})
+			continue
+		}
+
+		// --- Check if already processed (Store Lookup) ---
+		processed, err := checkedStore(store, post.Permalink, func() {
+			if found, _, _ := match(post); len(found) > 0 {
+				storeOutage.holdPost(rules.profile, post, monitor) // Posts without keywords aren't recorded anyway
+			}
+		})
+
+		if err != nil {
+			// An actual error occurred during the query
+			fmt.Printf("Error checking store for post permalink %s: %v%s\n", post.Permalink, err, rules.logTag())
+			continue // Held until the store recovers
+		} else if processed {
+			// Found the document, already processed
+			continue
+		}
+		// Not processed, so proceed.
+		// --- End Check ---
+
+		// Check for keywords (same as before)
+		found, groups, alert := match(post)
+		alert = rules.applyKeywordLogic(post.Subreddit, found, groups, alert)
+
+		if len(found) == 0 {
+			rememberForRecheck(rules.profile, post) // An edit may still add the keywords
+			continue
+		}
+
+		item := rules.postItem(post, found, groups)
+		if !alert {
+			// Too few distinct keywords: record the partial match as a near miss, without alerting
+			fmt.Printf("Near miss: %s in post from r/%s: https://www.reddit.com%s%s\n",
+				describeMatches(found), post.Subreddit, post.Permalink, rules.logTag())
+			item.NearMiss = true
+			markItem(store, item)
+			continue
+		}
+		if skipNewAuthor(store, rules, item) {
+			continue
+		}
+		recordKeywordStats(rules.profile, post.Subreddit, found)
+
+		// Same content already matched in another subreddit (or the original of a crosspost): don't alert again
+		if original := findDuplicatePost(store, post); original != nil {
+			fmt.Printf("Suppressed duplicate post in r/%s: https://www.reddit.com%s duplicates https://www.reddit.com%s (r/%s)%s\n",
+				post.Subreddit, post.Permalink, original.Permalink, original.Subreddit, rules.logTag())
+			ctxUpdate, cancelUpdate := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := store.AddAlsoPostedIn(ctxUpdate, original.Permalink, "r/"+post.Subreddit); err != nil {
+				fmt.Printf("Error noting duplicate on %s: %v%s\n", original.Permalink, err, rules.logTag())
+			}
+			cancelUpdate()
+			item.DuplicateOf = original.Permalink
+			markItem(store, item)
+			continue
+		}
+		if suppressInThread(store, rules, item) {
+			continue
+		}
+
+		// New match found!
+		fmt.Printf("Found %s in NEW post from r/%s (%s): https://www.reddit.com%s%s\n",
+			describeMatches(found), post.Subreddit, post.Listing, post.Permalink, rules.logTag())
+
+		// Format email content (link, then a copy of the item)
+		keywordPriority := rules.keywordPriority(found)
+		subject := rules.alertSubject("Post", post.Subreddit, groups, keywordPriority)
+		body := fmt.Sprintf("%s found in post (%s listing):\nhttps://www.reddit.com%s", describeMatches(found), post.Listing, post.Permalink)
+		if post.Listing == "edited" {
+			subject += " (edited post)"
+			body = fmt.Sprintf("%s found in post after it was edited:\nhttps://www.reddit.com%s", describeMatches(found), post.Permalink)
+		}
+		if post.Selftext == "" && post.URL != "" && !strings.Contains(post.URL, post.Permalink) {
+			body += "\nLinks to: " + post.URL // Link posts have no selftext, show where they point
+		}
+		body += newSnapshot(post.Title, post.Selftext, post.Author, post.Score).plainText() // Readable after the post is deleted
+
+		// Send notification
+		followThread(rules.profile, post)
+		deliverAlert(store, notifier, rules, markSheetPending(item), Alert{
+			Subject: subject, Body: body, Kind: "post", Subreddit: post.Subreddit, Permalink: post.Permalink,
+			Title: post.Title, Snippet: item.Snippet, Keywords: found, Groups: groups, Priority: rules.alertPriority(groups),
+			SMS: rules.smsAlert(groups), KeywordPriority: keywordPriority,
+		})
+		// No need to add to a map or save a file here
+	}
+	// No need for the final saveProcessedIDs call here
+}
+
+// commentSkips counts comments skipped by the length bounds, the bot blocklist and the duplicate heuristic
+type commentSkips struct {
+	length     int
+	bots       int
+	duplicates int
+}
+
+// processComments checks comments for keywords, sends email for new matches, and tracks processed IDs.
+func processComments(store Store, notifier Notifier, rules matchRules, comments []Comment) commentSkips {
+	skips := commentSkips{}
+	// newMatchesFound variable is less relevant now, DB handles state.
+	for _, comment := range comments {
+		if !commentLengthAllowed(comment.Body) {
+			skips.length++
+			continue // "Lol", "This." or walls of bot text
+		}
+		if isBotAccount(comment.Author) {
+			skips.bots++
+			continue
+		}
+		if !rules.subredditConfig(comment.Subreddit).allowsAuthor(comment.Author) {
+			continue // Author filtered by whitelist/blacklist
+		}
+		if reason := tooOldReason(comment.CreatedUtc); !comment.Held && reason != "" {
+			debugf("Skipping comment %s from r/%s: created %s ago, %s", comment.Permalink, comment.Subreddit, itemAge(comment.CreatedUtc).Round(time.Minute), reason)
+			markSkippedItem(store, ProcessedItem{Permalink: comment.Permalink, Subreddit: comment.Subreddit, Kind: "comment", Skipped: "too old", ProcessedAt: time.Now()})
+			continue
+		}
+
+		// --- Check if already processed (Store Lookup) ---
+		processed, err := checkedStore(store, comment.Permalink, func() {
+			if found, _, _ := rules.matchText("", comment.Body); len(found) > 0 {
+				storeOutage.holdComment(rules.profile, comment)
+			}
+		})
+
+		if err != nil {
+			fmt.Printf("Error checking store for comment permalink %s: %v%s\n", comment.Permalink, err, rules.logTag())
+			continue // Held until the store recovers
+		} else if processed {
+			continue // Already processed
+		}
+		// Not processed, continue
+		// --- End Check ---
+
+		// Skip boilerplate repeated across threads (bot templates, copy-pasted spam)
+		if duplicateComments != nil && duplicateComments.seen(comment) {
+			skips.duplicates++
+			continue
+		}
+
+		// Check for keywords (same as before)
+		found, groups, alert := rules.matchText("", comment.Body)
+		alert = rules.applyKeywordLogic(comment.Subreddit, found, groups, alert)
+
+		if len(found) == 0 {
+			continue
+		}
+
+		item := rules.commentItem(comment, found, groups)
+		if !alert {
+			// Too few distinct keywords: record the partial match as a near miss, without alerting
+			fmt.Printf("Near miss: keywords %v in comment from r/%s: https://www.reddit.com%s%s\n",
+				found, comment.Subreddit, comment.Permalink, rules.logTag())
+			item.NearMiss = true
+			markItem(store, item)
+			continue
+		}
+		if skipNewAuthor(store, rules, item) {
+			continue
+		}
+		recordKeywordStats(rules.profile, comment.Subreddit, found)
+		if suppressInThread(store, rules, item) {
+			continue
+		}
+
+		// New match found!
+		fmt.Printf("Found keywords %v in NEW comment from r/%s: https://www.reddit.com%s%s\n",
+			found, comment.Subreddit, comment.Permalink, rules.logTag())
+
+		// Format email content (link, then a copy of the item)
+		body := fmt.Sprintf("Keywords %v found in comment:\nhttps://www.reddit.com%s", found, comment.Permalink)
+
+		// Add parent post context from the listing, or look it up; a failed lookup only omits it
+		postTitle, postPermalink := comment.LinkTitle, comment.postPermalink()
+		if postTitle == "" || postPermalink == "" {
+			if parent, err := redditClient.fetchParentPost(context.Background(), comment.Permalink); err != nil {
+				fmt.Printf("WARN: Could not fetch parent post for comment %s: %v%s\n", comment.Permalink, err, rules.logTag())
+			} else {
+				postTitle, postPermalink = parent.Title, parent.Permalink
+			}
+		}
+		keywordPriority := rules.keywordPriority(found)
+		subject := rules.alertSubject("Comment", comment.Subreddit, groups, keywordPriority)
+		if postTitle != "" {
+			subject += fmt.Sprintf(" on %q", truncate(postTitle, 80))
+			body += fmt.Sprintf("\n\nIn post: %s\nhttps://www.reddit.com%s", postTitle, postPermalink)
+		}
+		body += newSnapshot(postTitle, comment.Body, comment.Author, comment.Score).plainText()
+
+		// Send notification
+		deliverAlert(store, notifier, rules, markSheetPending(item), Alert{
+			Subject: subject, Body: body, Kind: "comment", Subreddit: comment.Subreddit, Permalink: comment.Permalink,
+			Title: postTitle, Snippet: item.Snippet, Keywords: found, Groups: groups, Priority: rules.alertPriority(groups),
+			SMS: rules.smsAlert(groups), KeywordPriority: keywordPriority,
+		})
+	}
+	// No need for the final saveProcessedIDs call here
+	return skips
+}
+
+// skipNewAuthor records a match by an account younger than min_author_age_days without alerting,
+// and reports whether it did.
+func skipNewAuthor(store Store, rules matchRules, item ProcessedItem) bool {
+	if !authorTooNew(item.Author) {
+		return false
+	}
+	fmt.Printf("Skipping %s by u/%s in r/%s, account younger than min_author_age_days: https://www.reddit.com%s%s\n",
+		item.Kind, item.Author, item.Subreddit, item.Permalink, rules.logTag())
+	markSkippedItem(store, ProcessedItem{Permalink: item.Permalink, Subreddit: item.Subreddit, Kind: item.Kind, Listing: item.Listing, Keywords: item.Keywords, Skipped: "new account", ProcessedAt: time.Now()})
+	return true
+}
+
+// suppressInThread records a match whose keywords were already notified in its thread within
+// dedup_window_minutes without alerting, and reports whether it did.
+func suppressInThread(store Store, rules matchRules, item ProcessedItem) bool {
+	if !threadAlerts.suppress(rules.profile, item.Subreddit, item.Permalink, item.Keywords) {
+		return false
+	}
+	fmt.Printf("Suppressed notification for %s in r/%s, %v already notified in this thread: https://www.reddit.com%s%s\n",
+		item.Kind, item.Subreddit, item.Keywords, item.Permalink, rules.logTag())
+	markItem(store, item)
+	return true
+}
+
+// deliverAlert publishes the alert of a new match to stream clients and sends it, unless its
+// keyword priority leaves it to the daily digest, then records item. A failed notification is
+// handed to notificationFailed instead.
+func deliverAlert(store Store, notifier Notifier, rules matchRules, item ProcessedItem, alert Alert) {
+	matchStream.publish(alert) // Stream clients see the match without waiting for the email
+	if alert.KeywordPriority == "low" {
+		fmt.Printf("Low priority match, leaving it to the daily digest%s\n", rules.logTag())
+		markItem(store, item)
+		return
+	}
+	if batch, ok := notifier.(*batchNotifier); ok && alert.KeywordPriority != "high" {
+		batch.queueMatch(store, rules, item, alert) // Recorded once the batch is sent
+		return
+	}
+	if err := notifyAlert(notifier, alert); err != nil {
+		fmt.Printf("Error sending %s notification: %v%s\n", alert.Kind, err, rules.logTag())
+		notificationFailed(store, rules, item, alert, err)
+		return
+	}
+	threadAlerts.record(rules.profile, item.Subreddit, item.Permalink, item.Keywords)
+	markItem(store, item)
+}
+
+// commentLengthAllowed reports whether a comment body is within min_comment_length and max_comment_length.
+func commentLengthAllowed(body string) bool {
+	length := utf8.RuneCountInString(strings.TrimSpace(body))
+	return length >= minCommentLength && (maxCommentLength <= 0 || length <= maxCommentLength)
+}
+
+// postItem builds the processed item stored for a matched post.
+func (r matchRules) postItem(post Post, found, groups []string) ProcessedItem {
+	item := ProcessedItem{
+		Permalink:   post.Permalink,
+		Subreddit:   post.Subreddit,
+		Kind:        "post",
+		Listing:     post.Listing,
+		Keywords:    found,
+		Groups:      groups,
+		ProcessedAt: time.Now(), // Store processing time
+		PostName:    post.Name,
+		ContentHash: post.contentHash(),
+		Title:       post.Title,
+		Author:      post.Author,
+		CreatedUTC:  createdTime(post.CreatedUtc),
+	}
+	item.KeywordPriority = r.keywordPriority(found)
+	item.Snapshot = newSnapshot(post.Title, post.Selftext, post.Author, post.Score)
+	if post.Selftext != "" {
+		item.Snippet = alertSnippet(post.Selftext)
+	} else {
+		item.Snippet = alertSnippet(post.Title)
+	}
+	if r.storeFullText {
+		item.FullText = post.Title + "\n\n" + post.Selftext
+	}
+	if r.storeFullContent {
+		item.Content = itemContent(post)
+	}
+	return item
+}
+
+// commentItem builds the processed item stored for a matched comment.
+func (r matchRules) commentItem(comment Comment, found, groups []string) ProcessedItem {
+	item := ProcessedItem{
+		Permalink:   comment.Permalink,
+		Subreddit:   comment.Subreddit,
+		Kind:        "comment",
+		Keywords:    found,
+		Groups:      groups,
+		ProcessedAt: time.Now(),
+		Title:       comment.LinkTitle,
+		Author:      comment.Author,
+		CreatedUTC:  createdTime(comment.CreatedUtc),
+	}
+	item.KeywordPriority = r.keywordPriority(found)
+	item.Snapshot = newSnapshot(comment.LinkTitle, comment.Body, comment.Author, comment.Score)
+	item.Snippet = alertSnippet(comment.Body)
+	if r.storeFullText {
+		item.FullText = comment.Body
+	}
+	if r.storeFullContent {
+		item.Content = itemContent(comment)
+	}
+	return item
+}
+
+// itemContent converts a Post or Comment into the content sub-document, keyed by its JSON
+// (Reddit) field names so it can be queried like the API responses.
+func itemContent(v interface{}) map[string]interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Printf("WARN: Could not encode item content: %v\n", err)
+		return nil
+	}
+	content := map[string]interface{}{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		fmt.Printf("WARN: Could not encode item content: %v\n", err)
+		return nil
+	}
+	return content
+}
+
+// createdTime converts a created_utc timestamp, epoch seconds as a float (Reddit sends e.g.
+// 1700000000.0, sometimes with a fraction), to UTC. 0, negative and non-finite values (unknown)
+// give the zero time.
+func createdTime(createdUtc float64) time.Time {
+	if !(createdUtc > 0) || createdUtc > math.MaxInt64/1e9 { // NaN fails the first test
+		return time.Time{}
+	}
+	seconds, fraction := math.Modf(createdUtc)
+	return time.Unix(int64(seconds), int64(math.Round(fraction*1e9))).UTC()
+}
+
+// itemAge returns how long ago an item with the given created_utc was created, 0 if unknown.
+func itemAge(createdUtc float64) time.Duration {
+	created := createdTime(createdUtc)
+	if created.IsZero() {
+		return 0
+	}
+	return time.Since(created)
+}
+
+// ageChecked reports whether tooOldReason applies to the post: only to posts of new listings,
+// a subreddit's or a multireddit's, where an old post is a sticky or a slow subreddit's backlog.
+// The other listings, search results, backfills and rechecks of edited posts surface older posts
+// on purpose, and held posts were already new enough when they were fetched.
+func (p Post) ageChecked() bool {
+	return (p.Listing == "new" || p.Listing == "multireddit/new") && !p.Held
+}
+
+// tooOldReason returns why an item is too old to alert on, "" if it isn't: older than maxItemAge,
+// or created startupLookback before startup. Items without a timestamp are never too old, and
+// nothing is with --backfill.
+func tooOldReason(createdUtc float64) string {
+	created := createdTime(createdUtc)
+	if backfillMode || created.IsZero() {
+		return ""
+	}
+	if maxItemAge > 0 && time.Since(created) > maxItemAge {
+		return "older than max_post_age_minutes"
+	}
+	if startupLookback > 0 && created.Before(startedAt.Add(-startupLookback)) {
+		return "created more than startup_lookback_minutes before startup"
+	}
+	return ""
+}
+
+// markSkippedItem records an item skipped for its age or its author's so it isn't checked again. It
+// stays in the listing for a while, so finding it already recorded is expected and not logged.
+func markSkippedItem(store Store, item ProcessedItem) {
+	if storeOutage.isDown() {
+		return // Still in the listing next time, recorded then
+	}
+	ctxInsert, cancelInsert := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelInsert()
+	if err := store.Mark(ctxInsert, item); err != nil && !errors.Is(err, ErrAlreadyProcessed) {
+		fmt.Printf("Error inserting skipped %s permalink %s into store: %v\n", item.Kind, item.Permalink, err)
+	}
+}
+
+// markItem records a processed item in the store, logging failures. An item that is
+// already stored (the Has check raced another insert) is only reported as info. Items that
+// can't be recorded are held and recorded once the store recovers, see storeOutage.
+func markItem(store Store, item ProcessedItem) {
+	if storeOutage.isDown() {
+		storeOutage.holdMark(store, item)
+		return
+	}
+	ctxInsert, cancelInsert := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelInsert()
+	if err := store.Mark(ctxInsert, item); err != nil {
+		if errors.Is(err, ErrAlreadyProcessed) {
+			fmt.Printf("Info: Attempted to insert duplicate permalink %s, already processed.\n", item.Permalink)
+		} else {
+			fmt.Printf("Error inserting processed %s permalink %s into store, holding it: %v\n", item.Kind, item.Permalink, err)
+			markFailed(store, item, err)
+		}
+	}
+}
+
+// checkEnv verifies that all required environment variables are set.
+func checkEnv() error {
+	if err := checkNotifierEnv(); err != nil {
+		return err
+	}
+	return checkMongoEnv()
+}
+
+// checkNotifierEnv checks the settings needed to send notifications.
+func checkNotifierEnv() error {
+	if recipientEmail == "" {
+		return fmt.Errorf("RECIPIENT_EMAIL environment variable must be set")
+	}
+	return checkEmailProvider()
+}
+
+// checkMongoEnv checks the settings needed to connect to MongoDB, when it's required.
+func checkMongoEnv() error {
+	if mongoURI == "" && mongoRequired() {
+		return fmt.Errorf("MONGODB_URI environment variable must be set")
+	}
+	return nil
+}
+
+// mongoRequired reports whether the monitor needs MongoDB: for the processed items, unless
+// --memory-store or another store keeps them, and for CONFIG_SOURCE=mongo.
+func mongoRequired() bool {
+	if useMemoryStore {
+		return false
+	}
+	return mongoConfigEnabled || !processedItemsElsewhere()
+}
+
+// MongoDB TLS settings, for deployments that require verified or mutual TLS
+var mongoTLSEnabled = os.Getenv("MONGODB_TLS_ENABLED") == "true"
+var mongoTLSCAFile = os.Getenv("MONGODB_TLS_CA_FILE")     // PEM CA bundle, the system roots when empty
+var mongoTLSCertFile = os.Getenv("MONGODB_TLS_CERT_FILE") // PEM client certificate for mTLS
+var mongoTLSKeyFile = os.Getenv("MONGODB_TLS_KEY_FILE")   // PEM client key for mTLS
+var mongoTLSInsecureSkipVerify = os.Getenv("MONGODB_TLS_INSECURE_SKIP_VERIFY") == "true"
+
+// mongoTLSConfig builds the TLS config from the MONGODB_TLS_* variables, reading the CA, certificate and key files.
+func mongoTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: mongoTLSInsecureSkipVerify}
+	if mongoTLSCAFile != "" {
+		pem, err := os.ReadFile(mongoTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MONGODB_TLS_CA_FILE: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("MONGODB_TLS_CA_FILE %s contains no PEM certificates", mongoTLSCAFile)
+		}
+		config.RootCAs = pool
+	}
+	if (mongoTLSCertFile == "") != (mongoTLSKeyFile == "") {
+		return nil, fmt.Errorf("MONGODB_TLS_CERT_FILE and MONGODB_TLS_KEY_FILE must be set together")
+	}
+	if mongoTLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(mongoTLSCertFile, mongoTLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load MongoDB client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if mongoTLSInsecureSkipVerify {
+		fmt.Println("WARN: MONGODB_TLS_INSECURE_SKIP_VERIFY is set, the MongoDB server certificate is not verified")
+	}
+	return config, nil
+}
+
+// connectMongo connects to MongoDB and pings the primary node to verify the connection.
+func connectMongo() (*mongo.Client, error) {
+	clientOptions := options.Client().ApplyURI(mongoURI)
+	if mongoTLSEnabled {
+		tlsConfig, err := mongoTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+	ctxConnect, cancelConnect := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelConnect()
+	client, err := mongo.Connect(ctxConnect, clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to MongoDB: %w", err)
+	}
+
+	// Ping the primary node to verify connection
+	ctxPing, cancelPing := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelPing()
+	err = client.Ping(ctxPing, readpref.Primary())
+	if err != nil {
+		// Attempt to disconnect before returning
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("MongoDB ping failed: %w", err)
+	}
+	return client, nil
+}
+
+// mongoCollection returns the named collection of the monitor's database, nil when MongoDB isn't
+// used (--memory-store, or another store without MONGODB_URI).
+func mongoCollection(name string) *mongo.Collection {
+	if mongoClient == nil {
+		return nil
+	}
+	return mongoClient.Database(mongoDatabaseName).Collection(name)
+}
+
+func main() {
+	// Subcommands
+	runArgs := os.Args[1:]
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		switch os.Args[1] {
+		case "run":
+			// Same as no command, the monitor itself
+			runArgs = os.Args[2:]
+		case "validate":
+			os.Exit(runValidate())
+		case "list-processed":
+			os.Exit(runListProcessed(os.Args[2:]))
+		case "replay":
+			os.Exit(runReplay(os.Args[2:]))
+		case "matches":
+			os.Exit(runMatches(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "config":
+			os.Exit(runConfigCommand(os.Args[2:]))
+		case "drain-dlq":
+			os.Exit(runDrainDLQ(os.Args[2:]))
+		case "stats":
+			os.Exit(runStats(os.Args[2:]))
+		default:
+			fmt.Printf("Unknown command %q. Available commands: run, validate, list-processed, matches, export, replay, config, drain-dlq, stats\n", os.Args[1])
+			os.Exit(2)
+		}
+	}
+
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.StringVar(&onlyProfile, "profile", "", "only run this profile, for debugging")
+	fs.StringVar(&recordDir, "record", "", "save every Reddit response to a file in this directory")
+	fs.StringVar(&replayDir, "replay", "", "answer Reddit requests from the responses recorded in this directory")
+	fs.BoolVar(&useMemoryStore, "memory-store", false, "keep processed items only until exit and don't connect to MongoDB")
+	fs.BoolVar(&logNotifications, "log-notifications", false, "print notifications instead of sending them")
+	fs.BoolVar(&backfillMode, "backfill", false, "alert on items of any age, ignoring max_post_age_minutes and startup_lookback_minutes")
+	fs.StringVar(&backfillSubredditsFlag, "backfill-subreddits", "", "comma-separated subreddits whose recent posts are matched once, summed up in one digest instead of alerted")
+	fs.IntVar(&backfillDaysFlag, "backfill-days", defaultQuietBackfillDays, "how many days back --backfill-subreddits goes")
+	_ = fs.Parse(runArgs) // Exits on error
+
+	fmt.Println("Starting Reddit keyword monitor...")
+
+	if err := loadConfig(); err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		os.Exit(1)
+	}
+	if onlyProfile != "" && findProfile(onlyProfile) == nil {
+		fmt.Printf("FATAL: --profile %q is not a configured profile\n", onlyProfile)
+		os.Exit(2)
+	}
+
+	// --- Configuration Validation ---
+	if !logNotifications {
+		if err := checkNotifierEnv(); err != nil {
+			fmt.Printf("FATAL: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := checkMongoEnv(); err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		os.Exit(1)
+	}
+	if err := configureProxy(); err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		os.Exit(1)
+	}
+	if err := setupRedditFixtures(redditClient); err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		os.Exit(2)
+	}
+	if err := checkRedditConnectivity(); err != nil {
+		fmt.Printf("WARN: Reddit connectivity check failed: %v\n", err)
+	} else {
+		fmt.Println("Reddit connectivity check passed.")
+	}
+
+	// --- Processed Items Store ---
+	var store Store                // Nil until MongoDB is connected when it keeps the processed items
+	var localStore maintainedStore // File or bolt store, processed items kept outside MongoDB
+	storeLocation := ""            // Where processed items are kept, when not in MongoDB
+	stores := 0
+	for _, setting := range []string{storeFilePath, storeBoltPath, dynamoDBTableName} {
+		if setting != "" {
+			stores++
+		}
+	}
+	var err error
+	switch {
+	case useMemoryStore:
+		localStore, err = newScratchStore()
+		fmt.Println("Keeping processed items until exit (--memory-store), MongoDB isn't used.")
+	case stores > 1:
+		err = fmt.Errorf("only one of STORE_FILE_PATH, STORE_BOLT_PATH and DYNAMODB_TABLE_NAME can be set")
+	case storeFilePath != "":
+		localStore, err = NewFileStore(storeFilePath, storeFileTTL)
+		storeLocation = storeFilePath
+	case storeBoltPath != "":
+		localStore, err = NewBoltStore(storeBoltPath, storeBoltTTL)
+		storeLocation = storeBoltPath
+	case dynamoDBTableName != "":
+		var dynamo *DynamoStore
+		if dynamo, err = NewDynamoStore(dynamoDBTableName, dynamoDBTTL); err == nil {
+			store = dynamo
+		}
+		storeLocation = "DynamoDB table " + dynamoDBTableName
+	}
+	if err != nil {
+		fmt.Printf("FATAL: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("Successfully connected to MongoDB.")
+	if localStore != nil {
+		store = localStore
+	}
 
-	// Get collection handle
-	// TODO: Consider making DB name and Collection name configurable via Env Vars too
-	processedItemsCollection = mongoClient.Database("reddit_monitor").Collection("processed_items")
+	// --- Connect to MongoDB ---
+	// Only optional when another store keeps the processed items, then it's used if MONGODB_URI is
+	// set, for what only MongoDB keeps (digests, thread following, the DLQ...)
+	if !useMemoryStore && (mongoRequired() || mongoURI != "") {
+		mongoClient, err = connectMongo()
+		if err != nil {
+			fmt.Printf("FATAL: %v\n", err)
+			if localStore != nil {
+				localStore.close()
+			}
+			os.Exit(1)
+		}
+		fmt.Println("Successfully connected to MongoDB.")
+
+		// Get collection handle
+		// TODO: Consider making DB name and Collection name configurable via Env Vars too
+		processedItemsCollection = mongoCollection(processedItemsCollectionName)
+		if store == nil {
+			store = NewMongoStore(processedItemsCollection)
+		}
+	}
+
+	// Shared config from the config collection, overriding the config file
+	if mongoConfigEnabled && mongoClient == nil {
+		fmt.Println("WARN: CONFIG_SOURCE=mongo is ignored with --memory-store")
+	} else if mongoConfigEnabled {
+		configCollection = mongoCollection(configCollectionName)
+		if err := loadMongoConfig(); err != nil {
+			fmt.Printf("FATAL: %v\n", err)
+			if mongoClient != nil {
+				_ = mongoClient.Disconnect(context.Background())
+			}
+			os.Exit(1)
+		}
+	}
 
 	// Ensure index exists (run in background)
-	go setupMongoIndex()
-
-	// Optional: Graceful shutdown handling
-	// Setup signal catching for SIGINT and SIGTERM
-	// sigs := make(chan os.Signal, 1)
-	// signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	// go func() {
-	// 	sig := <-sigs
-	// 	fmt.Println()
-	// 	fmt.Println("Received signal:", sig)
-	// 	fmt.Println("Disconnecting from MongoDB...")
-	// 	ctxDisconnect, cancelDisconnect := context.WithTimeout(context.Background(), 10*time.Second)
-	// 	defer cancelDisconnect()
-	// 	if err := mongoClient.Disconnect(ctxDisconnect); err != nil {
-	// 		fmt.Printf("Error during MongoDB disconnect: %v\n", err)
-	// 	}
-	// 	fmt.Println("MongoDB disconnected. Exiting.")
-	// 	os.Exit(0)
-	// }()
+	if mongoClient != nil {
+		go setupMongoIndex()
+	}
+
+	// Check subreddits exist and are public. Fail-fast mode blocks startup, otherwise run in background.
+	if failOnInvalidSubreddits {
+		statuses := checkAllSubreddits(30 * time.Second)
+		reportSubredditStatuses(statuses)
+		if invalid := invalidSubreddits(statuses); len(invalid) > 0 {
+			fmt.Printf("FATAL: %d invalid subreddit(s) configured and FAIL_ON_INVALID_SUBREDDITS is set.\n", len(invalid))
+			if mongoClient != nil {
+				_ = mongoClient.Disconnect(context.Background())
+			}
+			os.Exit(1)
+		}
+		if subredditRecheck {
+			go func() {
+				time.Sleep(subredditCheckInterval)
+				monitorSubreddits()
+			}()
+		}
+	} else {
+		go monitorSubreddits()
+	}
+
+	// Graceful shutdown: SIGINT/SIGTERM cancel ctx, which stops every poll goroutine
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	jobs := allPollJobs()
+	if amqpURL != "" {
+		amqpConn.start() // Declares the exchange in the background
+	}
+	if natsURL != "" {
+		natsConn.start() // Creates the stream in the background
+	}
+	notifier := healthNotifier{withAlertChannels(newNotifier(recipientEmail), topLevelAlertChannels())}
+	health.events = mongoCollection(healthEventsCollectionName)
+	notificationDLQ = mongoCollection(notificationDLQCollectionName)
+	notificationRetryQueue = mongoCollection(notificationRetryQueueCollectionName)
+
+	keywordStatsCollection = mongoCollection(keywordStatsCollectionName)
+	if mongoClient == nil {
+		if followThreads || recheckWindow > 0 || dailyDigestEnabled || weeklyReportEnabled || statusCheckWindow > 0 {
+			fmt.Println("WARN: Thread following, rechecks, digests, weekly reports and status checks need MongoDB, they are off without it")
+		}
+	} else {
+		if followThreads {
+			setupFollowedThreads(mongoCollection(followedThreadsCollectionName))
+		}
+		if recheckWindow > 0 {
+			setupPendingRecheck(mongoCollection(pendingRecheckCollectionName))
+		}
+	}
+
+	if duplicateCommentCheck {
+		duplicateComments = newCommentDeduper(mongoCollection(commentHashesCollectionName))
+	}
 
+	// One-time backfill of older posts for subreddits with backfill_pages, before regular polling
+	if onlyProfile == "" && mongoClient != nil {
+		backfillSubreddits(ctx, mongoCollection(backfillsCollectionName), store, notifier)
+	}
+
+	// Quiet backfills run next to polling, resumed from where the last run stopped
+	if mongoClient != nil {
+		quietBackfills = newQuietBackfiller(ctx, mongoCollection(quietBackfillsCollectionName), store, notifier)
+		quietBackfills.resume()
+		for _, name := range strings.Split(backfillSubredditsFlag, ",") {
+			if strings.TrimSpace(name) == "" {
+				continue
+			}
+			if _, err := quietBackfills.start(name, backfillDaysFlag); err != nil {
+				fmt.Printf("WARN: Not backfilling %s: %v\n", name, err)
+			}
+		}
+	} else if backfillSubredditsFlag != "" {
+		fmt.Println("WARN: --backfill-subreddits needs MongoDB to keep its progress, ignored without it")
+	}
 
 	fmt.Println("--- Configuration ---")
 	fmt.Println("Monitoring subreddits:", subreddits)
-	fmt.Println("Looking for keywords:", keywords)
-	fmt.Println("Sending notifications to:", recipientEmail)
-	fmt.Println("Persistence: MongoDB")
+	for _, cfg := range subredditConfigs {
+		fmt.Printf("  r/%s listings: %v\n", cfg.Name, cfg.listings())
+	}
+	for _, job := range jobs {
+		fmt.Printf("Polling %s every %s: %d listing chunk(s) and %d comment chunk(s) of up to %d subreddits\n",
+			job.name, job.interval, len(job.listingChunks), len(job.commentChunks), subredditChunkSize)
+	}
+	fmt.Printf("Fetching up to %d chunks at a time\n", fetchConcurrency)
+	fmt.Println("Looking for keywords:", keywordLabels(keywords))
+	for _, group := range keywordGroups {
+		fmt.Printf("Keyword group %q: %v\n", group.Name, keywordLabels(group.Keywords))
+	}
+	if len(watchedDomains) > 0 {
+		fmt.Println("Watching domains:", watchedDomains)
+	}
+	if searchMode {
+		queries, complete := currentMatchRules().searchQueries()
+		fmt.Println("Search mode, searching subreddits for:", queries)
+		if !complete {
+			fmt.Println("  Some keywords can't be searched for (patterns, substring matches), listings are fetched too")
+		}
+	}
+	for _, monitor := range searchMonitors {
+		fmt.Printf("Search monitor: %q (subreddit: %q, sort: %q)\n", monitor.Query, monitor.Subreddit, monitor.Sort)
+	}
+	if logNotifications {
+		fmt.Println("Printing notifications instead of sending them (--log-notifications)")
+	} else {
+		fmt.Println("Sending notifications to:", recipientEmail)
+	}
+	for _, profile := range profiles {
+		fmt.Printf("Profile %s: %d subreddit(s), keywords %v, notifying %v\n",
+			profile.Name, len(profile.Subreddits), keywordLabels(profile.Keywords), profile.Recipients)
+	}
+	if len(profiles) > 0 {
+		fmt.Println("Profile dedupe:", profileDedupe)
+	}
+	if onlyProfile != "" {
+		fmt.Printf("Only running profile %s (--profile), the top-level subreddits aren't polled\n", onlyProfile)
+	}
+	if backfillMode {
+		fmt.Println("Backfill mode (--backfill): items of any age alert, max_post_age_minutes and startup_lookback_minutes are ignored")
+	}
+	if localStore != nil {
+		go localStore.runCompaction(ctx)
+	}
+	if storeLocation != "" && mongoClient == nil {
+		fmt.Printf("Persistence: processed items in %s, MongoDB isn't used\n", storeLocation)
+	} else if storeLocation != "" {
+		fmt.Printf("Persistence: processed items in %s, everything else in MongoDB\n", storeLocation)
+	} else if useMemoryStore {
+		fmt.Println("Persistence: none, processed items are kept until exit")
+	} else {
+		fmt.Println("Persistence: MongoDB")
+	}
 	fmt.Println("---------------------")
 
-	// Remove old file loading/saving logic
+	if heartbeatEmailInterval > 0 {
+		fmt.Println("Sending heartbeat emails every", heartbeatEmailInterval)
+		go runHeartbeatEmails(ctx, notifier)
+	}
 
-	for {
-		fmt.Println("\nFetching new data at", time.Now().Format(time.RFC1123))
-		// Fetch and process posts
-		posts, err := fetchPosts(postEndpoint)
+	// Recurring tasks all run on one cron scheduler, stopped once polling ends
+	if dailyDigestEnabled && mongoClient != nil {
+		spec, err := digestCronSpec()
+		if err == nil {
+			var digest *DigestScheduler
+			if digest, err = NewDigestScheduler(spec, processedItemsCollection, notifier); err == nil {
+				err = digest.Register(cronJobs)
+			}
+		}
 		if err != nil {
-			fmt.Println("Error fetching posts:", err)
-		} else {
-			processPosts(posts)
+			fmt.Printf("FATAL: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if weeklyReportEnabled && mongoClient != nil {
+		reports := mongoCollection(reportStateCollectionName)
+		report, err := NewWeeklyReporter(weeklyReportSchedule, processedItemsCollection, reports, notifier)
+		if err == nil {
+			err = report.Register(cronJobs)
 		}
-
-		// Fetch and process comments
-		comments, err := fetchComments(commentEndpoint)
 		if err != nil {
-			fmt.Println("Error fetching comments:", err)
-		} else {
-			processComments(comments)
+			fmt.Printf("FATAL: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if statusCheckWindow > 0 && mongoClient != nil {
+		err := cronJobs.AddFunc("match status check", statusCheckSchedule, func() {
+			checkMatchStatuses(ctx, processedItemsCollection)
+		})
+		if err != nil {
+			fmt.Printf("FATAL: %v\n", err)
+			os.Exit(1)
 		}
+	}
+	cronJobs.Start()
 
-		// Wait before the next iteration
-		time.Sleep(5 * time.Minute)
+	// Admin API for runtime keyword and subreddit changes, only with ADMIN_API_TOKEN set
+	auditLog = mongoCollection(auditLogCollectionName)
+	go runAdminAPI(ctx)
+
+	// REST API adding and removing single keywords and subreddits, only with MANAGEMENT_API_PORT set
+	go runManagementAPI(ctx)
+	go runStreamServer(ctx)
+
+	// Reload the config file on SIGHUP, changes apply from the next cycle
+	go watchConfigReloads(ctx)
+	if mongoConfigEnabled {
+		go watchMongoConfig(ctx)
+	}
+
+	// Poll until a shutdown signal, each job on its own interval
+	runPollJobs(ctx, store, notifier)
+	fmt.Println("Stopping scheduled tasks...")
+	select {
+	case <-cronJobs.Stop().Done():
+	case <-time.After(30 * time.Second):
+		fmt.Println("WARN: Scheduled tasks still running after 30s, exiting anyway")
+	}
+	if quietBackfills != nil {
+		quietBackfills.wait() // Saves its progress on the way out
+	}
+	smtpConn.close()
+	kafkaConn.close()
+	amqpConn.close()
+	natsConn.close() // Flushes first
+	if localStore != nil {
+		localStore.close()
 	}
-}
\ No newline at end of file
+
+	if mongoClient == nil {
+		fmt.Println("Exiting.")
+		return
+	}
+	fmt.Println("Disconnecting from MongoDB...")
+	ctxDisconnect, cancelDisconnect := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelDisconnect()
+	if err := mongoClient.Disconnect(ctxDisconnect); err != nil {
+		fmt.Printf("Error during MongoDB disconnect: %v\n", err)
+	}
+	fmt.Println("MongoDB disconnected. Exiting.")
+}
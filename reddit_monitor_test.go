@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeDoer is an HTTPDoer answering every request with do, keeping the last request
+type fakeDoer struct {
+	do  func(req *http.Request) (*http.Response, error)
+	req *http.Request
+}
+
+func (f *fakeDoer) Do(req *http.Request) (*http.Response, error) {
+	f.req = req
+	return f.do(req)
+}
+
+// respond returns a fakeDoer answering with status, body and headers.
+func respond(status int, body string, headers map[string]string) *fakeDoer {
+	return &fakeDoer{do: func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}
+		for name, value := range headers {
+			resp.Header.Set(name, value)
+		}
+		return resp, nil
+	}}
+}
+
+// testRedditClient returns an unpaced RedditClient sending its requests to doer.
+func testRedditClient(doer HTTPDoer) *RedditClient {
+	return &RedditClient{HTTP: doer, UserAgent: "test-agent", Unpaced: true}
+}
+
+const testListing = `{"data": {"children": [{"kind": "t3", "data": {"title": "a"}}], "after": "t3_next"}}`
+
+func TestGetRedditJSON(t *testing.T) {
+	doer := respond(http.StatusOK, testListing, map[string]string{"Content-Type": "application/json"})
+	response, err := getRedditJSON[PostResponse](context.Background(), testRedditClient(doer), "https://www.reddit.com/r/golang/new.json")
+	if err != nil {
+		t.Fatalf("getRedditJSON: %v", err)
+	}
+	if len(response.Data.Children) != 1 || response.Data.After != "t3_next" {
+		t.Errorf("response = %+v, want one child and after t3_next", response)
+	}
+	if got := doer.req.Header.Get("User-Agent"); got != "test-agent" {
+		t.Errorf("User-Agent = %q, want the client's", got)
+	}
+	if got := doer.req.Header.Get("Accept-Encoding"); got != "gzip" {
+		t.Errorf("Accept-Encoding = %q, want gzip", got)
+	}
+}
+
+func TestGetRedditJSONDecompressesGzip(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(testListing))
+	writer.Close()
+	doer := respond(http.StatusOK, compressed.String(), map[string]string{"Content-Encoding": "gzip"})
+
+	response, err := getRedditJSON[PostResponse](context.Background(), testRedditClient(doer), "https://www.reddit.com/r/golang/new.json")
+	if err != nil || response.Data.After != "t3_next" {
+		t.Errorf("getRedditJSON = %+v, %v, want the decompressed listing", response, err)
+	}
+}
+
+func TestGetRedditJSONErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		doer    *fakeDoer
+		wantErr string
+	}{
+		{"non-200 status", respond(http.StatusTooManyRequests, `{"message": "Too Many Requests"}`, nil), "unexpected status code: 429"},
+		{"server error", respond(http.StatusServiceUnavailable, "", nil), "unexpected status code: 503"},
+		{"HTML block page", respond(http.StatusOK, "<html><body>blocked</body></html>", map[string]string{"Content-Type": "text/html"}), "error decoding JSON response"},
+		{"truncated JSON", respond(http.StatusOK, `{"data": {"children": [`, nil), "error decoding JSON response"},
+		{"transport error", &fakeDoer{do: func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection reset")
+		}}, "error executing request: connection reset"},
+	}
+	for _, tt := range tests {
+		_, err := getRedditJSON[PostResponse](context.Background(), testRedditClient(tt.doer), "https://www.reddit.com/r/golang/new.json")
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: getRedditJSON error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMinKeywordMatchesCountsDistinctKeywords(t *testing.T) {
+	rules := matchRules{
+		keywords:   []KeywordSpec{{Keyword: "VA"}, {Keyword: "leads"}, {Keyword: "VA"}},
+		groups:     []KeywordGroup{{Name: "hiring", Keywords: []KeywordSpec{{Keyword: "hiring"}, {Keyword: "remote"}}, MinKeywordMatches: 2}},
+		minMatches: 2,
+	}
+	tests := []struct {
+		name       string
+		title      string
+		body       string
+		wantFound  []string
+		wantGroups []string
+		wantAlert  bool
+	}{
+		{"one keyword repeated", "VA needed", "VA, va and more VA", []string{"VA"}, nil, false},
+		{"two distinct keywords", "VA needed", "buying leads", []string{"VA", "leads"}, nil, true},
+		{"group keyword repeated", "Hiring", "hiring! HIRING!", []string{"hiring"}, nil, false},
+		{"group threshold reached", "Hiring", "remote role", []string{"hiring", "remote"}, []string{"hiring"}, true},
+		{"near miss keeps its keywords", "", "leads leads leads", []string{"leads"}, nil, false},
+	}
+	for _, tt := range tests {
+		found, groups, alert := rules.matchText(tt.title, tt.body)
+		if !slices.Equal(found, tt.wantFound) || !slices.Equal(groups, tt.wantGroups) || alert != tt.wantAlert {
+			t.Errorf("%s: matchText = %q, %q, %v, want %q, %q, %v", tt.name, found, groups, alert, tt.wantFound, tt.wantGroups, tt.wantAlert)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Reddit OAuth ---
+
+// Credentials of a Reddit app (https://www.reddit.com/prefs/apps). With both set, requests use an
+// app-only OAuth token and go to oauth.reddit.com.
+var redditClientID = os.Getenv("REDDIT_CLIENT_ID")
+var redditClientSecret = os.Getenv("REDDIT_CLIENT_SECRET")
+
+// redditTokenURL issues app-only tokens
+const redditTokenURL = "https://www.reddit.com/api/v1/access_token"
+
+// redditTokenRefreshMargin renews the token this long before it expires
+const redditTokenRefreshMargin = time.Minute
+
+// redditOAuthEnabled reports whether Reddit app credentials are configured.
+func redditOAuthEnabled() bool {
+	return redditClientID != "" && redditClientSecret != ""
+}
+
+// redditTokenSource caches the app-only token shared by every Reddit request
+type redditTokenSource struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// redditToken is the token of redditClientID
+var redditToken = &redditTokenSource{}
+
+// get returns the cached token, requesting a new one through doer when there is none or it is
+// about to expire.
+func (s *redditTokenSource) get(ctx context.Context, doer HTTPDoer, userAgent string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Until(s.expires) > redditTokenRefreshMargin {
+		return s.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, redditTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("error creating token request: %w", err)
+	}
+	req.SetBasicAuth(redditClientID, redditClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := doer.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error requesting Reddit OAuth token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error requesting Reddit OAuth token: status %s: %s", resp.Status, truncate(string(body), 200))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"` // Seconds
+		Error       string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("error decoding Reddit OAuth token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("error requesting Reddit OAuth token: %s (check REDDIT_CLIENT_ID and REDDIT_CLIENT_SECRET)", token.Error)
+	}
+	s.token, s.expires = token.AccessToken, time.Now().Add(time.Duration(token.ExpiresIn)*time.Second)
+	return s.token, nil
+}
+
+// reset drops the cached token, after Reddit rejected it.
+func (s *redditTokenSource) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+// authorize sends req to oauth.reddit.com with the app-only token, when OAuth is enabled.
+func (c *RedditClient) authorize(req *http.Request) error {
+	if !redditOAuthEnabled() || c.Unpaced {
+		return nil
+	}
+	token, err := redditToken.get(req.Context(), c.HTTP, c.UserAgent)
+	if err != nil {
+		return err
+	}
+	if host := strings.ToLower(req.URL.Hostname()); host == "reddit.com" || strings.HasSuffix(host, ".reddit.com") {
+		req.URL.Host, req.Host = "oauth.reddit.com", "oauth.reddit.com"
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// --- Config Reload ---
+
+// watchConfigReloads reloads the config file on every SIGHUP until ctx is done.
+func watchConfigReloads(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			reloadConfig()
+		}
+	}
+}
+
+// reloadConfig re-reads the config file, or the config collection in Mongo config mode, and swaps it in
+// once the running poll cycles have finished. An invalid config is logged and ignored, the current one keeps running.
+func reloadConfig() {
+	if mongoConfigEnabled {
+		reloadMongoConfig()
+		return
+	}
+	cfg, path, err := readConfig()
+	if err != nil {
+		fmt.Printf("Error reloading config, keeping the current one: %v\n", err)
+		return
+	}
+	if cfg == nil {
+		fmt.Printf("Error reloading config, keeping the current one: %s does not exist\n", path)
+		return
+	}
+	swapConfig(cfg, "config file "+path)
+}
+
+// pendingConfig is a reloaded config waiting for the running poll cycles to finish, see swapConfig
+var (
+	pendingConfigMu     sync.Mutex
+	pendingConfig       *Config
+	pendingConfigSource string
+)
+
+// swapConfig hands a validated config to the scheduler, which applies it once the running poll
+// cycles have finished and restarts the poll jobs.
+func swapConfig(cfg *Config, source string) {
+	pendingConfigMu.Lock()
+	pendingConfig, pendingConfigSource = cfg, source
+	pendingConfigMu.Unlock()
+	signalConfigReload()
+}
+
+// applyPendingConfig applies the config handed to swapConfig, if any. The scheduler calls it
+// while no poll cycle runs, so a cycle never sees half a config.
+func applyPendingConfig() {
+	pendingConfigMu.Lock()
+	cfg, source := pendingConfig, pendingConfigSource
+	pendingConfig = nil
+	pendingConfigMu.Unlock()
+	if cfg == nil {
+		return
+	}
+
+	configMu.Lock()
+	oldKeywords, oldSubreddits, oldMin := allKeywordLabels(), subredditConfigs, minKeywordMatches
+	oldNames := monitoredSubredditNames()
+	applyConfig(cfg)
+	if monitoredSubredditNames() != oldNames {
+		listingETags.clear() // Listings must be fetched in full again for the new subreddits
+	}
+	summary := configDiffSummary(oldKeywords, allKeywordLabels(), oldSubreddits, subredditConfigs, oldMin, minKeywordMatches)
+	configMu.Unlock()
+
+	fmt.Printf("Reloaded %s: %s\n", source, summary)
+}
+
+// allKeywordLabels returns the labels of the ungrouped and grouped keywords.
+func allKeywordLabels() []string {
+	labels := keywordLabels(keywords)
+	for _, group := range keywordGroups {
+		for _, label := range keywordLabels(group.Keywords) {
+			labels = append(labels, group.Name+"/"+label)
+		}
+	}
+	return labels
+}
+
+// configDiffSummary describes a reload, e.g. "+3 keywords, -1 subreddit".
+func configDiffSummary(oldKeywords, newKeywords []string, oldSubs, newSubs []SubredditConfig, oldMin, newMin int) string {
+	changes := []string{}
+	added, removed := diffLabels(oldKeywords, newKeywords)
+	changes = appendCount(changes, "+", added, "keyword")
+	changes = appendCount(changes, "-", removed, "keyword")
+
+	oldByName := map[string]SubredditConfig{}
+	for _, sub := range oldSubs {
+		oldByName[strings.ToLower(sub.Name)] = sub
+	}
+	addedSubs, changedSubs := 0, 0
+	for _, sub := range newSubs {
+		old, ok := oldByName[strings.ToLower(sub.Name)]
+		if !ok {
+			addedSubs++
+			continue
+		}
+		delete(oldByName, strings.ToLower(sub.Name))
+		if fmt.Sprint(old) != fmt.Sprint(sub) {
+			changedSubs++
+		}
+	}
+	changes = appendCount(changes, "+", addedSubs, "subreddit")
+	changes = appendCount(changes, "-", len(oldByName), "subreddit")
+	changes = appendCount(changes, "~", changedSubs, "subreddit")
+
+	if oldMin != newMin {
+		changes = append(changes, fmt.Sprintf("min_keyword_matches %d -> %d", oldMin, newMin))
+	}
+	if len(changes) == 0 {
+		return "no keyword or subreddit changes"
+	}
+	return strings.Join(changes, ", ")
+}
+
+// diffLabels counts the labels only in newLabels (added) and only in oldLabels (removed).
+func diffLabels(oldLabels, newLabels []string) (added, removed int) {
+	old := map[string]bool{}
+	for _, label := range oldLabels {
+		old[label] = true
+	}
+	current := map[string]bool{}
+	for _, label := range newLabels {
+		current[label] = true
+		if !old[label] {
+			added++
+		}
+	}
+	for label := range old {
+		if !current[label] {
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// appendCount appends e.g. "+2 keywords" to changes when count is non-zero.
+func appendCount(changes []string, sign string, count int, noun string) []string {
+	if count == 0 {
+		return changes
+	}
+	if count != 1 {
+		noun += "s"
+	}
+	return append(changes, fmt.Sprintf("%s%d %s", sign, count, noun))
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --- Replay Subcommand ---
+
+// parseSince accepts a date (2006-01-02) or a duration back from now (e.g. 72h).
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q, use a date (2006-01-02) or a duration (72h)", value)
+	}
+	return time.Now().Add(-d), nil
+}
+
+// newKeywords returns the keywords in found that aren't in previous.
+func newKeywords(found, previous []string) []string {
+	seen := map[string]bool{}
+	for _, keyword := range previous {
+		seen[keyword] = true
+	}
+	added := []string{}
+	for _, keyword := range found {
+		if !seen[keyword] {
+			added = append(added, keyword)
+		}
+	}
+	return added
+}
+
+// runReplay re-runs the current keywords against stored items that have full_text and reports
+// newly matching ones. Returns the process exit code.
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	sinceFlag := fs.String("since", "168h", "only replay items processed since this date (2006-01-02) or duration")
+	dryRun := fs.Bool("dry-run", false, "only log new matches, don't send notifications")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	since, err := parseSince(*sinceFlag)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+
+	if err := loadConfig(); err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return 1
+	}
+	if *dryRun {
+		if mongoURI == "" {
+			fmt.Println("FATAL: MONGODB_URI environment variable must be set.")
+			return 1
+		}
+	} else if err := checkEnv(); err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return 1
+	}
+
+	client, err := connectMongo()
+	if err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return 1
+	}
+	defer client.Disconnect(context.Background())
+	collection := client.Database(mongoDatabaseName).Collection(processedItemsCollectionName)
+
+	filter := map[string]interface{}{
+		"processed_at": map[string]interface{}{"$gte": since},
+		"$or": []interface{}{
+			map[string]interface{}{"full_text": map[string]interface{}{"$exists": true, "$ne": ""}},
+			map[string]interface{}{"content": map[string]interface{}{"$exists": true}},
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		fmt.Printf("Error querying processed items: %v\n", err)
+		return 1
+	}
+	defer cursor.Close(ctx)
+
+	fmt.Printf("Replaying stored items processed since %s (dry run: %v)...\n", since.Format(time.RFC1123), *dryRun)
+	scanned, matched := 0, 0
+	for cursor.Next(ctx) {
+		var item ProcessedItem
+		if err := cursor.Decode(&item); err != nil {
+			fmt.Printf("Error decoding processed item: %v\n", err)
+			continue
+		}
+		scanned++
+
+		title, text := replayText(item)
+		found, _, _ := matchText(title, text)
+		added := newKeywords(found, item.Keywords)
+		if len(added) == 0 {
+			continue
+		}
+		matched++
+		fmt.Printf("Replay: new keywords %v in %s from r/%s: https://www.reddit.com%s\n",
+			added, item.Kind, item.Subreddit, item.Permalink)
+		if *dryRun {
+			continue
+		}
+
+		subject := fmt.Sprintf("Reddit Keyword Alert (replay): %s in r/%s", item.Kind, item.Subreddit)
+		body := fmt.Sprintf("New keywords %v found in previously processed %s:\nhttps://www.reddit.com%s", added, item.Kind, item.Permalink)
+		if err := newNotifier(recipientEmail).Notify(subject, body); err != nil {
+			fmt.Println("Error sending replay notification email:", err)
+			continue
+		}
+
+		// Record the new keywords (without re-inserting) so the next replay doesn't notify again
+		ctxUpdate, cancelUpdate := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err := collection.UpdateOne(ctxUpdate,
+			map[string]interface{}{"permalink": item.Permalink},
+			map[string]interface{}{"$addToSet": map[string]interface{}{"keywords": map[string]interface{}{"$each": added}}})
+		cancelUpdate()
+		if err != nil {
+			fmt.Printf("Error updating keywords for %s: %v\n", item.Permalink, err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		fmt.Printf("Error reading processed items: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Replay finished: %d items scanned, %d with new matches.\n", scanned, matched)
+	return 0
+}
+
+// replayText returns the title and text of a stored item to match again. The full text is
+// stored as "title\n\nselftext" for posts and as the body for comments; without it they are
+// taken from the stored content.
+func replayText(item ProcessedItem) (title, text string) {
+	if item.FullText == "" {
+		if item.Kind == "post" {
+			title, _ = item.Content["title"].(string)
+			text, _ = item.Content["selftext"].(string)
+			return title, text
+		}
+		text, _ = item.Content["body"].(string)
+		return "", text
+	}
+	if item.Kind == "post" {
+		title, text, _ = strings.Cut(item.FullText, "\n\n")
+		return title, text
+	}
+	return "", item.FullText
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// --- Retries ---
+
+// retryAttempts is how often withRetry tries a call in total
+const retryAttempts = 4
+
+// retryBaseDelay is the first backoff delay, doubled after every failed attempt
+var retryBaseDelay = 1 * time.Second
+
+// retryableError marks a failure worth retrying, such as a 429 or 5xx response.
+// retryAfter is the delay the server asked for, 0 if it didn't.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// permanentError marks a failure retrying can't fix, such as an invalid phone number. Notifications
+// failing with one skip the retry queue and are dead-lettered right away.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// withRetry calls fn until it succeeds, returns an error that isn't a retryableError, or
+// retryAttempts calls failed. Retries back off exponentially unless the server asked for a delay.
+func withRetry(name string, fn func() error) error {
+	delay := retryBaseDelay
+	var err error
+	for attempt := 1; attempt <= retryAttempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt == retryAttempts {
+			break
+		}
+		wait := delay
+		if retryable.retryAfter > 0 {
+			wait = retryable.retryAfter
+		}
+		fmt.Printf("WARN: %s failed (attempt %d of %d), retrying in %v: %v\n", name, attempt, retryAttempts, wait, err)
+		time.Sleep(wait)
+		delay *= 2
+	}
+	return err
+}
+
+// statusError turns an unsuccessful HTTP response into an error, retryable for 429 and 5xx.
+func statusError(resp *http.Response, body []byte) error {
+	err := fmt.Errorf("unexpected status %s: %s", resp.Status, truncate(string(body), 200))
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return err
+	}
+	retryAfter := time.Duration(0)
+	if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	return &retryableError{err: err, retryAfter: retryAfter}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// --- Notification Retry Queue ---
+
+// notificationRetryQueueCollectionName holds failed notifications until they are sent or dead-lettered
+const notificationRetryQueueCollectionName = "notification_retry_queue"
+
+// notificationRetryQueue is the retry queue, nil when not connected (failed items are then left
+// unrecorded and matched again next cycle)
+var notificationRetryQueue *mongo.Collection
+
+// Queued notifications are retried after retryQueueBaseDelay, doubled after every failed retry up to retryQueueMaxDelay
+const (
+	retryQueueBaseDelay = 1 * time.Minute
+	retryQueueMaxDelay  = 1 * time.Hour
+)
+
+// retryQueueMu keeps jobs finishing their cycles together from sending the same queued notification twice
+var retryQueueMu sync.Mutex
+
+// QueuedNotification is a failed alert waiting in notification_retry_queue
+type QueuedNotification struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Permalink   string             `bson:"permalink"`
+	Subreddit   string             `bson:"subreddit"`
+	Kind        string             `bson:"kind"`
+	Profile     string             `bson:"profile"` // Profile whose notifier sends it, empty for the top-level config
+	Alert       Alert              `bson:"alert"`
+	Channels    []string           `bson:"channels,omitempty"` // Channels still to send it through, all if empty, see channelName
+	Error       string             `bson:"error"`              // Last failure
+	RetryCount  int                `bson:"retry_count"`        // Retries that failed so far
+	NextRetryAt time.Time          `bson:"next_retry_at"`
+	QueuedAt    time.Time          `bson:"queued_at"`
+}
+
+// retryDelay returns how long to wait before the next retry of a notification retried retryCount times.
+func retryDelay(retryCount int) time.Duration {
+	delay := retryQueueBaseDelay
+	for i := 0; i < retryCount && delay < retryQueueMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryQueueMaxDelay)
+}
+
+// notificationFailed handles a failed alert for item: the alert is queued in notification_retry_queue
+// for the channels that failed, channels failing with a permanentError are dead-lettered right away,
+// and the item is recorded so polling doesn't match it again. Channels that sent the alert don't get
+// it again. If the failure can't be stored the item is left unrecorded and the next cycle matches
+// and notifies it again.
+func notificationFailed(store Store, rules matchRules, item ProcessedItem, alert Alert, err error) {
+	retry, permanent := splitFailure(err)
+	queued := QueuedNotification{
+		Permalink: item.Permalink,
+		Subreddit: item.Subreddit,
+		Kind:      item.Kind,
+		Profile:   rules.profile,
+		Alert:     alert,
+		QueuedAt:  time.Now(),
+	}
+	if permanent != nil && notificationDLQ != nil {
+		// Retrying can't help, dead-letter it right away
+		letter := queued
+		letter.Channels = permanent.channels
+		ctxInsert, cancelInsert := context.WithTimeout(context.Background(), 5*time.Second)
+		dlqErr := deadLetter(ctxInsert, letter, permanent.err)
+		cancelInsert()
+		if dlqErr == nil {
+			item.DeadLettered = true
+			permanent = nil
+		} else {
+			fmt.Printf("Error moving notification for %s to %s: %v\n", item.Permalink, notificationDLQCollectionName, dlqErr)
+		}
+	}
+	retry = mergeFailures(retry, permanent) // Permanent failures that couldn't be dead-lettered
+	if retry == nil {
+		markItem(store, item)
+		return
+	}
+	if notificationRetryQueue == nil {
+		fmt.Printf("WARN: Notification for %s failed on %s, retrying next cycle: %v\n", item.Permalink, channelList(retry.channels), retry.err)
+		return
+	}
+	queued.Channels = retry.channels
+	queued.Error = retry.err.Error()
+	queued.NextRetryAt = time.Now().Add(retryDelay(0))
+	ctxInsert, cancelInsert := context.WithTimeout(context.Background(), 5*time.Second)
+	_, insertErr := notificationRetryQueue.InsertOne(ctxInsert, queued)
+	cancelInsert()
+	if insertErr != nil {
+		fmt.Printf("Error queueing notification for %s, retrying next cycle: %v\n", item.Permalink, insertErr)
+		return
+	}
+	fmt.Printf("WARN: Notification for %s failed on %s, queued for retry at %s: %v\n",
+		item.Permalink, channelList(queued.Channels), queued.NextRetryAt.Format(time.TimeOnly), retry.err)
+	markItem(store, item)
+}
+
+// drainRetryQueue retries the profile's queued notifications that are due, on the channels they
+// failed on. Sent ones are removed, failed ones are rescheduled with a longer delay for the channels
+// still failing, or moved to notification_dlq after max_retries retries.
+func drainRetryQueue(ctx context.Context, notifier Notifier, profile string) {
+	if notificationRetryQueue == nil {
+		return
+	}
+	retryQueueMu.Lock()
+	defer retryQueueMu.Unlock()
+
+	ctxFind, cancelFind := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelFind()
+	cursor, err := notificationRetryQueue.Find(ctxFind, map[string]interface{}{
+		"profile":       profile,
+		"next_retry_at": map[string]interface{}{"$lte": time.Now()},
+	})
+	if err != nil {
+		fmt.Printf("Error querying %s: %v\n", notificationRetryQueueCollectionName, err)
+		return
+	}
+	queue := []QueuedNotification{}
+	if err := cursor.All(ctxFind, &queue); err != nil {
+		fmt.Printf("Error reading %s: %v\n", notificationRetryQueueCollectionName, err)
+		return
+	}
+
+	for _, queued := range queue {
+		if ctx.Err() != nil {
+			return
+		}
+		sendErr := notifyAlertChannels(notifier, queued.Alert, queued.Channels)
+		retry, permanent := splitFailure(sendErr)
+		if retry != nil && queued.RetryCount+1 >= maxNotificationRetries {
+			permanent, retry = mergeFailures(permanent, retry), nil
+		}
+
+		ctxUpdate, cancelUpdate := context.WithTimeout(context.Background(), 5*time.Second)
+		if permanent != nil {
+			letter := queued
+			letter.Channels = permanent.channels
+			letter.RetryCount++
+			if dlqErr := deadLetter(ctxUpdate, letter, permanent.err); dlqErr != nil {
+				fmt.Printf("Error moving notification for %s to %s: %v\n", queued.Permalink, notificationDLQCollectionName, dlqErr)
+				retry = mergeFailures(retry, permanent) // Keep retrying until the DLQ takes it
+			}
+		}
+		var err error
+		if retry == nil {
+			if sendErr == nil {
+				fmt.Printf("Queued notification for %s sent on retry %d.\n", queued.Permalink, queued.RetryCount+1)
+			}
+			_, err = notificationRetryQueue.DeleteOne(ctxUpdate, map[string]interface{}{"_id": queued.ID})
+		} else {
+			queued.RetryCount++
+			next := time.Now().Add(retryDelay(queued.RetryCount))
+			fmt.Printf("WARN: Retry %d of %d for %s failed on %s, next at %s: %v\n",
+				queued.RetryCount, maxNotificationRetries, queued.Permalink, channelList(retry.channels), next.Format(time.TimeOnly), retry.err)
+			_, err = notificationRetryQueue.UpdateByID(ctxUpdate, queued.ID, map[string]interface{}{
+				"$set": map[string]interface{}{"error": retry.err.Error(), "next_retry_at": next, "channels": retry.channels},
+				"$inc": map[string]interface{}{"retry_count": 1},
+			})
+		}
+		cancelUpdate()
+		if err != nil {
+			fmt.Printf("Error updating %s for %s: %v\n", notificationRetryQueueCollectionName, queued.Permalink, err)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --- Poll Scheduling ---
+
+// defaultPollInterval is how often subreddits without poll_interval_seconds and search monitors are polled
+const defaultPollInterval = 5 * time.Minute
+
+// minPollInterval keeps per-subreddit intervals from hammering Reddit
+const minPollInterval = 30 * time.Second
+
+// pollJob is a set of subreddit chunks polled together on one interval. Subreddits with
+// their own poll_interval_seconds get a job each, the rest share the default job.
+type pollJob struct {
+	name           string
+	interval       time.Duration
+	subreddits     []string // Searched per keyword in search mode
+	listingChunks  []subredditChunk
+	commentChunks  []subredditChunk
+	searchMonitors bool     // Only the shared job polls the search monitors
+	profile        *Profile // nil for the top-level config
+
+	primary bool // Only the first job of the config or profile polls its followed threads and rechecks edited posts
+}
+
+// pollInterval returns the subreddit's own polling interval, or 0 if it uses the shared default.
+func (c SubredditConfig) pollInterval() time.Duration {
+	return time.Duration(c.PollIntervalSeconds) * time.Second
+}
+
+// buildPollJobs builds the shared job for subreddits on the default interval, followed by one
+// job per subreddit with its own interval.
+func buildPollJobs(configs []SubredditConfig, size int) []pollJob {
+	shared := []SubredditConfig{}
+	own := []pollJob{}
+	for _, cfg := range configs {
+		if cfg.pollInterval() == 0 {
+			shared = append(shared, cfg)
+			continue
+		}
+		job := pollJob{
+			name:          "r/" + cfg.Name,
+			interval:      cfg.pollInterval(),
+			listingChunks: buildListingChunks([]SubredditConfig{cfg}, size),
+		}
+		if !cfg.isMultireddit() {
+			job.subreddits = []string{cfg.Name}
+			job.commentChunks = buildSubredditChunks(job.subreddits, "comments", size)
+		}
+		own = append(own, job)
+	}
+
+	if len(shared) == 0 && len(searchMonitors) == 0 {
+		return own
+	}
+	sharedNames := make([]string, 0, len(shared))
+	for _, cfg := range shared {
+		if !cfg.isMultireddit() {
+			sharedNames = append(sharedNames, cfg.Name) // Multireddits are only fetched from their URL
+		}
+	}
+	jobs := []pollJob{{
+		name:           "default",
+		interval:       defaultPollInterval,
+		subreddits:     sharedNames,
+		listingChunks:  buildListingChunks(shared, size),
+		commentChunks:  buildSubredditChunks(sharedNames, "comments", size),
+		searchMonitors: true,
+	}}
+	return append(jobs, own...)
+}
+
+// searchQueries returns the distinct Reddit search queries for all keywords and keyword groups,
+// and whether every keyword could be turned into one.
+func (r matchRules) searchQueries() (queries []string, complete bool) {
+	specs := append([]KeywordSpec{}, r.keywords...)
+	for _, group := range r.groups {
+		specs = append(specs, group.Keywords...)
+	}
+	complete = true
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		query := spec.searchQuery()
+		if query == "" {
+			complete = false
+			continue
+		}
+		if !seen[strings.ToLower(query)] {
+			seen[strings.ToLower(query)] = true
+			queries = append(queries, query)
+		}
+	}
+	return queries, complete
+}
+
+// pollSearch fetches the newest posts containing each keyword through Reddit search, one
+// request per subreddit and keyword, and processes them. Posts found by several keywords are processed once.
+func (j pollJob) pollSearch(ctx context.Context, store Store, notifier Notifier, rules matchRules, queries []string) {
+	posts := []Post{}
+	seen := map[string]bool{}
+	for _, subreddit := range j.subreddits {
+		for _, query := range queries {
+			if ctx.Err() != nil {
+				return
+			}
+			results, err := redditClient.fetchSearch(ctx, subreddit, query)
+			if err != nil {
+				fmt.Printf("Error searching r/%s for %s: %v\n", subreddit, query, err)
+				continue
+			}
+			for _, post := range results {
+				if !seen[post.Permalink] {
+					seen[post.Permalink] = true
+					post.Listing = "search"
+					posts = append(posts, post)
+				}
+			}
+		}
+	}
+	processPosts(store, notifier, rules, posts)
+}
+
+// poll fetches and processes one round of the job's listings, search monitors and comments.
+// In search mode the listings are replaced by keyword searches, unless some keywords (raw
+// patterns, substring matches) can't be searched for. Comments always come from the listing.
+// It returns false if the cycle failed entirely, every post and comment fetch erroring.
+func (j pollJob) poll(ctx context.Context, store Store, notifier Notifier) bool {
+	fmt.Printf("\nFetching new data for %s at %s\n", j.name, time.Now().Format(time.RFC1123))
+	ctx, limiterWait := withLimiterWait(ctx)
+
+	// Copy the rules, keyword and subreddit changes through the APIs apply from the next cycle
+	configMu.RLock()
+	rules := currentMatchRules()
+	configMu.RUnlock()
+	if j.profile != nil {
+		rules = j.profile.rules()
+		store, notifier = profileStore(store, j.profile.Name), j.profile.notifier()
+	}
+	retryNotifier := notifier // Unbatched, retries need to know whether the send failed
+	if batchNotifications {
+		batch := newBatchNotifier(notifier, rules.profile)
+		notifier = batch
+		defer func() {
+			if err := batch.flush(); err != nil {
+				fmt.Printf("Error sending batched notifications for %s: %v\n", j.name, err)
+			}
+		}()
+	}
+
+	replayHeldItems(store, notifier, rules)
+
+	fetches, failures := 0, 0
+	fetchListings := true
+	if searchMode && len(j.subreddits) > 0 {
+		queries, complete := rules.searchQueries()
+		j.pollSearch(ctx, store, notifier, rules, queries)
+		fetchListings = !complete
+	}
+
+	// Fetch and process posts (chunks that failed are already logged)
+	if fetchListings && len(j.listingChunks) > 0 {
+		var malformed atomic.Int64
+		posts, failedPostChunks := fetchChunked(j.listingChunks, "posts", func(c subredditChunk) ([]Post, error) {
+			posts, skipped, err := redditClient.fetchPosts(ctx, c.endpoint)
+			malformed.Add(int64(skipped))
+			for i := range posts {
+				posts[i].Listing = c.listing // Record which listing surfaced the post
+				if c.label != "" {
+					posts[i].Subreddit = c.label // Multireddit posts are labeled with the configured name
+				}
+			}
+			return posts, err
+		})
+		fetches++
+		if failedPostChunks == len(j.listingChunks) {
+			fmt.Printf("Error fetching posts for %s: all subreddit chunks failed\n", j.name)
+			failures++
+			health.record(subsystemRedditPosts, fmt.Errorf("all %d post chunk(s) of %s failed", failedPostChunks, j.name))
+		} else {
+			health.record(subsystemRedditPosts, nil)
+			processPosts(store, notifier, rules, posts)
+			if n := malformed.Load(); n > 0 {
+				fmt.Printf("WARN: Skipped %d malformed post(s) in the listings of %s\n", n, j.name)
+			}
+		}
+	}
+
+	// Fetch and process search monitors
+	if j.searchMonitors {
+		for _, monitor := range searchMonitors {
+			results, _, err := redditClient.fetchPosts(ctx, monitor.endpoint()) // Malformed results are logged
+			if err != nil {
+				fmt.Printf("Error fetching search results for %q: %v\n", monitor.Query, err)
+				continue
+			}
+			for i := range results {
+				results[i].Listing = "search"
+			}
+			processSearchResults(store, notifier, rules, monitor, results)
+		}
+	}
+
+	// Fetch and process comments
+	if len(j.commentChunks) > 0 {
+		var malformed atomic.Int64
+		comments, failedCommentChunks := fetchChunked(j.commentChunks, "comments", func(c subredditChunk) ([]Comment, error) {
+			comments, skipped, err := redditClient.fetchComments(ctx, c.endpoint)
+			malformed.Add(int64(skipped))
+			return comments, err
+		})
+		fetches++
+		if failedCommentChunks == len(j.commentChunks) {
+			fmt.Printf("Error fetching comments for %s: all subreddit chunks failed\n", j.name)
+			failures++
+			health.record(subsystemRedditComments, fmt.Errorf("all %d comment chunk(s) of %s failed", failedCommentChunks, j.name))
+		} else {
+			health.record(subsystemRedditComments, nil)
+			skips := processComments(store, notifier, rules, comments)
+			fmt.Printf("Cycle summary for %s: %d comment(s) fetched, %d skipped as malformed, %d skipped by length, %d skipped as bot accounts, %d skipped as duplicates\n",
+				j.name, len(comments), malformed.Load(), skips.length, skips.bots, skips.duplicates)
+		}
+	}
+	if j.primary {
+		pollFollowedThreads(ctx, store, notifier, rules)
+		recheckEditedPosts(ctx, store, notifier, rules)
+	}
+	if fetches > 0 && failures < fetches {
+		checkSpikes(ctx, rules, j.subreddits, retryNotifier)
+	}
+	drainRetryQueue(ctx, retryNotifier, rules.profile)
+	syncSheet(ctx)
+	fmt.Printf("Cycle for %s waited %v on the Reddit rate limiter\n", j.name, limiterWait.total().Round(time.Millisecond))
+	checkMongoHealth()
+	return fetches == 0 || failures < fetches
+}
+
+// safePoll is poll recovering from panics, so one bad cycle doesn't stop monitoring. A cycle
+// that panicked counts as failed.
+func (j pollJob) safePoll(ctx context.Context, store Store, notifier Notifier) (ok bool) {
+	defer recoverPanic("poll cycle of "+j.name, nil)
+	return j.poll(ctx, store, notifier)
+}
+
+// runPollJobs starts a goroutine per job that polls immediately and then every job interval,
+// all sharing store and notifier. When the subreddits or the config change, the jobs are stopped
+// after their running cycle, a reloaded config is applied and the jobs are rebuilt. It returns once
+// ctx is cancelled and every goroutine has stopped.
+func runPollJobs(ctx context.Context, store Store, notifier Notifier) {
+	for {
+		configMu.RLock()
+		jobs := allPollJobs()
+		configMu.RUnlock()
+
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for _, job := range jobs {
+			wg.Add(1)
+			go func(job pollJob) {
+				defer wg.Done()
+				for {
+					if job.safePoll(ctx, store, notifier) {
+						pingHeartbeat() // A cycle that fetched nothing must not look alive
+					}
+					select {
+					case <-ctx.Done():
+						fmt.Printf("Stopped polling %s.\n", job.name)
+						return
+					case <-stop:
+						return
+					case <-time.After(job.interval):
+					}
+				}
+			}(job)
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-configReload:
+			fmt.Println("Config changed, restarting poll jobs once their running cycles finish...")
+			close(stop)
+			wg.Wait()
+			applyPendingConfig() // No cycle is running
+		}
+	}
+}
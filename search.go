@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Match Search ---
+
+// textIndexName is the name of the text index over the stored titles and texts of matches
+const textIndexName = "match_text"
+
+// Server error codes of an index that clashes with an existing one: same name with other keys or
+// options, or a second text index (a collection can only have one)
+const (
+	mongoIndexOptionsConflict  = 85
+	mongoIndexKeySpecsConflict = 86
+)
+
+// textIndexModel indexes snapshot titles and texts, and the titles and snippets of matches stored
+// before snapshots. Titles weigh more.
+func textIndexModel() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "snapshot.title", Value: "text"}, {Key: "snapshot.text", Value: "text"},
+			{Key: "title", Value: "text"}, {Key: "snippet", Value: "text"},
+		},
+		Options: options.Index().SetName(textIndexName).
+			SetWeights(bson.D{{Key: "snapshot.title", Value: 3}, {Key: "title", Value: 3}}),
+	}
+}
+
+// ensureTextIndex creates the text index of collection. An older index in the way, with the same
+// name or another text index, is dropped and the text index created again.
+func ensureTextIndex(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateOne(ctx, textIndexModel())
+	var cmdErr mongo.CommandError
+	if err == nil || !errors.As(err, &cmdErr) || (cmdErr.Code != mongoIndexOptionsConflict && cmdErr.Code != mongoIndexKeySpecsConflict) {
+		return err
+	}
+
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing indexes: %w", err)
+	}
+	var indexes []bson.M
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return fmt.Errorf("error listing indexes: %w", err)
+	}
+	for _, index := range indexes {
+		name, _ := index["name"].(string)
+		key, _ := index["key"].(bson.M)
+		if _, isText := key["_fts"]; name == "_id_" || (name != textIndexName && !isText) {
+			continue
+		}
+		if _, err := collection.Indexes().DropOne(ctx, name); err != nil {
+			return fmt.Errorf("error dropping conflicting index %q: %w", name, err)
+		}
+		fmt.Printf("Dropped index %q, it conflicted with the %s text index\n", name, textIndexName)
+	}
+	_, err = collection.Indexes().CreateOne(ctx, textIndexModel())
+	return err
+}
+
+// matchesFindOptions sorts matches newest first or, for a text search, most relevant first with
+// the relevance returned as text_score.
+func matchesFindOptions(search string) *options.FindOptions {
+	if search == "" {
+		return options.Find().SetSort(map[string]interface{}{"processed_at": -1})
+	}
+	textScore := map[string]interface{}{"$meta": "textScore"}
+	return options.Find().
+		SetSort(bson.D{{Key: "text_score", Value: textScore}, {Key: "processed_at", Value: -1}}).
+		SetProjection(map[string]interface{}{"text_score": textScore})
+}
+
+// searchTerms returns the words and quoted phrases of a text search, without negated ones.
+func searchTerms(search string) []string {
+	terms := []string{}
+	for i, part := range strings.Split(search, `"`) {
+		if i%2 == 1 { // Inside quotes
+			if phrase := strings.TrimSpace(part); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			if !strings.HasPrefix(word, "-") {
+				terms = append(terms, word)
+			}
+		}
+	}
+	return terms
+}
+
+// termsPattern matches any of terms, case-insensitively, nil without terms. Mongo matches stems,
+// so "calling" also finds "calls": terms match as word prefixes without their last few letters.
+func termsPattern(terms []string) *regexp.Regexp {
+	alternatives := []string{}
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) > 5 {
+			runes := []rune(term)
+			term = string(runes[:len(runes)-3]) // Rough stem, e.g. "calling" -> "call"
+		}
+		alternatives = append(alternatives, regexp.QuoteMeta(term)+`\w*`)
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(alternatives, "|") + `)`)
+}
+
+// searchText returns the stored text of a match to search in, its snapshot if it has one.
+func searchText(item ProcessedItem) string {
+	if item.Snapshot != nil {
+		return item.Snapshot.Title + "\n" + item.Snapshot.Text
+	}
+	return item.Title + "\n" + item.Snippet
+}
+
+// searchExcerpt returns about maxSnippetLength characters of text on one line, around the first
+// match of pattern, with the matches wrapped in open and close. Text is HTML escaped when escape is
+// set. Mongo text search doesn't return offsets, so they are found here.
+func searchExcerpt(text string, pattern *regexp.Regexp, open, close string, escape bool) string {
+	text = strings.Join(strings.Fields(text), " ")
+	start := 0
+	if pattern != nil {
+		if loc := pattern.FindStringIndex(text); loc != nil {
+			start = max(0, loc[0]-maxSnippetLength/3)
+			for start > 0 && !utf8.RuneStart(text[start]) {
+				start--
+			}
+		}
+	}
+	excerpt := text[start:]
+	if start > 0 {
+		excerpt = "..." + excerpt
+	}
+	excerpt = truncate(excerpt, maxSnippetLength)
+
+	wrap := func(s string) string { return s }
+	if escape {
+		wrap = html.EscapeString
+	}
+	if pattern == nil {
+		return wrap(excerpt)
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringIndex(excerpt, -1) {
+		b.WriteString(wrap(excerpt[last:loc[0]]) + open + wrap(excerpt[loc[0]:loc[1]]) + close)
+		last = loc[1]
+	}
+	b.WriteString(wrap(excerpt[last:]))
+	return b.String()
+}
+
+// highlightSearch sets the Highlight of items, an HTML excerpt with the search terms in <mark>.
+func highlightSearch(items []ProcessedItem, search string) {
+	pattern := termsPattern(searchTerms(search))
+	for i := range items {
+		items[i].Highlight = searchExcerpt(searchText(items[i]), pattern, "<mark>", "</mark>", true)
+	}
+}
+
+// printSearchResults prints text search results, best first, each with an excerpt showing the
+// search terms in **bold**.
+func printSearchResults(items []ProcessedItem, search string) {
+	pattern := termsPattern(searchTerms(search))
+	for _, item := range items {
+		fmt.Printf("%s  r/%s  score %.2f  https://www.reddit.com%s\n", item.ProcessedAt.Local().Format(time.RFC3339), item.Subreddit, item.TextScore, item.Permalink)
+		fmt.Printf("  %s\n\n", searchExcerpt(searchText(item), pattern, "**", "**", false))
+	}
+	fmt.Printf("%d match(es) for %q\n", len(items), search)
+}
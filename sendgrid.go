@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// --- SendGrid ---
+
+var sendgridAPIKey = os.Getenv("SENDGRID_API_KEY") // Send through SendGrid instead of Gmail SMTP when set
+var sendgridFrom = os.Getenv("SENDGRID_FROM")      // Verified sender address, defaults to GMAIL_USER
+
+// sendgridEndpoint is SendGrid's mail send API
+const sendgridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridNotifier sends alerts through the SendGrid REST API
+type SendGridNotifier struct {
+	apiKey    string
+	from      string
+	recipient string
+	endpoint  string
+	client    *http.Client
+}
+
+// NewSendGridNotifier returns a Notifier emailing recipient through SendGrid.
+func NewSendGridNotifier(recipient string) *SendGridNotifier {
+	from := sendgridFrom
+	if from == "" {
+		from = gmailUser
+	}
+	return &SendGridNotifier{apiKey: sendgridAPIKey, from: from, recipient: recipient, endpoint: sendgridEndpoint, client: httpClient}
+}
+
+// sendgridAddress is an email address in a SendGrid request
+type sendgridAddress struct {
+	Email string `json:"email"`
+}
+
+// sendgridPersonalization lists the recipients of a SendGrid request
+type sendgridPersonalization struct {
+	To []sendgridAddress `json:"to"`
+}
+
+// sendgridContent is one content part of a SendGrid request
+type sendgridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// sendgridRequest is the body of a /v3/mail/send request
+type sendgridRequest struct {
+	Personalizations []sendgridPersonalization `json:"personalizations"`
+	From             sendgridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendgridContent         `json:"content"`
+}
+
+// channel names the notifier after its recipient, see channelName.
+func (n *SendGridNotifier) channel() string { return "email " + n.recipient }
+
+// Notify sends the alert as a plain text and HTML email, retrying rate limits and server errors.
+func (n *SendGridNotifier) Notify(subject, body string) error {
+	return n.send(newEmailMessage(n.from, n.recipient, subject, body))
+}
+
+// NotifyHTML sends a report with its own HTML version.
+func (n *SendGridNotifier) NotifyHTML(subject, text, htmlText string) error {
+	return n.send(emailMessage{From: n.from, To: n.recipient, Subject: subject, Text: text, HTML: htmlText})
+}
+
+// send posts message to SendGrid.
+func (n *SendGridNotifier) send(message emailMessage) error {
+	request := sendgridRequest{
+		Personalizations: []sendgridPersonalization{{To: []sendgridAddress{{Email: message.To}}}},
+		From:             sendgridAddress{Email: message.From},
+		Subject:          message.Subject,
+		Content: []sendgridContent{
+			{Type: "text/plain", Value: message.Text}, // SendGrid requires text/plain first
+			{Type: "text/html", Value: message.HTML},
+		},
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	err = withRetry("SendGrid send", func() error {
+		req, err := http.NewRequest(http.MethodPost, n.endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+n.apiKey)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := n.client.Do(req)
+		if err != nil {
+			return &retryableError{err: err} // Network errors are usually transient
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode >= 300 {
+			return statusError(resp, respBody) // 429 and 5xx are retried, 400/401/403/413 are permanent
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send email through SendGrid: %w", err)
+	}
+	fmt.Println("Email sent successfully to", n.recipient, "through SendGrid")
+	return nil
+}
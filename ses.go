@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/aws/smithy-go"
+)
+
+// --- Amazon SES ---
+
+var sesFrom = os.Getenv("SES_FROM") // Verified sender identity
+
+// sesTimeout bounds an SES call, the SDK's own retries included
+const sesTimeout = 30 * time.Second
+
+// checkSESEnv verifies the variables needed to send through SES are set. Credentials and the
+// region come from the AWS SDK's default chain, see loadAWSConfig.
+func checkSESEnv() error {
+	if sesFrom == "" {
+		return fmt.Errorf("SES_FROM must be set to send through SES")
+	}
+	return nil
+}
+
+// sesAPI is the part of the SES v2 client the notifier uses
+type sesAPI interface {
+	SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error)
+}
+
+// SESNotifier sends alerts through the Amazon SES v2 SendEmail API, with the AWS SDK's credential
+// chain (environment, shared files, SSO, container or instance role)
+type SESNotifier struct {
+	recipient string
+
+	mu     sync.Mutex
+	client sesAPI // Created on first use, see sesClient
+}
+
+// NewSESNotifier returns a Notifier sending to recipient through SES.
+func NewSESNotifier(recipient string) *SESNotifier {
+	return &SESNotifier{recipient: recipient}
+}
+
+// sesClient returns the SES client, loading the AWS config the first time. A failed load is
+// retried on the next send.
+func (n *SESNotifier) sesClient(ctx context.Context) (sesAPI, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.client != nil {
+		return n.client, nil
+	}
+	cfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n.client = sesv2.NewFromConfig(cfg)
+	return n.client, nil
+}
+
+// channel names the notifier after its recipient, see channelName.
+func (n *SESNotifier) channel() string { return "email " + n.recipient }
+
+// Notify sends the alert through SES, retrying throttling and server errors.
+func (n *SESNotifier) Notify(subject, body string) error {
+	return n.send(newEmailMessage(sesFrom, n.recipient, subject, body))
+}
+
+// NotifyHTML sends a report with its own HTML version.
+func (n *SESNotifier) NotifyHTML(subject, text, htmlText string) error {
+	return n.send(emailMessage{From: sesFrom, To: n.recipient, Subject: subject, Text: text, HTML: htmlText})
+}
+
+// sesContent returns data as SES message content.
+func sesContent(data string) *types.Content {
+	return &types.Content{Data: aws.String(data), Charset: aws.String("UTF-8")}
+}
+
+// send sends message through SES.
+func (n *SESNotifier) send(message emailMessage) error {
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(message.From),
+		Destination:      &types.Destination{ToAddresses: []string{message.To}},
+		Content: &types.EmailContent{Simple: &types.Message{
+			Subject: sesContent(message.Subject),
+			Body:    &types.Body{Text: sesContent(message.Text), Html: sesContent(message.HTML)},
+		}},
+	}
+	err := withRetry("SES send", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), sesTimeout)
+		defer cancel()
+		client, err := n.sesClient(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = client.SendEmail(ctx, input)
+		return sesError(err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send email through SES: %w", err)
+	}
+	fmt.Println("Email sent successfully to", n.recipient, "through SES")
+	return nil
+}
+
+// sesError classifies a SendEmail error for withRetry: throttling, server errors and network
+// failures are retried, a rejected message or any other client error is permanent.
+func sesError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return &retryableError{err: err}
+	}
+	switch apiErr.ErrorCode() {
+	case "TooManyRequestsException", "LimitExceededException", "Throttling", "ThrottlingException":
+		return &retryableError{err: err}
+	case "MessageRejected", "MailFromDomainNotVerifiedException", "AccountSuspendedException", "SendingPausedException":
+		return &permanentError{err: err}
+	}
+	if apiErr.ErrorFault() == smithy.FaultServer {
+		return &retryableError{err: err}
+	}
+	return &permanentError{err: err}
+}
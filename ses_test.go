@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeSES is an sesAPI failing with errs in turn, then succeeding, keeping the inputs it got
+type fakeSES struct {
+	errs   []error
+	inputs []*sesv2.SendEmailInput
+}
+
+func (f *fakeSES) SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+	f.inputs = append(f.inputs, params)
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &sesv2.SendEmailOutput{MessageId: aws.String("m-1")}, nil
+}
+
+func TestSESNotifierMessage(t *testing.T) {
+	oldFrom := sesFrom
+	sesFrom = "alerts@example.com"
+	t.Cleanup(func() { sesFrom = oldFrom })
+	client := &fakeSES{}
+	n := &SESNotifier{recipient: "team@example.com", client: client}
+
+	if err := n.NotifyHTML("Weekly report", "plain", "<p>report</p>"); err != nil {
+		t.Fatalf("NotifyHTML: %v", err)
+	}
+	if len(client.inputs) != 1 {
+		t.Fatalf("SendEmail called %d time(s), want once", len(client.inputs))
+	}
+	input := client.inputs[0]
+	if aws.ToString(input.FromEmailAddress) != "alerts@example.com" {
+		t.Errorf("from = %q, want SES_FROM", aws.ToString(input.FromEmailAddress))
+	}
+	if input.Destination == nil || len(input.Destination.ToAddresses) != 1 || input.Destination.ToAddresses[0] != "team@example.com" {
+		t.Errorf("destination = %+v, want the recipient", input.Destination)
+	}
+	message := input.Content.Simple
+	for name, content := range map[string]*types.Content{"subject": message.Subject, "text": message.Body.Text, "html": message.Body.Html} {
+		if aws.ToString(content.Charset) != "UTF-8" {
+			t.Errorf("%s charset = %q, want UTF-8", name, aws.ToString(content.Charset))
+		}
+	}
+	if aws.ToString(message.Subject.Data) != "Weekly report" || aws.ToString(message.Body.Text.Data) != "plain" || aws.ToString(message.Body.Html.Data) != "<p>report</p>" {
+		t.Errorf("message = %q, %q, %q, want the report", aws.ToString(message.Subject.Data), aws.ToString(message.Body.Text.Data), aws.ToString(message.Body.Html.Data))
+	}
+}
+
+func TestSESNotifierErrors(t *testing.T) {
+	withFastRetries(t)
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+		permanent bool
+	}{
+		{"throttled", &smithy.GenericAPIError{Code: "TooManyRequestsException", Fault: smithy.FaultClient}, retryAttempts, false},
+		{"sending quota", &types.LimitExceededException{Message: aws.String("Maximum sending rate exceeded")}, retryAttempts, false},
+		{"server error", &smithy.GenericAPIError{Code: "InternalFailure", Fault: smithy.FaultServer}, retryAttempts, false},
+		{"network failure", errors.New("connection reset"), retryAttempts, false},
+		{"message rejected", &types.MessageRejected{Message: aws.String("Email address is not verified")}, 1, true},
+		{"bad request", &types.BadRequestException{Message: aws.String("Missing required field")}, 1, true},
+	}
+	for _, tt := range tests {
+		client := &fakeSES{}
+		for i := 0; i < retryAttempts; i++ {
+			client.errs = append(client.errs, tt.err)
+		}
+		n := &SESNotifier{recipient: "team@example.com", client: client}
+		err := n.Notify("subject", "body")
+		var permanent *permanentError
+		if err == nil || !strings.Contains(err.Error(), "SES") || errors.As(err, &permanent) != tt.permanent {
+			t.Errorf("%s: Notify = %v, want a failure, permanent %v", tt.name, err, tt.permanent)
+		}
+		if len(client.inputs) != tt.wantCalls {
+			t.Errorf("%s: SendEmail called %d time(s), want %d", tt.name, len(client.inputs), tt.wantCalls)
+		}
+	}
+}
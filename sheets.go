@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Google Sheets ---
+
+// SheetsConfig holds the Google Sheets settings of the config file. Matches are appended to the
+// sheet when SpreadsheetID is set.
+type SheetsConfig struct {
+	SpreadsheetID   string
+	SheetName       string // Tab the rows go to, defaults to Sheet1
+	CredentialsFile string // Service account JSON key, defaults to GOOGLE_APPLICATION_CREDENTIALS
+}
+
+// sheets is the configured spreadsheet, zero when the integration is off
+var sheets SheetsConfig
+
+// sheetsMaxRows caps the rows appended per cycle, one append request each cycle stays well under the Sheets quota
+const sheetsMaxRows = 500
+
+// sheetsScope is the OAuth scope needed to append rows
+const sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+// sheetsMu keeps jobs finishing their cycles together from appending the same rows twice
+var sheetsMu sync.Mutex
+
+// credentialsFile returns the service account key path.
+func (c SheetsConfig) credentialsFile() string {
+	if c.CredentialsFile != "" {
+		return c.CredentialsFile
+	}
+	return os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+}
+
+// validate checks that a configured spreadsheet has credentials.
+func (c SheetsConfig) validate() error {
+	if c.SpreadsheetID == "" {
+		return nil
+	}
+	if c.credentialsFile() == "" {
+		return fmt.Errorf("sheets_credentials_file or GOOGLE_APPLICATION_CREDENTIALS must be set with sheets_spreadsheet_id")
+	}
+	return nil
+}
+
+// sheetName returns the tab rows are appended to.
+func (c SheetsConfig) sheetName() string {
+	if c.SheetName == "" {
+		return "Sheet1"
+	}
+	return c.SheetName
+}
+
+// markSheetPending flags a match to be appended to the sheet, when the integration is on.
+func markSheetPending(item ProcessedItem) ProcessedItem {
+	if sheets.SpreadsheetID != "" {
+		synced := false
+		item.SheetSynced = &synced
+	}
+	return item
+}
+
+// serviceAccountKey is the part of a service account JSON key used to get access tokens
+type serviceAccountKey struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// googleToken caches the access token of the service account
+type googleToken struct {
+	mu      sync.Mutex
+	key     *serviceAccountKey
+	rsaKey  *rsa.PrivateKey
+	token   string
+	expires time.Time
+}
+
+// sheetsToken is the cached token used by syncSheet
+var sheetsToken = &googleToken{}
+
+// loadKey reads and parses the service account key, once.
+func (t *googleToken) loadKey(path string) error {
+	if t.key != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read service account key: %w", err)
+	}
+	var key serviceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return fmt.Errorf("failed to parse service account key %s: %w", path, err)
+	}
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return fmt.Errorf("service account key %s has no PEM private_key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse private_key of %s: %w", path, err)
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return fmt.Errorf("private_key of %s is not an RSA key", path)
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	t.key, t.rsaKey = &key, rsaKey
+	return nil
+}
+
+// get returns a valid access token, exchanging a signed JWT for a new one when the cached one
+// expires within a minute.
+func (t *googleToken) get(path string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && time.Until(t.expires) > time.Minute {
+		return t.token, nil
+	}
+	if err := t.loadKey(path); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   t.key.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   t.key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, t.rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+	assertion := unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	resp, err := httpClient.PostForm(t.key.TokenURI, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get access token: %w", statusError(resp, body))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("failed to parse access token: %w", err)
+	}
+	t.token = token.AccessToken
+	t.expires = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return t.token, nil
+}
+
+// sheetRow formats a match as a sheet row: timestamp, subreddit, keywords, title, permalink, author.
+func sheetRow(item ProcessedItem) []string {
+	return []string{
+		item.ProcessedAt.UTC().Format(time.RFC3339),
+		"r/" + item.Subreddit,
+		strings.Join(item.Keywords, ", "),
+		item.Title,
+		"https://www.reddit.com" + item.Permalink,
+		item.Author,
+	}
+}
+
+// appendSheetRows appends rows to the configured sheet in one request.
+func appendSheetRows(rows [][]string) error {
+	token, err := sheetsToken.get(sheets.credentialsFile())
+	if err != nil {
+		return err
+	}
+	sheetRange := "'" + strings.ReplaceAll(sheets.sheetName(), "'", "''") + "'!A:F"
+	endpoint := "https://sheets.googleapis.com/v4/spreadsheets/" + url.PathEscape(sheets.SpreadsheetID) +
+		"/values/" + url.PathEscape(sheetRange) + ":append?valueInputOption=RAW&insertDataOption=INSERT_ROWS"
+	payload, err := json.Marshal(map[string]interface{}{"values": rows})
+	if err != nil {
+		return err
+	}
+
+	return withRetry("Sheets append", func() error {
+		req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return &retryableError{err: err}
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode >= 300 {
+			return statusError(resp, respBody)
+		}
+		return nil
+	})
+}
+
+// syncSheet appends the matches not yet in the sheet (sheet_synced false), oldest first, and flags
+// them synced. A failed append leaves them for the next cycle.
+func syncSheet(ctx context.Context) {
+	if sheets.SpreadsheetID == "" || processedItemsCollection == nil {
+		return
+	}
+	sheetsMu.Lock()
+	defer sheetsMu.Unlock()
+
+	ctxFind, cancelFind := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelFind()
+	cursor, err := processedItemsCollection.Find(ctxFind, map[string]interface{}{"sheet_synced": false},
+		options.Find().SetSort(map[string]interface{}{"processed_at": 1}).SetLimit(sheetsMaxRows))
+	if err != nil {
+		fmt.Printf("Error querying matches to append to the sheet: %v\n", err)
+		return
+	}
+	pending := []ProcessedItem{}
+	if err := cursor.All(ctxFind, &pending); err != nil {
+		fmt.Printf("Error reading matches to append to the sheet: %v\n", err)
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	rows := make([][]string, 0, len(pending))
+	filters := make([]interface{}, 0, len(pending))
+	for _, item := range pending {
+		rows = append(rows, sheetRow(item))
+		filters = append(filters, map[string]interface{}{"permalink": item.Permalink, "profile": profileFilter(item.Profile)})
+	}
+	if err := appendSheetRows(rows); err != nil {
+		fmt.Printf("Error appending %d match(es) to the sheet, retrying next cycle: %v\n", len(rows), err)
+		return
+	}
+
+	ctxUpdate, cancelUpdate := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelUpdate()
+	_, err = processedItemsCollection.UpdateMany(ctxUpdate, map[string]interface{}{"$or": filters},
+		map[string]interface{}{"$set": map[string]interface{}{"sheet_synced": true}})
+	if err != nil {
+		fmt.Printf("Error flagging %d match(es) as appended to the sheet, they may be appended again: %v\n", len(rows), err)
+		return
+	}
+	fmt.Printf("Appended %d match(es) to sheet %s.\n", len(rows), sheets.sheetName())
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+// --- Persistent SMTP Connection ---
+
+// smtpConn is the authenticated SMTP connection shared by every email sent through Gmail SMTP
+var smtpConn = &smtpConnection{}
+
+// smtpConnection reuses one SMTP session across sends instead of a TCP+TLS handshake and login per email
+type smtpConnection struct {
+	mu     sync.Mutex
+	client *smtp.Client // nil until the first send, and after a failure
+}
+
+// dialSMTP connects to the SMTP server, upgrades the session to TLS and authenticates.
+func dialSMTP() (*smtp.Client, error) {
+	conn, err := net.DialTimeout("tcp", smtpHost+":"+smtpPort, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	client, err := smtp.NewClient(conn, smtpHost)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	if err := client.StartTLS(&tls.Config{ServerName: smtpHost}); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("STARTTLS failed: %w", err)
+	}
+	if err := client.Auth(smtp.PlainAuth("", gmailUser, gmailAppPassword, smtpHost)); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+	return client, nil
+}
+
+// send delivers msg over the shared connection, connecting first if there is none. A connection
+// the server dropped while idle (between cycles) is noticed with NOOP and replaced. If a send on a
+// reused connection fails with a network error, it is retried once on a new connection.
+func (c *smtpConnection) send(from string, to []string, msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reused := c.client != nil
+	if reused && c.client.Noop() != nil {
+		c.drop()
+		reused = false
+	}
+	if c.client == nil {
+		client, err := dialSMTP()
+		if err != nil {
+			return err
+		}
+		c.client = client
+	}
+
+	err := c.deliver(from, to, msg)
+	var protocolErr *textproto.Error
+	if err != nil && errors.As(err, &protocolErr) {
+		// The server refused this message, the session itself is fine
+		if c.client.Reset() != nil {
+			c.drop()
+		}
+		return err
+	}
+	if err != nil && reused {
+		fmt.Printf("WARN: SMTP connection failed (%v), reconnecting and retrying once\n", err)
+		c.drop()
+		client, dialErr := dialSMTP()
+		if dialErr != nil {
+			return dialErr
+		}
+		c.client = client
+		err = c.deliver(from, to, msg)
+	}
+	if err != nil {
+		c.drop()
+	}
+	return err
+}
+
+// deliver runs one mail transaction on the current connection.
+func (c *smtpConnection) deliver(from string, to []string, msg []byte) error {
+	if err := c.client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := c.client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// drop closes the current connection without QUIT, it is assumed broken.
+func (c *smtpConnection) drop() {
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
+
+// close ends the session with QUIT, on shutdown.
+func (c *smtpConnection) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil {
+		return
+	}
+	if err := c.client.Quit(); err != nil {
+		c.client.Close()
+	}
+	c.client = nil
+}
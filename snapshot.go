@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// --- Match Snapshots ---
+
+// snapshotMatches stores the full title, text, author and score with every match, so it can be
+// read after the item is deleted on Reddit
+var snapshotMatches = true
+
+// maxSnapshotBytes caps the text of a snapshot, longer selftexts are truncated with a marker
+const maxSnapshotBytes = 40 * 1024
+
+// MatchSnapshot is a copy of a matched post or comment as it was when it matched
+type MatchSnapshot struct {
+	Title     string `bson:"title" json:"title"` // Post title, or the parent post's title for comments
+	Text      string `bson:"text" json:"text"`   // Selftext or comment body, at most maxSnapshotBytes
+	Author    string `bson:"author" json:"author"`
+	Score     int    `bson:"score" json:"score"`
+	Truncated bool   `bson:"truncated,omitempty" json:"truncated,omitempty"`
+}
+
+// newSnapshot returns the snapshot of a match, nil when snapshots are disabled.
+func newSnapshot(title, text, author string, score int) *MatchSnapshot {
+	if !snapshotMatches {
+		return nil
+	}
+	snapshot := &MatchSnapshot{Title: title, Text: text, Author: author, Score: score}
+	if len(text) > maxSnapshotBytes {
+		cut := maxSnapshotBytes
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut-- // Don't split a character
+		}
+		snapshot.Text = text[:cut] + fmt.Sprintf("\n\n[... truncated, %d more bytes]", len(text)-cut)
+		snapshot.Truncated = true
+	}
+	return snapshot
+}
+
+// plainText renders the snapshot for the notification email, below the link.
+func (s *MatchSnapshot) plainText() string {
+	if s == nil {
+		return ""
+	}
+	text := fmt.Sprintf("\n\n--- Copy at match time ---\nTitle: %s\nAuthor: u/%s\nScore: %d", s.Title, s.Author, s.Score)
+	if s.Text != "" {
+		text += "\n\n" + s.Text
+	}
+	return text
+}
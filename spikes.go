@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Keyword Spike Detection ---
+
+// spikeDetection alerts when a keyword matches far more often in a cycle than in the cycles before it
+var spikeDetection = false
+
+// spikeThresholdStddev is how many standard deviations above the rolling mean a cycle count must be
+var spikeThresholdStddev = 3.0
+
+// spikeAlertChannel is where spike alerts go: email, ntfy, teams, pushover or matrix, "" for the regular notifier
+var spikeAlertChannel = ""
+
+// spikeAlertChannels are the valid values of spike_alert_channel
+var spikeAlertChannels = []string{"email", "ntfy", "teams", "pushover", "matrix"}
+
+// spikeWindowCycles is how many past cycles the rolling stats of a keyword cover
+const spikeWindowCycles = 12
+
+// spikeKey identifies the match counts of one keyword in one subreddit of a profile
+type spikeKey struct {
+	profile   string
+	subreddit string
+	keyword   string
+}
+
+// cycleMatches counts the matches of the cycles in progress, until each job checks its subreddits
+var cycleMatches = struct {
+	sync.Mutex
+	counts map[spikeKey]int
+}{counts: map[spikeKey]int{}}
+
+// RollingKeywordStat is the rolling window of a keyword in one subreddit, kept in keyword_stats
+// next to the daily counts (it has no date)
+type RollingKeywordStat struct {
+	Keyword      string    `bson:"keyword"`
+	Subreddit    string    `bson:"subreddit"`
+	Profile      string    `bson:"profile,omitempty"`
+	RecentCycles []int     `bson:"recent_cycles"` // Matches per cycle, oldest first, at most spikeWindowCycles
+	Mean         float64   `bson:"mean"`
+	Stddev       float64   `bson:"stddev"`
+	UpdatedAt    time.Time `bson:"updated_at"`
+}
+
+// countCycleMatches adds matches of found in subreddit to the current cycle. Keywords are counted
+// under their label, without the matched text, and subreddits in lowercase.
+func countCycleMatches(profile, subreddit string, found []string) {
+	if !spikeDetection {
+		return
+	}
+	cycleMatches.Lock()
+	defer cycleMatches.Unlock()
+	for _, keyword := range found {
+		label, _, _ := strings.Cut(keyword, " = ")
+		cycleMatches.counts[spikeKey{profile, strings.ToLower(subreddit), label}]++
+	}
+}
+
+// takeCycleMatches removes and returns the counts of profile in subreddits (lowercase).
+func takeCycleMatches(profile string, subreddits []string) map[spikeKey]int {
+	cycleMatches.Lock()
+	defer cycleMatches.Unlock()
+	taken := map[spikeKey]int{}
+	for key, count := range cycleMatches.counts {
+		if key.profile == profile && slices.Contains(subreddits, key.subreddit) {
+			taken[key] = count
+			delete(cycleMatches.counts, key)
+		}
+	}
+	return taken
+}
+
+// meanStddev returns the mean and population standard deviation of counts.
+func meanStddev(counts []int) (float64, float64) {
+	if len(counts) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, count := range counts {
+		sum += float64(count)
+	}
+	mean := sum / float64(len(counts))
+	variance := 0.0
+	for _, count := range counts {
+		variance += (float64(count) - mean) * (float64(count) - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(counts)))
+}
+
+// spikeThreshold returns the count a cycle must exceed to be a spike. The deviation is at least 1,
+// so a keyword that rarely matches doesn't spike on a couple of matches.
+func spikeThreshold(mean, stddev float64) float64 {
+	return mean + spikeThresholdStddev*math.Max(stddev, 1)
+}
+
+// spikeNotifier returns the notifier of spike_alert_channel, or regular if none is set or the
+// channel isn't configured.
+func spikeNotifier(regular Notifier) Notifier {
+	switch spikeAlertChannel {
+	case "email":
+		return newNotifier(recipientEmail)
+	case "ntfy":
+		if ntfyTopic != "" {
+			return NewNtfyNotifier(ntfyTopic)
+		}
+	case "teams":
+		if teamsWebhookURL != "" {
+			return NewTeamsNotifier(teamsWebhookURL)
+		}
+	case "pushover":
+		if pushoverUserKey != "" && pushoverAppToken != "" {
+			return NewPushoverNotifier(pushoverUserKey)
+		}
+	case "matrix":
+		if matrixRoomID != "" {
+			return NewMatrixNotifier()
+		}
+	}
+	return regular
+}
+
+// warnSpikeChannel warns when spike_alert_channel names a channel that isn't configured.
+func warnSpikeChannel() {
+	if !spikeDetection || spikeAlertChannel == "" || spikeAlertChannel == "email" {
+		return
+	}
+	if spikeNotifier(nil) == nil {
+		fmt.Printf("WARN: spike_alert_channel %s is not configured, spike alerts go to the regular notifier\n", spikeAlertChannel)
+	}
+}
+
+// checkSpikes ends the cycle of subreddits for the spike stats: every keyword with a rolling
+// window or matches this cycle gets the cycle's count appended, and an alert is sent when the
+// count exceeds the mean plus spike_threshold_stddev deviations of a full window. Failed cycles
+// aren't checked, their matches count towards the next one.
+func checkSpikes(ctx context.Context, rules matchRules, subreddits []string, notifier Notifier) {
+	if !spikeDetection || keywordStatsCollection == nil {
+		return
+	}
+	lowered := make([]string, 0, len(subreddits))
+	for _, subreddit := range subreddits {
+		lowered = append(lowered, strings.ToLower(subreddit))
+	}
+	counts := takeCycleMatches(rules.profile, lowered)
+
+	ctxFind, cancelFind := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelFind()
+	cursor, err := keywordStatsCollection.Find(ctxFind, map[string]interface{}{
+		"profile":       profileFilter(rules.profile),
+		"subreddit":     map[string]interface{}{"$in": lowered},
+		"recent_cycles": map[string]interface{}{"$exists": true},
+	})
+	if err != nil {
+		fmt.Printf("Error querying rolling keyword stats%s: %v\n", rules.logTag(), err)
+		return
+	}
+	windows := []RollingKeywordStat{}
+	if err := cursor.All(ctxFind, &windows); err != nil {
+		fmt.Printf("Error reading rolling keyword stats%s: %v\n", rules.logTag(), err)
+		return
+	}
+	history := map[spikeKey][]int{}
+	for _, window := range windows {
+		history[spikeKey{rules.profile, window.Subreddit, window.Keyword}] = window.RecentCycles
+	}
+	for key := range counts {
+		if _, ok := history[key]; !ok {
+			history[key] = nil
+		}
+	}
+
+	now := time.Now()
+	for key, previous := range history {
+		count := counts[key]
+		filter := map[string]interface{}{
+			"keyword": key.keyword, "subreddit": key.subreddit, "profile": profileFilter(rules.profile),
+			"recent_cycles": map[string]interface{}{"$exists": true},
+		}
+		recent := append(previous, count)
+		if len(recent) > spikeWindowCycles {
+			recent = recent[len(recent)-spikeWindowCycles:]
+		}
+		mean, stddev := meanStddev(recent)
+
+		ctxUpdate, cancelUpdate := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err = keywordStatsCollection.UpdateOne(ctxUpdate, filter,
+			map[string]interface{}{"$set": map[string]interface{}{
+				"recent_cycles": recent, "mean": mean, "stddev": stddev, "updated_at": now,
+			}},
+			options.Update().SetUpsert(true))
+		cancelUpdate()
+		if err != nil {
+			fmt.Printf("Error updating rolling stats for %q in r/%s: %v\n", key.keyword, key.subreddit, err)
+		}
+
+		if len(previous) < spikeWindowCycles {
+			continue // Not enough history yet
+		}
+		previousMean, previousStddev := meanStddev(previous)
+		threshold := spikeThreshold(previousMean, previousStddev)
+		if float64(count) <= threshold {
+			continue
+		}
+		fmt.Printf("Spike: %d match(es) of %q in r/%s this cycle, mean %.1f, stddev %.1f%s\n",
+			count, key.keyword, key.subreddit, previousMean, previousStddev, rules.logTag())
+		subject := fmt.Sprintf("Reddit Keyword Spike: %q in r/%s", key.keyword, key.subreddit)
+		if rules.profile != "" {
+			subject = "[" + rules.profile + "] " + subject
+		}
+		body := fmt.Sprintf("%d match(es) of %q in r/%s this cycle, against a mean of %.1f (stddev %.1f) over the last %d cycles.\n"+
+			"The spike threshold is %.1f (spike_threshold_stddev %g).\nhttps://www.reddit.com/r/%s/new",
+			count, key.keyword, key.subreddit, previousMean, previousStddev, len(previous), threshold, spikeThresholdStddev, key.subreddit)
+		if err := spikeNotifier(notifier).Notify(subject, body); err != nil {
+			fmt.Printf("Error sending spike alert for %q in r/%s: %v%s\n", key.keyword, key.subreddit, err, rules.logTag())
+		}
+	}
+}
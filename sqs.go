@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// --- Amazon SQS ---
+
+// SQS settings from the config file, match events are sent to the queue when sqsQueueURL is set
+var sqsQueueURL = ""
+var sqsRegion = "" // Defaults to the region of the queue URL, then AWS_REGION
+
+// sqsMaxBatch is the most messages SendMessageBatch takes
+const sqsMaxBatch = 10
+
+// sqsTimeout bounds an SQS call, the SDK's own retries included
+const sqsTimeout = 30 * time.Second
+
+// sqsAPI is the part of the SQS client the notifier uses
+type sqsAPI interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+// SQSNotifier sends a MatchEvent for every alert to an SQS queue, with the AWS SDK's credential
+// chain (environment, shared files, SSO, container or instance role)
+type SQSNotifier struct {
+	queueURL string
+	endpoint string // Scheme and host of the queue URL, so local emulators work too
+	region   string
+	fifo     bool
+
+	mu     sync.Mutex
+	client sqsAPI // Created on first use, see sqsClient
+}
+
+// NewSQSNotifier returns a Notifier sending to the configured SQS queue.
+func NewSQSNotifier() *SQSNotifier {
+	n := &SQSNotifier{queueURL: sqsQueueURL, region: sqsRegion, fifo: strings.HasSuffix(sqsQueueURL, ".fifo")}
+	if u, err := url.Parse(sqsQueueURL); err == nil {
+		n.endpoint = u.Scheme + "://" + u.Host
+		if n.region == "" {
+			n.region = sqsURLRegion(u.Host)
+		}
+	}
+	return n
+}
+
+// sqsURLRegion returns the region of an SQS host such as sqs.eu-west-1.amazonaws.com, "" for
+// other hosts (local emulators, legacy queue.amazonaws.com).
+func sqsURLRegion(host string) string {
+	parts := strings.Split(host, ".")
+	if len(parts) >= 4 && parts[0] == "sqs" {
+		return parts[1]
+	}
+	return ""
+}
+
+// sqsClient returns the SQS client, loading the AWS config the first time. A failed load is
+// retried on the next send.
+func (n *SQSNotifier) sqsClient(ctx context.Context) (sqsAPI, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.client != nil {
+		return n.client, nil
+	}
+	cfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n.client = sqs.NewFromConfig(cfg, func(o *sqs.Options) {
+		if n.region != "" {
+			o.Region = n.region
+		}
+		if n.endpoint != "" {
+			o.BaseEndpoint = aws.String(n.endpoint)
+		}
+	})
+	return n.client, nil
+}
+
+// entry returns the message of alert. On FIFO queues the subreddit is the message group, so the
+// matches of a subreddit stay in order, and the permalink dedupes resends within five minutes.
+func (n *SQSNotifier) entry(alert Alert) (types.SendMessageBatchRequestEntry, error) {
+	body, err := json.Marshal(MatchEvent{SchemaVersion: matchEventSchemaVersion, NotificationData: notificationData(alert)})
+	if err != nil {
+		return types.SendMessageBatchRequestEntry{}, err
+	}
+	entry := types.SendMessageBatchRequestEntry{MessageBody: aws.String(string(body))}
+	if n.fifo {
+		entry.MessageGroupId = aws.String(strings.ToLower(alert.Subreddit))
+		entry.MessageDeduplicationId = aws.String(matchEventID(alert))
+	}
+	return entry, nil
+}
+
+// Notify does nothing: the queue carries match events only, digests and summaries go to the
+// other channels.
+func (n *SQSNotifier) Notify(subject, body string) error {
+	return nil
+}
+
+// NotifyAlert sends the match with SendMessage. The SDK retries throttling and server errors.
+func (n *SQSNotifier) NotifyAlert(alert Alert) error {
+	entry, err := n.entry(alert)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sqsTimeout)
+	defer cancel()
+	client, err := n.sqsClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to send to SQS: %w", err)
+	}
+	_, err = client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:               aws.String(n.queueURL),
+		MessageBody:            entry.MessageBody,
+		MessageGroupId:         entry.MessageGroupId,
+		MessageDeduplicationId: entry.MessageDeduplicationId,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send to SQS: %w", err)
+	}
+	fmt.Println("Match event sent to SQS")
+	return nil
+}
+
+// NotifyBatch sends the matches of a batched cycle with SendMessageBatch, 10 per request. Entries
+// SQS failed on its side are retried, the ones it refused (sender fault) are not.
+func (n *SQSNotifier) NotifyBatch(subject, body string, alerts []Alert) error {
+	errs := []error{}
+	for start := 0; start < len(alerts); start += sqsMaxBatch {
+		pending := map[string]types.SendMessageBatchRequestEntry{}
+		for i, alert := range alerts[start:min(start+sqsMaxBatch, len(alerts))] {
+			entry, err := n.entry(alert)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			entry.Id = aws.String(strconv.Itoa(i))
+			pending[*entry.Id] = entry
+		}
+		err := withRetry("SQS batch send", func() error {
+			request := &sqs.SendMessageBatchInput{QueueUrl: aws.String(n.queueURL)}
+			for _, id := range sortedKeys(pending) {
+				request.Entries = append(request.Entries, pending[id])
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), sqsTimeout)
+			defer cancel()
+			client, err := n.sqsClient(ctx)
+			if err != nil {
+				return err
+			}
+			response, err := client.SendMessageBatch(ctx, request)
+			if err != nil {
+				return err // Already retried by the SDK
+			}
+			retry := map[string]types.SendMessageBatchRequestEntry{}
+			var lastErr error
+			for _, failed := range response.Failed {
+				err := fmt.Errorf("%s: %s", aws.ToString(failed.Code), aws.ToString(failed.Message))
+				if failed.SenderFault {
+					errs = append(errs, fmt.Errorf("SQS refused a message: %w", err))
+				} else if entry, ok := pending[aws.ToString(failed.Id)]; ok {
+					retry[aws.ToString(failed.Id)], lastErr = entry, err
+				}
+			}
+			pending = retry
+			if len(pending) > 0 {
+				return &retryableError{err: fmt.Errorf("%d message(s) failed: %w", len(pending), lastErr)}
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to send to SQS: %w", err)
+	}
+	fmt.Printf("%d match event(s) sent to SQS\n", len(alerts))
+	return nil
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// --- Match Analytics ---
+
+// statsWindows are the selectable analytics windows, each compared with the window before it
+var statsWindows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// defaultStatsTopHours is how many of the busiest hours of day are reported by default
+const defaultStatsTopHours = 5
+
+// CountDelta is the number of matches of a keyword or subreddit in a window and the one before it
+type CountDelta struct {
+	Name     string `bson:"_id" json:"name"`
+	Count    int64  `bson:"current" json:"count"`
+	Previous int64  `bson:"previous" json:"previous"`
+	Delta    int64  `bson:"-" json:"delta"` // Count - Previous
+}
+
+// HourCount is the number of matches in one hour of day (local time) over a window
+type HourCount struct {
+	Hour  int   `bson:"_id" json:"hour"`
+	Count int64 `bson:"count" json:"count"`
+}
+
+// RemovalRate is how many checked matches of a subreddit in a window were removed or deleted
+type RemovalRate struct {
+	Subreddit    string  `bson:"_id" json:"subreddit"`
+	Checked      int64   `bson:"checked" json:"checked"` // Matches with a status, see status_check_days
+	Removed      int64   `bson:"removed" json:"removed"`
+	Deleted      int64   `bson:"deleted" json:"deleted"`
+	RemovedShare float64 `bson:"-" json:"removed_share"` // Removed / Checked
+}
+
+// MatchStats are the analytics of a window, the body of GET /api/stats
+type MatchStats struct {
+	Window        string       `json:"window"`
+	Since         time.Time    `json:"since"`
+	Until         time.Time    `json:"until"`
+	Total         int64        `json:"total"`
+	PreviousTotal int64        `json:"previous_total"`
+	Keywords      []CountDelta `json:"keywords"`
+	Subreddits    []CountDelta `json:"subreddits"`
+	BusiestHours  []HourCount  `json:"busiest_hours"`
+
+	Removals []RemovalRate `json:"removals"` // Per subreddit, most removals first
+}
+
+// countsPipeline groups the matches of the window and the one before it by field, counting each
+// window separately. Keyword labels that carry the matched text ("re:... = \"text\"") are counted
+// under their label.
+func countsPipeline(filter map[string]interface{}, field string, since time.Time) mongo.Pipeline {
+	inWindow := func(current bool) bson.M {
+		comparison := "$gte"
+		if !current {
+			comparison = "$lt"
+		}
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{comparison: bson.A{"$processed_at", since}}, 1, 0}}}
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+	key := interface{}("$" + field)
+	if field == "keywords" {
+		pipeline = append(pipeline, bson.D{{Key: "$unwind", Value: "$keywords"}})
+		key = bson.M{"$arrayElemAt": bson.A{bson.M{"$split": bson.A{"$keywords", " = "}}, 0}}
+	}
+	return append(pipeline,
+		bson.D{{Key: "$group", Value: bson.M{"_id": key, "current": inWindow(true), "previous": inWindow(false)}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "current", Value: -1}, {Key: "previous", Value: -1}, {Key: "_id", Value: 1}}}},
+	)
+}
+
+// aggregateCounts runs a counts pipeline and fills in the deltas.
+func aggregateCounts(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) ([]CountDelta, error) {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	counts := []CountDelta{}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	for i := range counts {
+		counts[i].Delta = counts[i].Count - counts[i].Previous
+	}
+	return counts, nil
+}
+
+// queryMatchStats aggregates the matches of window (a statsWindows key) ending at now: counts per
+// keyword and subreddit against the previous window, the topHours busiest hours of day and the
+// share of checked matches removed per subreddit.
+// Everything is counted by Mongo, no match is loaded.
+func queryMatchStats(ctx context.Context, collection *mongo.Collection, window string, now time.Time, topHours int) (MatchStats, error) {
+	length, ok := statsWindows[window]
+	if !ok {
+		return MatchStats{}, fmt.Errorf("invalid window %q (must be 24h, 7d or 30d)", window)
+	}
+	since := now.Add(-length)
+	stats := MatchStats{Window: window, Since: since, Until: now}
+
+	// Both windows, so one pass counts current and previous
+	filter := matchesFilter(since.Add(-length), "", "")
+	filter["processed_at"] = map[string]interface{}{"$gte": since.Add(-length), "$lt": now}
+
+	var err error
+	if stats.Keywords, err = aggregateCounts(ctx, collection, countsPipeline(filter, "keywords", since)); err != nil {
+		return stats, fmt.Errorf("error counting matches per keyword: %w", err)
+	}
+	if stats.Subreddits, err = aggregateCounts(ctx, collection, countsPipeline(filter, "subreddit", since)); err != nil {
+		return stats, fmt.Errorf("error counting matches per subreddit: %w", err)
+	}
+	for _, sub := range stats.Subreddits { // Every match has one subreddit
+		stats.Total += sub.Count
+		stats.PreviousTotal += sub.Previous
+	}
+
+	// Hours are bucketed at the current UTC offset, so a DST change within the window shifts some by one
+	currentFilter := matchesFilter(since, "", "")
+	currentFilter["processed_at"] = map[string]interface{}{"$gte": since, "$lt": now}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: currentFilter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$hour": bson.M{"date": "$processed_at", "timezone": now.Format("-07:00")}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: topHours}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return stats, fmt.Errorf("error counting matches per hour: %w", err)
+	}
+	stats.BusiestHours = []HourCount{}
+	if err := cursor.All(ctx, &stats.BusiestHours); err != nil {
+		return stats, fmt.Errorf("error counting matches per hour: %w", err)
+	}
+
+	// Only matches the status checks have seen
+	statusCount := func(status string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", status}}, 1, 0}}}
+	}
+	currentFilter["status"] = map[string]interface{}{"$exists": true}
+	pipeline = mongo.Pipeline{
+		{{Key: "$match", Value: currentFilter}},
+		{{Key: "$group", Value: bson.M{
+			"_id": "$subreddit", "checked": bson.M{"$sum": 1}, "removed": statusCount(statusRemoved), "deleted": statusCount(statusDeleted),
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "removed", Value: -1}, {Key: "checked", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	if cursor, err = collection.Aggregate(ctx, pipeline); err != nil {
+		return stats, fmt.Errorf("error counting removed matches: %w", err)
+	}
+	stats.Removals = []RemovalRate{}
+	if err := cursor.All(ctx, &stats.Removals); err != nil {
+		return stats, fmt.Errorf("error counting removed matches: %w", err)
+	}
+	for i := range stats.Removals {
+		stats.Removals[i].RemovedShare = float64(stats.Removals[i].Removed) / float64(stats.Removals[i].Checked)
+	}
+	return stats, nil
+}
+
+// formatDelta renders a change against the previous window, e.g. "+3 (+50%)", or "+2 (new)" after an empty window.
+func formatDelta(count, previous int64) string {
+	delta := count - previous
+	if previous == 0 {
+		if count == 0 {
+			return "0"
+		}
+		return fmt.Sprintf("%+d (new)", delta)
+	}
+	return fmt.Sprintf("%+d (%+.0f%%)", delta, float64(delta)*100/float64(previous))
+}
+
+// formatMatchStats renders stats as plain text, for the stats subcommand and summary emails.
+func formatMatchStats(stats MatchStats) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Matches in the last %s: %d, %s vs the previous %s\n", stats.Window, stats.Total,
+		formatDelta(stats.Total, stats.PreviousTotal), stats.Window)
+
+	for _, section := range []struct {
+		title  string
+		counts []CountDelta
+		prefix string
+	}{{"Keywords", stats.Keywords, ""}, {"Subreddits", stats.Subreddits, "r/"}} {
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		if len(section.counts) == 0 {
+			b.WriteString("  none\n")
+			continue
+		}
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		for _, count := range section.counts {
+			fmt.Fprintf(tw, "  %s%s\t%d\t%s\n", section.prefix, count.Name, count.Count, formatDelta(count.Count, count.Previous))
+		}
+		tw.Flush()
+	}
+
+	b.WriteString("\nBusiest hours of day:\n")
+	if len(stats.BusiestHours) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, hour := range stats.BusiestHours {
+		fmt.Fprintf(&b, "  %02d:00-%02d:00  %d\n", hour.Hour, (hour.Hour+1)%24, hour.Count)
+	}
+
+	b.WriteString("\nRemoved matches:\n")
+	if len(stats.Removals) == 0 {
+		b.WriteString("  none checked\n")
+	}
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, removal := range stats.Removals {
+		fmt.Fprintf(tw, "  r/%s\t%d of %d removed (%.0f%%)\t%d deleted\n",
+			removal.Subreddit, removal.Removed, removal.Checked, removal.RemovedShare*100, removal.Deleted)
+	}
+	tw.Flush()
+	return b.String()
+}
+
+// handleStats serves GET /api/stats?window=24h|7d|30d&top=N.
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if processedItemsCollection == nil {
+		http.Error(w, "MongoDB is not connected", http.StatusServiceUnavailable)
+		return
+	}
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = "7d"
+	}
+	if _, ok := statsWindows[window]; !ok {
+		http.Error(w, fmt.Sprintf("invalid window %q (must be 24h, 7d or 30d)", window), http.StatusBadRequest)
+		return
+	}
+	top := defaultStatsTopHours
+	if value := r.URL.Query().Get("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 24 {
+			http.Error(w, "top must be between 1 and 24", http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	stats, err := queryMatchStats(ctx, processedItemsCollection, window, time.Now(), top)
+	if err != nil {
+		fmt.Printf("Error serving /api/stats: %v\n", err)
+		http.Error(w, "failed to compute stats", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// runStats prints the match analytics of a window. It only reads from Mongo. Returns the process exit code.
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	window := fs.String("window", "7d", "window to report: 24h, 7d or 30d")
+	top := fs.Int("top", defaultStatsTopHours, "number of busiest hours of day to show")
+	asJSON := fs.Bool("json", false, "print JSON instead of text")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if _, ok := statsWindows[*window]; !ok {
+		fmt.Printf("Invalid --window %q, must be 24h, 7d or 30d\n", *window)
+		return 2
+	}
+	if *top < 1 || *top > 24 {
+		fmt.Println("--top must be between 1 and 24")
+		return 2
+	}
+	if mongoURI == "" {
+		fmt.Println("FATAL: MONGODB_URI environment variable must be set.")
+		return 1
+	}
+
+	client, err := connectMongo()
+	if err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return 1
+	}
+	defer client.Disconnect(context.Background())
+	collection := client.Database(mongoDatabaseName).Collection(processedItemsCollectionName,
+		options.Collection().SetReadPreference(readpref.SecondaryPreferred()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	stats, err := queryMatchStats(ctx, collection, *window, time.Now(), *top)
+	if err != nil {
+		fmt.Printf("Error computing stats: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(stats); err != nil {
+			fmt.Printf("Error writing stats: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Print(formatMatchStats(stats))
+	return 0
+}
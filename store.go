@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// --- Processed Item Stores ---
+
+// storeBoltPath is the bbolt database processed items are kept in instead of MongoDB, when set
+var storeBoltPath = os.Getenv("STORE_BOLT_PATH")
+
+// storeBoltTTL is how long processed items stay in the bolt database
+var storeBoltTTL = time.Duration(envInt("STORE_BOLT_TTL_DAYS", 30)) * 24 * time.Hour
+
+// processedItemsElsewhere reports whether a store other than MongoDB is set to keep the processed
+// items, so MongoDB is optional.
+func processedItemsElsewhere() bool {
+	return storeFilePath != "" || storeBoltPath != "" || dynamoDBTableName != ""
+}
+
+// maintainedStore is a local Store with a background task (compaction, expiry) and a file to close
+type maintainedStore interface {
+	Store
+	runCompaction(ctx context.Context)
+	close()
+}
+
+// Store records which posts/comments have already been processed, keyed by permalink
+type Store interface {
+	Has(ctx context.Context, permalink string) (bool, error)
+	Mark(ctx context.Context, item ProcessedItem) error
+	// FindDuplicate returns the earliest item with contentHash processed since since, or the post
+	// named parentName (a crosspost's original) at any time. It returns nil if there is none.
+	FindDuplicate(ctx context.Context, contentHash, parentName string, since time.Time) (*ProcessedItem, error)
+	// AddAlsoPostedIn appends note (e.g. "r/X") to the also_posted_in list of the item.
+	AddAlsoPostedIn(ctx context.Context, permalink, note string) error
+}
+
+// ErrAlreadyProcessed is returned by Store.Mark when the permalink was already stored
+var ErrAlreadyProcessed = errors.New("item already processed")
+
+// MongoStore is a Store backed by the processed_items MongoDB collection
+type MongoStore struct {
+	collection *mongo.Collection
+	profile    string // Stamped on marked items, and scopes lookups with per-profile dedupe
+}
+
+// NewMongoStore returns a Store using the given collection.
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection}
+}
+
+// Has reports whether a document with this permalink exists.
+func (s *MongoStore) Has(ctx context.Context, permalink string) (bool, error) {
+	var result struct{} // We only care if a document is found, not its content
+	// FindOne returns ErrNoDocuments if not found
+	err := s.withReconnect(ctx, func() error {
+		return s.collection.FindOne(ctx, s.scope(map[string]interface{}{"permalink": permalink})).Decode(&result)
+	})
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Mark inserts the item. A duplicate key error (code 11000) is reported as ErrAlreadyProcessed.
+func (s *MongoStore) Mark(ctx context.Context, item ProcessedItem) error {
+	item.Profile = s.profile
+	err := s.withReconnect(ctx, func() error {
+		_, err := s.collection.InsertOne(ctx, item)
+		return err
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadyProcessed
+	}
+	return err
+}
+
+// FindDuplicate queries by content_hash within the window or by post_name, earliest first.
+// Items that are duplicates themselves are skipped so notes go to the match that alerted.
+func (s *MongoStore) FindDuplicate(ctx context.Context, contentHash, parentName string, since time.Time) (*ProcessedItem, error) {
+	or := []interface{}{}
+	if contentHash != "" {
+		or = append(or, map[string]interface{}{"content_hash": contentHash, "processed_at": map[string]interface{}{"$gte": since}})
+	}
+	if parentName != "" {
+		or = append(or, map[string]interface{}{"post_name": parentName})
+	}
+	if len(or) == 0 {
+		return nil, nil
+	}
+	filter := s.scope(map[string]interface{}{"$or": or, "duplicate_of": map[string]interface{}{"$exists": false}})
+	var item ProcessedItem
+	err := s.withReconnect(ctx, func() error {
+		return s.collection.FindOne(ctx, filter, options.FindOne().SetSort(map[string]interface{}{"processed_at": 1})).Decode(&item)
+	})
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// AddAlsoPostedIn adds note to the item's also_posted_in set.
+func (s *MongoStore) AddAlsoPostedIn(ctx context.Context, permalink, note string) error {
+	return s.withReconnect(ctx, func() error {
+		_, err := s.collection.UpdateOne(ctx,
+			s.scope(map[string]interface{}{"permalink": permalink}),
+			map[string]interface{}{"$addToSet": map[string]interface{}{"also_posted_in": note}})
+		return err
+	})
+}
+
+// Reconnect settings for MongoStore operations failing with network errors
+const (
+	mongoReconnectAttempts  = 3
+	mongoReconnectBaseDelay = time.Second
+)
+
+// mongoReconnects counts reconnection attempts, reported by GET /api/status
+var mongoReconnects atomic.Int64
+
+// withReconnect runs op, and if it fails with a network error (including command errors the
+// server labels NetworkError) reconnects to MongoDB (up to
+// mongoReconnectAttempts times with exponential backoff) and runs it again. A deadline hit while
+// reconnecting is returned immediately.
+func (s *MongoStore) withReconnect(ctx context.Context, op func() error) error {
+	err := op()
+	if !mongo.IsNetworkError(err) {
+		return err
+	}
+	delay := mongoReconnectBaseDelay
+	for attempt := 1; attempt <= mongoReconnectAttempts; attempt++ {
+		fmt.Printf("WARN: MongoDB network error (%v), reconnecting (attempt %d/%d)\n", err, attempt, mongoReconnectAttempts)
+		mongoReconnects.Add(1)
+		pingErr := s.collection.Database().Client().Ping(ctx, readpref.Primary())
+		if errors.Is(pingErr, context.DeadlineExceeded) {
+			return pingErr
+		}
+		if pingErr == nil {
+			if err = op(); !mongo.IsNetworkError(err) {
+				return err
+			}
+		} else {
+			err = pingErr
+		}
+		if attempt == mongoReconnectAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("MongoDB unreachable after %d reconnect attempts: %w", mongoReconnectAttempts, err)
+}
+
+// scope restricts filter to the store's profile when profiles dedupe separately.
+func (s *MongoStore) scope(filter map[string]interface{}) map[string]interface{} {
+	if profileDedupe == "profile" {
+		filter["profile"] = profileFilter(s.profile)
+	}
+	return filter
+}
+
+// profileFilter matches the profile field, items of the top-level config have none.
+func profileFilter(profile string) interface{} {
+	if profile == "" {
+		return nil // Matches a missing field
+	}
+	return profile
+}
+
+// profileStore returns the store a profile's jobs use: a MongoStore stamping the profile on items.
+// Other stores are shared as they are, deduping globally.
+func profileStore(store Store, profile string) Store {
+	if s, ok := store.(*MongoStore); ok {
+		return &MongoStore{collection: s.collection, profile: profile}
+	}
+	return store
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// --- Store Outage Buffering ---
+
+// storeOutageThreshold consecutive store errors put the store in outage mode: items are held
+// without querying it until a ping succeeds
+const storeOutageThreshold = 3
+
+// storeOutageBufferSize bounds the items held in memory during an outage. When it is full the
+// oldest item is dropped, a missed match being better than running out of memory.
+const storeOutageBufferSize = 1000
+
+// heldItem is an item that couldn't be checked or recorded while the store was failing
+type heldItem struct {
+	profile string         // Profile the item was fetched for, or whose store mark goes to
+	post    *Post          // A post to process again
+	monitor *SearchMonitor // The search monitor that found post, nil for listings
+	comment *Comment       // A comment to process again
+	mark    *ProcessedItem // An item processed (and possibly notified) but not recorded
+	store   Store          // Store mark goes to
+}
+
+// key identifies the item in the buffer, an item fetched again during the outage is held once.
+func (h heldItem) key() string {
+	switch {
+	case h.mark != nil:
+		return markKey(h.profile, h.mark.Permalink)
+	case h.post != nil:
+		return h.profile + " post " + h.post.Permalink
+	default:
+		return h.profile + " comment " + h.comment.Permalink
+	}
+}
+
+// markKey is the key of a held record of permalink in profile's store.
+func markKey(profile, permalink string) string {
+	return profile + " mark " + permalink
+}
+
+// describe names the item in logs.
+func (h heldItem) describe() string {
+	switch {
+	case h.mark != nil:
+		return "record of " + h.mark.Permalink
+	case h.post != nil:
+		return "post " + h.post.Permalink
+	default:
+		return "comment " + h.comment.Permalink
+	}
+}
+
+// storeOutageBuffer tracks the health of the store as seen by processing, and holds the items
+// of failed lookups and inserts until it recovers
+type storeOutageBuffer struct {
+	mu       sync.Mutex
+	failures int  // Consecutive store errors
+	down     bool // failures reached storeOutageThreshold and no ping succeeded since
+	items    []heldItem
+	keys     map[string]bool
+	dropped  int // Items dropped since the outage started
+}
+
+// storeOutage buffers the items of the running monitor
+var storeOutage = &storeOutageBuffer{keys: map[string]bool{}}
+
+// failed counts a store error, entering outage mode at storeOutageThreshold.
+func (b *storeOutageBuffer) failed(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= storeOutageThreshold && !b.down {
+		b.down = true
+		fmt.Printf("WARN: Store failed %d times in a row (%v), holding items in memory until it recovers\n", b.failures, err)
+	}
+}
+
+// succeeded resets the error count after a store operation worked.
+func (b *storeOutageBuffer) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.down {
+		b.failures = 0
+	}
+}
+
+// isDown reports whether the store is in outage mode.
+func (b *storeOutageBuffer) isDown() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.down
+}
+
+// held returns the number of items held.
+func (b *storeOutageBuffer) held() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.items)
+}
+
+// hold adds item to the buffer, dropping the oldest item if it is full.
+func (b *storeOutageBuffer) hold(item heldItem) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := item.key()
+	if b.keys[key] {
+		return
+	}
+	if len(b.items) >= storeOutageBufferSize {
+		oldest := b.items[0]
+		b.items = b.items[1:]
+		delete(b.keys, oldest.key())
+		b.dropped++
+		fmt.Printf("!!! STORE OUTAGE BUFFER FULL (%d items): dropped the %s, it will not be processed (%d dropped so far) !!!\n",
+			storeOutageBufferSize, oldest.describe(), b.dropped)
+	}
+	b.items = append(b.items, item)
+	b.keys[key] = true
+}
+
+// holdPost holds a post of the listings of profile, or of monitor if not nil.
+func (b *storeOutageBuffer) holdPost(profile string, post Post, monitor *SearchMonitor) {
+	post.Held = true
+	b.hold(heldItem{profile: profile, post: &post, monitor: monitor})
+}
+
+// holdComment holds a comment of profile.
+func (b *storeOutageBuffer) holdComment(profile string, comment Comment) {
+	comment.Held = true
+	b.hold(heldItem{profile: profile, comment: &comment})
+}
+
+// holdMark holds an item to record in store.
+func (b *storeOutageBuffer) holdMark(store Store, item ProcessedItem) {
+	b.hold(heldItem{profile: storeProfile(store), mark: &item, store: store})
+}
+
+// marking reports whether a record of permalink is held for profile: it was processed, checking
+// the store again would process it twice. With global dedupe any profile's record counts.
+func (b *storeOutageBuffer) marking(profile, permalink string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.keys[markKey(profile, permalink)] {
+		return true
+	}
+	if profileDedupe == "profile" {
+		return false
+	}
+	for _, item := range b.items {
+		if item.mark != nil && item.mark.Permalink == permalink {
+			return true
+		}
+	}
+	return false
+}
+
+// profiledStore is a Store scoped to a profile, see profileStore
+type profiledStore interface {
+	storeProfile() string
+}
+
+// storeProfile returns the profile store belongs to, "" for the top-level config and stores
+// shared by every profile.
+func storeProfile(store Store) string {
+	if s, ok := store.(profiledStore); ok {
+		return s.storeProfile()
+	}
+	return ""
+}
+
+// take removes and returns the held records and the held items of profile.
+func (b *storeOutageBuffer) take(profile string) (marks, items []heldItem) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	kept := b.items[:0]
+	for _, item := range b.items {
+		switch {
+		case item.mark != nil:
+			marks = append(marks, item)
+		case item.profile == profile:
+			items = append(items, item)
+		default:
+			kept = append(kept, item)
+			continue
+		}
+		delete(b.keys, item.key())
+	}
+	clear(b.items[len(kept):])
+	b.items = kept
+	return marks, items
+}
+
+// recovered pings store if it is in outage mode and leaves outage mode if the ping succeeds.
+// It reports whether the store is usable.
+func (b *storeOutageBuffer) recovered(store Store) bool {
+	if !b.isDown() {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := pingStore(ctx, store); err != nil {
+		fmt.Printf("WARN: Store still unavailable (%v), %d item(s) held\n", err, b.held())
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.down, b.failures = false, 0
+	if b.dropped > 0 {
+		fmt.Printf("Error: Store recovered, but %d held item(s) were dropped during the outage and not processed\n", b.dropped)
+	} else {
+		fmt.Printf("Info: Store recovered, replaying %d held item(s)\n", len(b.items))
+	}
+	b.dropped = 0
+	return true
+}
+
+// storePinger is a Store that can check its connection without a lookup
+type storePinger interface {
+	Ping(ctx context.Context) error
+}
+
+// pingStore checks that store answers, with a lookup if it can't ping.
+func pingStore(ctx context.Context, store Store) error {
+	if pinger, ok := store.(storePinger); ok {
+		return pinger.Ping(ctx)
+	}
+	_, err := store.Has(ctx, "")
+	return err
+}
+
+// storeProfile returns the profile stamped on the store's items.
+func (s *MongoStore) storeProfile() string {
+	return s.profile
+}
+
+// Ping checks the connection to MongoDB, reconnecting on network errors.
+func (s *MongoStore) Ping(ctx context.Context) error {
+	return s.withReconnect(ctx, func() error {
+		return s.collection.Database().Client().Ping(ctx, readpref.Primary())
+	})
+}
+
+// replayHeldItems records the held records and processes the held items of rules' profile, once
+// the store is usable again. Items that fail again are held again.
+func replayHeldItems(store Store, notifier Notifier, rules matchRules) {
+	if !storeOutage.recovered(store) || storeOutage.held() == 0 {
+		return
+	}
+	marks, items := storeOutage.take(rules.profile)
+	if len(marks)+len(items) == 0 {
+		return
+	}
+	fmt.Printf("Replaying %d record(s) and %d item(s) held while the store was failing%s\n", len(marks), len(items), rules.logTag())
+	// Records first, so the items already notified are found processed
+	for _, held := range marks {
+		markItem(held.store, *held.mark)
+	}
+	posts, comments := []Post{}, []Comment{}
+	searches := map[SearchMonitor][]Post{}
+	for _, held := range items {
+		switch {
+		case held.monitor != nil:
+			searches[*held.monitor] = append(searches[*held.monitor], *held.post)
+		case held.post != nil:
+			posts = append(posts, *held.post)
+		default:
+			comments = append(comments, *held.comment)
+		}
+	}
+	processPosts(store, notifier, rules, posts)
+	for monitor, results := range searches {
+		processSearchResults(store, notifier, rules, monitor, results)
+	}
+	processComments(store, notifier, rules, comments)
+}
+
+// checkedStore looks up permalink in store for processing. It returns processed=true for items
+// that must not be processed now: already recorded, or to be held because the store is failing.
+// hold is called to hold the item when the store fails or is in outage mode.
+func checkedStore(store Store, permalink string, hold func()) (processed bool, err error) {
+	if storeOutage.marking(storeProfile(store), permalink) {
+		return true, nil
+	}
+	if storeOutage.isDown() {
+		hold()
+		return true, nil
+	}
+	ctxFind, cancelFind := context.WithTimeout(context.Background(), 5*time.Second)
+	processed, err = store.Has(ctxFind, permalink)
+	cancelFind()
+	if err != nil {
+		storeOutage.failed(err)
+		hold()
+		return false, err
+	}
+	storeOutage.succeeded()
+	return processed, nil
+}
+
+// markFailed holds the record of item after store.Mark failed with err, unless it was already there.
+func markFailed(store Store, item ProcessedItem, err error) {
+	if errors.Is(err, ErrAlreadyProcessed) {
+		return
+	}
+	storeOutage.failed(err)
+	storeOutage.holdMark(store, item)
+}
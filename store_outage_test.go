@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// flakyStore is a MemoryStore of profile that fails every call while down
+type flakyStore struct {
+	*MemoryStore
+	profile string
+	down    bool
+}
+
+func (s *flakyStore) storeProfile() string { return s.profile }
+
+func (s *flakyStore) Has(ctx context.Context, permalink string) (bool, error) {
+	if s.down {
+		return false, errors.New("store unreachable")
+	}
+	return s.MemoryStore.Has(ctx, permalink)
+}
+
+func (s *flakyStore) Mark(ctx context.Context, item ProcessedItem) error {
+	if s.down {
+		return errors.New("store unreachable")
+	}
+	return s.MemoryStore.Mark(ctx, item)
+}
+
+// alertRecorder is a notifier keeping the alerts it was sent
+type alertRecorder struct {
+	alerts []Alert
+}
+
+func (r *alertRecorder) Notify(subject, body string) error { return nil }
+
+func (r *alertRecorder) NotifyAlert(alert Alert) error {
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+// withStoreOutage gives the test an empty storeOutage and profileDedupe set to dedupe.
+func withStoreOutage(t *testing.T, dedupe string) {
+	oldOutage, oldDedupe := storeOutage, profileDedupe
+	storeOutage, profileDedupe = &storeOutageBuffer{keys: map[string]bool{}}, dedupe
+	t.Cleanup(func() { storeOutage, profileDedupe = oldOutage, oldDedupe })
+}
+
+func TestReplayHeldSearchResultsUseTheProfileRules(t *testing.T) {
+	withStoreOutage(t, "profile")
+	store := &flakyStore{MemoryStore: NewMemoryStore(), profile: "widgets", down: true}
+	notifier := &alertRecorder{}
+	rules := matchRules{profile: "widgets", keywords: []KeywordSpec{{Keyword: "widget"}}, minMatches: 1}
+	monitor := SearchMonitor{Query: "gadgets"}
+	post := Post{Title: "Selling a widget", Subreddit: "market", Permalink: "/r/market/comments/1/widget/", Listing: "search"}
+
+	for range storeOutageThreshold {
+		processSearchResults(store, notifier, rules, monitor, []Post{post})
+	}
+	if !storeOutage.isDown() || storeOutage.held() != 1 {
+		t.Fatalf("outage %v with %d held item(s), want the result held once", storeOutage.isDown(), storeOutage.held())
+	}
+
+	store.down = false
+	replayHeldItems(store, notifier, rules)
+	if storeOutage.isDown() || storeOutage.held() != 0 {
+		t.Fatalf("outage %v with %d held item(s) after recovery, want none", storeOutage.isDown(), storeOutage.held())
+	}
+	if len(notifier.alerts) != 1 || !slices.Equal(notifier.alerts[0].Keywords, []string{"widget"}) {
+		t.Fatalf("alerts = %+v, want one for the profile's keyword widget", notifier.alerts)
+	}
+	if processed, _ := store.Has(context.Background(), post.Permalink); !processed {
+		t.Error("replayed result wasn't recorded")
+	}
+}
+
+func TestHeldRecordIsScopedToItsProfile(t *testing.T) {
+	withStoreOutage(t, "profile")
+	storeA := &flakyStore{MemoryStore: NewMemoryStore(), profile: "a", down: true}
+	storeB := &flakyStore{MemoryStore: NewMemoryStore(), profile: "b"}
+	item := ProcessedItem{Permalink: "/r/market/comments/2/widget/", Kind: "post"}
+
+	markItem(storeA, item) // Fails once, below the outage threshold
+	if storeOutage.isDown() || storeOutage.held() != 1 {
+		t.Fatalf("outage %v with %d held item(s), want the record held without an outage", storeOutage.isDown(), storeOutage.held())
+	}
+	if processed, _ := checkedStore(storeA, item.Permalink, func() {}); !processed {
+		t.Error("profile a's held record doesn't count as processed for profile a")
+	}
+	if processed, _ := checkedStore(storeB, item.Permalink, func() {}); processed {
+		t.Error("profile a's held record counts as processed for profile b with per-profile dedupe")
+	}
+
+	profileDedupe = "global"
+	if processed, _ := checkedStore(storeB, item.Permalink, func() {}); !processed {
+		t.Error("profile a's held record doesn't count as processed for profile b with global dedupe")
+	}
+}
+
+func TestOutageBufferDropsTheOldestItemWhenFull(t *testing.T) {
+	withStoreOutage(t, "profile")
+	store := &flakyStore{MemoryStore: NewMemoryStore(), profile: "widgets", down: true}
+	notifier := &alertRecorder{}
+	rules := matchRules{profile: "widgets", keywords: []KeywordSpec{{Keyword: "widget"}}, minMatches: 1}
+	posts := make([]Post, storeOutageBufferSize+1)
+	for i := range posts {
+		posts[i] = Post{Title: fmt.Sprintf("Selling widget #%d", i), Subreddit: "market", Permalink: fmt.Sprintf("/r/market/comments/%d/widget/", i)}
+	}
+
+	processPosts(store, notifier, rules, posts)
+	first := heldItem{profile: "widgets", post: &posts[0]}
+	if storeOutage.held() != storeOutageBufferSize || storeOutage.dropped != 1 {
+		t.Fatalf("%d held item(s), %d dropped, want a full buffer and one dropped", storeOutage.held(), storeOutage.dropped)
+	}
+	if storeOutage.keys[first.key()] || storeOutage.items[0].post.Permalink != posts[1].Permalink {
+		t.Fatalf("oldest held item = %s, want %s evicted from the items and keys", storeOutage.items[0].describe(), posts[0].Permalink)
+	}
+
+	store.down = false
+	replayHeldItems(store, notifier, rules)
+	if storeOutage.isDown() || storeOutage.held() != 0 || storeOutage.dropped != 0 {
+		t.Fatalf("outage %v with %d held, %d dropped after recovery, want none", storeOutage.isDown(), storeOutage.held(), storeOutage.dropped)
+	}
+	if len(notifier.alerts) != storeOutageBufferSize {
+		t.Errorf("%d alert(s) after the replay, want one per held item", len(notifier.alerts))
+	}
+	for _, alert := range notifier.alerts {
+		if alert.Permalink == posts[0].Permalink {
+			t.Errorf("dropped post %s was replayed", posts[0].Permalink)
+		}
+	}
+	if processed, _ := store.Has(context.Background(), posts[0].Permalink); processed {
+		t.Errorf("dropped post %s was recorded", posts[0].Permalink)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// MemoryStore is a map-based Store for tests and runs without MongoDB
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]ProcessedItem
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: map[string]ProcessedItem{}}
+}
+
+// Has reports whether the permalink has been marked.
+func (s *MemoryStore) Has(ctx context.Context, permalink string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.items[permalink]
+	return ok, nil
+}
+
+// Mark stores the item, returning ErrAlreadyProcessed if the permalink was already marked.
+func (s *MemoryStore) Mark(ctx context.Context, item ProcessedItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[item.Permalink]; ok {
+		return ErrAlreadyProcessed
+	}
+	s.items[item.Permalink] = item
+	return nil
+}
+
+// FindDuplicate scans the marked items, see Store.
+func (s *MemoryStore) FindDuplicate(ctx context.Context, contentHash, parentName string, since time.Time) (*ProcessedItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var earliest *ProcessedItem
+	for _, item := range s.items {
+		if item.DuplicateOf != "" {
+			continue
+		}
+		byHash := contentHash != "" && item.ContentHash == contentHash && !item.ProcessedAt.Before(since)
+		byParent := parentName != "" && item.PostName == parentName
+		if (byHash || byParent) && (earliest == nil || item.ProcessedAt.Before(earliest.ProcessedAt)) {
+			match := item
+			earliest = &match
+		}
+	}
+	return earliest, nil
+}
+
+// AddAlsoPostedIn appends note to the item's AlsoPostedIn, once.
+func (s *MemoryStore) AddAlsoPostedIn(ctx context.Context, permalink, note string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[permalink]
+	if !ok {
+		return nil
+	}
+	for _, existing := range item.AlsoPostedIn {
+		if existing == note {
+			return nil
+		}
+	}
+	item.AlsoPostedIn = append(item.AlsoPostedIn, note)
+	s.items[permalink] = item
+	return nil
+}
+
+// Items returns a copy of everything marked so far.
+func (s *MemoryStore) Items() []ProcessedItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]ProcessedItem, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	return items
+}
+
+// storeCases opens an empty store of every kind kept outside MongoDB and DynamoDB.
+func storeCases(t *testing.T) map[string]Store {
+	file, err := NewFileStore(filepath.Join(t.TempDir(), "processed_items.jsonl"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(file.close)
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "processed_items.db"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(bolt.close)
+	return map[string]Store{"memory": NewMemoryStore(), "file": file, "bolt": bolt}
+}
+
+func TestStoreMark(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range storeCases(t) {
+		item := ProcessedItem{Permalink: "/r/golang/comments/1/a/", Subreddit: "golang", ProcessedAt: time.Now()}
+		if processed, err := store.Has(ctx, item.Permalink); err != nil || processed {
+			t.Errorf("%s: Has before Mark = %v, %v, want false", name, processed, err)
+		}
+		if err := store.Mark(ctx, item); err != nil {
+			t.Errorf("%s: Mark: %v", name, err)
+		}
+		if processed, err := store.Has(ctx, item.Permalink); err != nil || !processed {
+			t.Errorf("%s: Has after Mark = %v, %v, want true", name, processed, err)
+		}
+		if err := store.Mark(ctx, item); !errors.Is(err, ErrAlreadyProcessed) {
+			t.Errorf("%s: second Mark = %v, want ErrAlreadyProcessed", name, err)
+		}
+	}
+}
+
+func TestStoreFindDuplicate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	items := []ProcessedItem{
+		{Permalink: "/r/a/comments/1/", Subreddit: "a", ContentHash: "h1", PostName: "t3_1", ProcessedAt: now.Add(-3 * time.Hour)},
+		{Permalink: "/r/b/comments/2/", Subreddit: "b", ContentHash: "h2", PostName: "t3_2", ProcessedAt: now.Add(-10 * time.Minute)},
+		{Permalink: "/r/c/comments/3/", Subreddit: "c", ContentHash: "h2", PostName: "t3_3", ProcessedAt: now.Add(-5 * time.Minute), DuplicateOf: "/r/b/comments/2/"},
+		{Permalink: "/r/d/comments/4/", Subreddit: "d", ContentHash: "h3", PostName: "t3_4", ProcessedAt: now.Add(-5 * time.Minute), DuplicateOf: "/r/x/comments/9/"},
+	}
+	tests := []struct {
+		name        string
+		contentHash string
+		parentName  string
+		want        string // Permalink, "" for none
+	}{
+		{"hash within the window", "h2", "", "/r/b/comments/2/"},
+		{"hash outside the window", "h1", "", ""},
+		{"crosspost of an old post", "", "t3_1", "/r/a/comments/1/"},
+		{"earliest of hash and post", "h2", "t3_1", "/r/a/comments/1/"},
+		{"duplicates aren't matched", "h3", "t3_4", ""},
+		{"unknown", "h9", "t3_9", ""},
+		{"nothing to look up", "", "", ""},
+	}
+	for name, store := range storeCases(t) {
+		for _, item := range items {
+			if err := store.Mark(ctx, item); err != nil {
+				t.Fatalf("%s: Mark: %v", name, err)
+			}
+		}
+		for _, tt := range tests {
+			found, err := store.FindDuplicate(ctx, tt.contentHash, tt.parentName, now.Add(-time.Hour))
+			got := ""
+			if found != nil {
+				got = found.Permalink
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("%s: %s: FindDuplicate = %q, %v, want %q", name, tt.name, got, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestStoreAddAlsoPostedIn(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range storeCases(t) {
+		if name == "file" {
+			continue // The file store doesn't keep the notes
+		}
+		item := ProcessedItem{Permalink: "/r/golang/comments/1/a/", PostName: "t3_1", ProcessedAt: time.Now()}
+		if err := store.Mark(ctx, item); err != nil {
+			t.Fatalf("%s: Mark: %v", name, err)
+		}
+		for _, note := range []string{"r/go", "r/go", "r/programming"} {
+			if err := store.AddAlsoPostedIn(ctx, item.Permalink, note); err != nil {
+				t.Errorf("%s: AddAlsoPostedIn: %v", name, err)
+			}
+		}
+		if err := store.AddAlsoPostedIn(ctx, "/r/golang/comments/2/b/", "r/go"); err != nil {
+			t.Errorf("%s: AddAlsoPostedIn of an unknown item: %v", name, err)
+		}
+		found, err := store.FindDuplicate(ctx, "", item.PostName, time.Time{})
+		if err != nil || found == nil || !slices.Equal(found.AlsoPostedIn, []string{"r/go", "r/programming"}) {
+			t.Errorf("%s: item after AddAlsoPostedIn = %+v, %v, want each note once", name, found, err)
+		}
+	}
+}
+
+func TestBoltStoreIndexesExistingDatabase(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "processed_items.db")
+	old := ProcessedItem{Permalink: "/r/a/comments/1/", ContentHash: "h1", PostName: "t3_1", ProcessedAt: time.Now().Add(-48 * time.Hour)}
+	recent := ProcessedItem{Permalink: "/r/b/comments/2/", ContentHash: "h2", PostName: "t3_2", ProcessedAt: time.Now()}
+
+	// A database written before the index bucket existed
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket(boltBucket)
+		if err != nil {
+			return err
+		}
+		for _, item := range []ProcessedItem{old, recent} {
+			value, _ := json.Marshal(item)
+			if err := bucket.Put([]byte(item.Permalink), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewBoltStore(path, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.close()
+	for _, item := range []ProcessedItem{old, recent} {
+		found, err := store.FindDuplicate(ctx, "", item.PostName, time.Time{})
+		if err != nil || found == nil || found.Permalink != item.Permalink {
+			t.Errorf("FindDuplicate(%s) = %+v, %v, want the item indexed on open", item.PostName, found, err)
+		}
+	}
+
+	if deleted, err := store.cleanup(); err != nil || deleted != 1 {
+		t.Fatalf("cleanup = %d, %v, want the old item deleted", deleted, err)
+	}
+	if found, err := store.FindDuplicate(ctx, "h1", "t3_1", time.Time{}); err != nil || found != nil {
+		t.Errorf("FindDuplicate of a deleted item = %+v, %v, want none", found, err)
+	}
+	err = store.db.View(func(tx *bolt.Tx) error {
+		if keys := tx.Bucket(boltIndexBucket).Stats().KeyN; keys != 2 {
+			t.Errorf("index has %d key(s) after cleanup, want the recent item's 2", keys)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
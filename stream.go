@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Match Streaming (WebSocket) ---
+
+// streamingPort serves /ws/matches and a demo page at / when set
+var streamingPort = os.Getenv("STREAMING_PORT")
+var streamingHost = os.Getenv("STREAMING_HOST")   // Listen host, defaults to 127.0.0.1
+var streamingToken = os.Getenv("STREAMING_TOKEN") // Required as ?token= when set, browsers can't set headers on WebSockets
+
+// NotificationData is a match as pushed to stream clients
+type NotificationData struct {
+	Kind      string    `json:"kind"` // "post" or "comment"
+	Subreddit string    `json:"subreddit"`
+	Permalink string    `json:"permalink"`
+	URL       string    `json:"url"`
+	Title     string    `json:"title,omitempty"`
+	Snippet   string    `json:"snippet,omitempty"`
+	Keywords  []string  `json:"keywords"`
+	Groups    []string  `json:"groups,omitempty"`
+	Priority  string    `json:"priority"`
+	Subject   string    `json:"subject"`
+	MatchedAt time.Time `json:"matched_at"`
+
+	KeywordPriority string `json:"keyword_priority"` // high, medium or low
+}
+
+// notificationData returns the stream form of an alert.
+func notificationData(alert Alert) NotificationData {
+	return NotificationData{
+		Kind:      alert.Kind,
+		Subreddit: alert.Subreddit,
+		Permalink: alert.Permalink,
+		URL:       alert.URL(),
+		Title:     alert.Title,
+		Snippet:   alert.Snippet,
+		Keywords:  alert.Keywords,
+		Groups:    alert.Groups,
+		Priority:  alert.Priority,
+		Subject:   alert.Subject,
+		MatchedAt: time.Now(),
+
+		KeywordPriority: alert.KeywordPriority,
+	}
+}
+
+// wsGUID is appended to the client key to build Sec-WebSocket-Accept (RFC 6455)
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes used by the stream
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+// wsMaxClientFrame caps frames read from clients, they only send control frames
+const wsMaxClientFrame = 4096
+
+// wsPingInterval keeps idle connections open through proxies and finds dead ones
+const wsPingInterval = 30 * time.Second
+
+// wsWriteTimeout bounds each frame write, so a stuck client can't block the others
+const wsWriteTimeout = 10 * time.Second
+
+// wsClient is one connected stream client
+type wsClient struct {
+	conn      net.Conn
+	send      chan []byte // Queued text messages, a client that lets it fill up is dropped
+	writeMu   sync.Mutex  // Frames are written by the writer, the reader (pongs, close echoes) and closeAll
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// writeFrame writes one frame, never interleaved with another goroutine's.
+func (c *wsClient) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return writeWSFrame(c.conn, opcode, payload)
+}
+
+// close ends the connection once, from whichever side notices first.
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// matchBroadcaster fans matches out to every connected stream client
+type matchBroadcaster struct {
+	mu      sync.Mutex
+	clients map[*wsClient]bool
+}
+
+// matchStream is the broadcaster behind /ws/matches
+var matchStream = &matchBroadcaster{clients: map[*wsClient]bool{}}
+
+// publish pushes alert to every client. Clients whose queue is full are dropped rather than
+// slowing down polling.
+func (b *matchBroadcaster) publish(alert Alert) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.clients) == 0 {
+		return
+	}
+	message, err := json.Marshal(notificationData(alert))
+	if err != nil {
+		fmt.Printf("Error encoding stream message: %v\n", err)
+		return
+	}
+	for client := range b.clients {
+		select {
+		case client.send <- message:
+		default:
+			fmt.Printf("WARN: Stream client %s is too slow, disconnecting it\n", client.conn.RemoteAddr())
+			delete(b.clients, client)
+			client.close()
+		}
+	}
+}
+
+// add registers a client.
+func (b *matchBroadcaster) add(client *wsClient) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clients[client] = true
+}
+
+// remove unregisters a client.
+func (b *matchBroadcaster) remove(client *wsClient) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.clients, client)
+}
+
+// closeAll disconnects every client, on shutdown.
+func (b *matchBroadcaster) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for client := range b.clients {
+		_ = client.writeFrame(wsOpClose, []byte{0x03, 0xE9}) // 1001 going away
+		client.close()
+		delete(b.clients, client)
+	}
+}
+
+// writeWSFrame writes one unmasked, unfragmented frame, as servers send them.
+func writeWSFrame(w io.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readWSFrame reads one masked client frame and returns its opcode and unmasked payload.
+func readWSFrame(r *bufio.Reader) (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("unmasked client frame")
+	}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > wsMaxClientFrame {
+		return 0, nil, fmt.Errorf("client frame of %d bytes is too large", length)
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// sameOrigin reports whether a browser request comes from a page served by the stream itself.
+// Requests without an Origin header don't come from a browser page and aren't restricted.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
+
+// handleMatchStream upgrades /ws/matches to a WebSocket and streams matches to it until either side closes.
+func handleMatchStream(w http.ResponseWriter, r *http.Request) {
+	if streamingToken != "" && subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(streamingToken)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !sameOrigin(r) { // Other sites' pages could otherwise read the stream through the visitor's browser
+		http.Error(w, "cross-origin stream requests are not allowed", http.StatusForbidden)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		fmt.Printf("Error upgrading stream connection: %v\n", err)
+		return
+	}
+	sum := sha1.Sum([]byte(key + wsGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return
+	}
+
+	client := &wsClient{conn: conn, send: make(chan []byte, 64), done: make(chan struct{})}
+	matchStream.add(client)
+	fmt.Printf("Stream client %s connected.\n", conn.RemoteAddr())
+	defer func() {
+		matchStream.remove(client)
+		client.close()
+		fmt.Printf("Stream client %s disconnected.\n", conn.RemoteAddr())
+	}()
+
+	// Reader: answers pings and notices the client closing or dropping
+	go func() {
+		defer client.close()
+		for {
+			opcode, payload, err := readWSFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case wsOpClose:
+				_ = client.writeFrame(wsOpClose, payload) // Echo the status code back
+				return
+			case wsOpPing:
+				if client.writeFrame(wsOpPong, payload) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	// Writer: sends messages and pings, the reader only answers the client's control frames
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-client.done:
+			return
+		case message := <-client.send:
+			err = client.writeFrame(wsOpText, message)
+		case <-ping.C:
+			err = client.writeFrame(wsOpPing, nil)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// streamDemoPage shows the stream in a browser
+const streamDemoPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Reddit Monitor - live matches</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+#status { color: #666; }
+li { margin-bottom: 1em; }
+.meta { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>Live matches</h1>
+<p id="status">Connecting...</p>
+<ul id="matches"></ul>
+<script>
+function connect() {
+  var status = document.getElementById("status");
+  var scheme = location.protocol === "https:" ? "wss://" : "ws://";
+  var token = new URLSearchParams(location.search).get("token");
+  var ws = new WebSocket(scheme + location.host + "/ws/matches" + (token ? "?token=" + encodeURIComponent(token) : ""));
+  ws.onopen = function () { status.textContent = "Connected, waiting for matches..."; };
+  ws.onclose = function () {
+    status.textContent = "Disconnected, reconnecting in 5s...";
+    setTimeout(connect, 5000);
+  };
+  ws.onmessage = function (event) {
+    var match = JSON.parse(event.data);
+    var item = document.createElement("li");
+    var link = document.createElement("a");
+    link.href = match.url;
+    link.textContent = match.title || match.subject;
+    var meta = document.createElement("div");
+    meta.className = "meta";
+    meta.textContent = new Date(match.matched_at).toLocaleTimeString() + " - r/" + match.subreddit +
+      " - " + match.kind + " - " + match.keywords.join(", ");
+    var snippet = document.createElement("div");
+    snippet.textContent = match.snippet || "";
+    item.append(link, meta, snippet);
+    document.getElementById("matches").prepend(item);
+  };
+}
+connect();
+</script>
+</body>
+</html>
+`
+
+// handleStreamDemo serves the demo page at /.
+func handleStreamDemo(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = io.WriteString(w, streamDemoPage)
+}
+
+// runStreamServer serves the match stream until ctx is cancelled. It does nothing without STREAMING_PORT.
+func runStreamServer(ctx context.Context) {
+	if streamingPort == "" {
+		return
+	}
+	host := streamingHost
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if streamingToken == "" && !isLoopbackHost(host) {
+		fmt.Printf("WARN: Match stream listens on %s without STREAMING_TOKEN, anyone who can reach it can read every match\n", host)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/matches", handleMatchStream)
+	mux.HandleFunc("/", handleStreamDemo)
+	addr := net.JoinHostPort(host, streamingPort)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		matchStream.closeAll() // Hijacked connections aren't closed by Shutdown
+		ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctxShutdown)
+	}()
+
+	fmt.Println("Match stream listening on", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("Error running match stream: %v\n", err)
+	}
+}
+
+// isLoopbackHost reports whether host only accepts local connections.
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
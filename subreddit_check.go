@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Subreddit Validation ---
+
+// Subreddit Check Configuration (Read from Environment Variables)
+var failOnInvalidSubreddits = os.Getenv("FAIL_ON_INVALID_SUBREDDITS") == "true" // Make invalid subreddits a fatal startup error
+var subredditCheckInterval = time.Duration(envInt("SUBREDDIT_CHECK_INTERVAL_MINUTES", 60)) * time.Minute
+var subredditRecheck = os.Getenv("SUBREDDIT_RECHECK") == "true" // Re-run the check every subredditCheckInterval
+var adminEmail = os.Getenv("ADMIN_EMAIL")                       // Falls back to RECIPIENT_EMAIL when empty
+
+// subredditStatus is the cached result of checking one subreddit's about.json.
+type subredditStatus struct {
+	Name      string
+	Valid     bool
+	Reason    string // Why the subreddit is invalid (e.g. "not found", "private", "banned")
+	Unknown   bool   // The check itself failed (network error, timeout), so validity is unknown
+	CheckedAt time.Time
+}
+
+// SubredditAbout matches the parts of /r/<name>/about.json we care about
+type SubredditAbout struct {
+	Kind   string `json:"kind"`
+	Reason string `json:"reason"` // Present on 403/404 error bodies, e.g. "private", "banned", "quarantined"
+	Data   struct {
+		DisplayName   string `json:"display_name"`
+		SubredditType string `json:"subreddit_type"`
+	} `json:"data"`
+}
+
+var subredditStatusMu sync.Mutex
+var subredditStatusCache = map[string]subredditStatus{}
+var reportedInvalidSubreddits = map[string]bool{} // Subreddits already included in an admin email
+
+// checkSubreddit fetches about.json for a single subreddit and classifies it.
+func checkSubreddit(ctx context.Context, name string) subredditStatus {
+	status := subredditStatus{Name: name, CheckedAt: time.Now()}
+
+	resp, err := redditClient.redditGet(ctx, fmt.Sprintf("https://www.reddit.com/r/%s/about.json", name))
+	if err != nil {
+		status.Unknown, status.Reason = true, err.Error()
+		return status
+	}
+	defer resp.Body.Close()
+
+	var about SubredditAbout
+	decodeErr := json.NewDecoder(resp.Body).Decode(&about)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		status.Reason = "not found"
+		if about.Reason != "" {
+			status.Reason = about.Reason
+		}
+	case resp.StatusCode == http.StatusForbidden:
+		status.Reason = "forbidden"
+		if about.Reason != "" {
+			status.Reason = about.Reason
+		}
+	case resp.StatusCode != http.StatusOK:
+		// 429/5xx say nothing about the subreddit itself
+		status.Unknown, status.Reason = true, fmt.Sprintf("unexpected status code: %d", resp.StatusCode)
+	case decodeErr != nil:
+		status.Unknown, status.Reason = true, fmt.Sprintf("error decoding JSON response: %v", decodeErr)
+	case about.Kind != "t5":
+		// Nonexistent names are redirected to a subreddit search listing instead of a 404
+		status.Reason = "not found"
+	case about.Data.SubredditType == "private":
+		status.Reason = "private"
+	default:
+		status.Valid = true
+	}
+	return status
+}
+
+// checkAllSubreddits checks every subreddit concurrently, bounded by timeout, and caches the results.
+func checkAllSubreddits(timeout time.Duration) []subredditStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	names := []string{}
+	configMu.RLock()
+	configs := subredditConfigs
+	configMu.RUnlock()
+	for _, cfg := range configs {
+		if !cfg.isMultireddit() {
+			names = append(names, cfg.Name) // Multireddits have no about.json
+		}
+	}
+
+	results := make([]subredditStatus, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = checkSubreddit(ctx, name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	subredditStatusMu.Lock()
+	for _, status := range results {
+		subredditStatusCache[status.Name] = status
+	}
+	subredditStatusMu.Unlock()
+	return results
+}
+
+// invalidSubreddits returns the statuses that are known to be invalid.
+func invalidSubreddits(statuses []subredditStatus) []subredditStatus {
+	invalid := []subredditStatus{}
+	for _, status := range statuses {
+		if !status.Valid && !status.Unknown {
+			invalid = append(invalid, status)
+		}
+	}
+	return invalid
+}
+
+// reportSubredditStatuses logs a prominent warning for invalid subreddits and sends one admin email
+// listing any that haven't been reported before.
+func reportSubredditStatuses(statuses []subredditStatus) {
+	newlyInvalid := []string{}
+	for _, status := range statuses {
+		if status.Unknown {
+			fmt.Printf("WARN: Could not check subreddit r/%s: %s\n", status.Name, status.Reason)
+			continue
+		}
+		if status.Valid {
+			continue
+		}
+		fmt.Printf("!!! WARNING: Subreddit r/%s is invalid (%s), no alerts will come from it !!!\n", status.Name, status.Reason)
+
+		subredditStatusMu.Lock()
+		if !reportedInvalidSubreddits[status.Name] {
+			reportedInvalidSubreddits[status.Name] = true
+			newlyInvalid = append(newlyInvalid, fmt.Sprintf("r/%s (%s)", status.Name, status.Reason))
+		}
+		subredditStatusMu.Unlock()
+	}
+
+	if len(newlyInvalid) == 0 {
+		return
+	}
+	to := adminEmail
+	if to == "" {
+		to = recipientEmail
+	}
+	subject := "Reddit Keyword Monitor: invalid subreddits configured"
+	body := "The following subreddits are banned, private or do not exist:\n" + strings.Join(newlyInvalid, "\n")
+	if err := newNotifier(to).Notify(subject, body); err != nil {
+		fmt.Println("Error sending invalid subreddit email:", err)
+	}
+}
+
+// monitorSubreddits checks subreddits once and, if enabled, re-checks them every subredditCheckInterval.
+// Run this in a goroutine from main to avoid blocking the main loop.
+func monitorSubreddits() {
+	for {
+		reportSubredditStatuses(checkAllSubreddits(30 * time.Second))
+		if !subredditRecheck {
+			return
+		}
+		time.Sleep(subredditCheckInterval)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// --- Microsoft Teams ---
+
+// teamsWebhookURL is a Teams incoming webhook, alerts are posted to it as Adaptive Cards when set
+var teamsWebhookURL = os.Getenv("TEAMS_WEBHOOK_URL")
+
+// teamsMaxPayload is the largest message Teams webhooks accept, 28KB
+const teamsMaxPayload = 28 * 1024
+
+// TeamsNotifier posts alerts to a Microsoft Teams channel through an incoming webhook
+type TeamsNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewTeamsNotifier returns a Notifier posting to the Teams incoming webhook webhookURL.
+func NewTeamsNotifier(webhookURL string) *TeamsNotifier {
+	return &TeamsNotifier{webhookURL: webhookURL, client: httpClient}
+}
+
+// teamsFact is a name and value row of an Adaptive Card FactSet
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// teamsElement is an Adaptive Card body element, a TextBlock or a FactSet
+type teamsElement struct {
+	Type   string      `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Weight string      `json:"weight,omitempty"`
+	Size   string      `json:"size,omitempty"`
+	Wrap   bool        `json:"wrap,omitempty"`
+	Facts  []teamsFact `json:"facts,omitempty"`
+}
+
+// teamsAction is an Adaptive Card button opening a URL
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// teamsCard is an Adaptive Card
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+	Actions []teamsAction  `json:"actions,omitempty"`
+}
+
+// teamsAttachment wraps a card in a webhook message
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+// teamsMessage is the body of a webhook request
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+// Notify posts a card with the subject as title and the body as text.
+func (n *TeamsNotifier) Notify(subject, body string) error {
+	return n.post(subject, nil, body, "")
+}
+
+// NotifyAlert posts a card with the subreddit and keywords as facts, the snippet, and an
+// "Open on Reddit" button.
+func (n *TeamsNotifier) NotifyAlert(alert Alert) error {
+	facts := []teamsFact{
+		{Title: "Subreddit", Value: "r/" + alert.Subreddit},
+		{Title: "Keywords", Value: strings.Join(alert.Keywords, ", ")},
+	}
+	if len(alert.Groups) > 0 {
+		facts = append(facts, teamsFact{Title: "Groups", Value: strings.Join(alert.Groups, ", ")})
+	}
+	if alert.Title != "" && alert.Kind == "comment" {
+		facts = append(facts, teamsFact{Title: "Post", Value: alert.Title})
+	}
+	return n.post(alert.Subject, facts, alert.Snippet, alert.URL())
+}
+
+// teamsPayload builds the webhook message of a card.
+func teamsPayload(title string, facts []teamsFact, text, link string) ([]byte, error) {
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body:    []teamsElement{{Type: "TextBlock", Text: title, Weight: "Bolder", Size: "Medium", Wrap: true}},
+	}
+	if len(facts) > 0 {
+		card.Body = append(card.Body, teamsElement{Type: "FactSet", Facts: facts})
+	}
+	if text != "" {
+		card.Body = append(card.Body, teamsElement{Type: "TextBlock", Text: text, Wrap: true})
+	}
+	if link != "" {
+		card.Actions = []teamsAction{{Type: "Action.OpenUrl", Title: "Open on Reddit", URL: link}}
+	}
+	return json.Marshal(teamsMessage{
+		Type:        "message",
+		Attachments: []teamsAttachment{{ContentType: "application/vnd.microsoft.card.adaptive", Content: card}},
+	})
+}
+
+// post sends the card, shortening text to fit teamsMaxPayload. Throttling (429, honoring
+// Retry-After) and server errors are retried.
+func (n *TeamsNotifier) post(title string, facts []teamsFact, text, link string) error {
+	payload, err := teamsPayload(title, facts, text, link)
+	if err == nil && len(payload) > teamsMaxPayload {
+		text = teamsFitText(title, facts, text, link)
+		payload, err = teamsPayload(title, facts, text, link)
+	}
+	if err != nil {
+		return err
+	}
+	if len(payload) > teamsMaxPayload {
+		return fmt.Errorf("Teams message is %d bytes, over the %d byte limit", len(payload), teamsMaxPayload)
+	}
+
+	err = withRetry("Teams webhook", func() error {
+		resp, err := n.client.Post(n.webhookURL, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			return &retryableError{err: err}
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode >= 300 {
+			return statusError(resp, respBody)
+		}
+		if strings.Contains(string(respBody), "HTTP error 429") {
+			// Legacy connector webhooks report throttling in a 200 response
+			return &retryableError{err: fmt.Errorf("throttled: %s", truncate(string(respBody), 200))}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to post to Teams: %w", err)
+	}
+	fmt.Println("Notification posted to Teams")
+	return nil
+}
+
+// teamsFitText returns the longest start of text, ending in "...", whose card fits teamsMaxPayload.
+// Lengths are measured JSON escaped, since quotes, control and HTML characters grow when encoded.
+func teamsFitText(title string, facts []teamsFact, text, link string) string {
+	rest, err := teamsPayload(title, facts, "...", link)
+	if err != nil {
+		return ""
+	}
+	budget := teamsMaxPayload - len(rest)
+	size := 0
+	for i, r := range text {
+		escaped, _ := json.Marshal(string(r))
+		size += len(escaped) - 2 // Without the quotes
+		if size > budget {
+			return text[:i] + "..."
+		}
+	}
+	return text
+}
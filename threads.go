@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Followed Threads ---
+
+// followedThreadsCollectionName holds the matched posts whose comment threads are watched
+const followedThreadsCollectionName = "followed_threads"
+
+// followedThreadsCollection is the followed threads collection, nil when not connected
+var followedThreadsCollection *mongo.Collection
+
+// followThreads watches the comment thread of every matched post for followThreadWindow
+var followThreads = false
+var followThreadWindow = 48 * time.Hour
+
+// threadCommentLimit is how many comments of a thread are fetched per cycle, newest first
+const threadCommentLimit = 500
+
+// FollowedThread is a matched post whose comments are matched each cycle until FollowedUntil
+type FollowedThread struct {
+	Permalink     string    `bson:"permalink"`
+	Profile       string    `bson:"profile"` // Profile that matched the post, "" for the top-level config
+	Subreddit     string    `bson:"subreddit"`
+	Title         string    `bson:"title"`
+	FollowedUntil time.Time `bson:"followed_until"`
+}
+
+// setupFollowedThreads ensures the indexes of the followed threads collection: one thread per
+// permalink and profile, and a TTL index dropping threads once they are no longer followed.
+func setupFollowedThreads(collection *mongo.Collection) {
+	followedThreadsCollection = collection
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "permalink", Value: 1}, {Key: "profile", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    map[string]interface{}{"followed_until": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		fmt.Printf("WARN: Could not create indexes on %s: %v\n", followedThreadsCollectionName, err)
+	}
+}
+
+// followThread starts following the comment thread of a matched post, unless it already is.
+// Matching the post again doesn't extend the window.
+func followThread(profile string, post Post) {
+	if !followThreads || followedThreadsCollection == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := followedThreadsCollection.UpdateOne(ctx,
+		map[string]interface{}{"permalink": post.Permalink, "profile": profile},
+		map[string]interface{}{"$setOnInsert": FollowedThread{
+			Permalink: post.Permalink, Profile: profile, Subreddit: post.Subreddit, Title: post.Title,
+			FollowedUntil: time.Now().Add(followThreadWindow),
+		}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		fmt.Printf("Error following thread %s: %v\n", post.Permalink, err)
+		return
+	}
+	if result.UpsertedCount > 0 {
+		fmt.Printf("Following the comments of https://www.reddit.com%s for %s\n", post.Permalink, followThreadWindow)
+	}
+}
+
+// threadListing is a comment listing of a thread, with the replies of every comment nested in it
+type threadListing struct {
+	Data struct {
+		Children []struct {
+			Kind string `json:"kind"` // t1 for comments, "more" for comments that weren't loaded
+			Data struct {
+				Comment
+				Replies json.RawMessage `json:"replies"` // "" without replies, a threadListing otherwise
+			} `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// flatten appends every comment of the listing and its replies to comments.
+func (l threadListing) flatten(comments []Comment) []Comment {
+	for _, child := range l.Data.Children {
+		if child.Kind != "t1" {
+			continue
+		}
+		comments = append(comments, child.Data.Comment)
+		if len(child.Data.Replies) > 0 && child.Data.Replies[0] == '{' {
+			var replies threadListing
+			if err := json.Unmarshal(child.Data.Replies, &replies); err != nil {
+				fmt.Printf("WARN: Could not decode replies to %s: %v\n", child.Data.Permalink, err)
+				continue
+			}
+			comments = replies.flatten(comments)
+		}
+	}
+	return comments
+}
+
+// fetchThreadComments retrieves the comment tree of a followed thread as a flat list, unless the
+// comments circuit is open. The request goes through redditGet and so the shared rate limiter.
+func (c *RedditClient) fetchThreadComments(ctx context.Context, thread FollowedThread) ([]Comment, error) {
+	if !commentsBreaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+	endpoint := fmt.Sprintf("https://www.reddit.com%s.json?sort=new&limit=%d", strings.TrimSuffix(thread.Permalink, "/"), threadCommentLimit)
+	// The response is [post listing, comment listing]
+	listings, err := getRedditJSON[[]json.RawMessage](ctx, c, endpoint)
+	if ctx.Err() == nil {
+		commentsBreaker.record(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(listings) < 2 {
+		return nil, fmt.Errorf("unexpected response for thread %s", thread.Permalink)
+	}
+	var listing threadListing
+	if err := json.Unmarshal(listings[1], &listing); err != nil {
+		return nil, fmt.Errorf("error decoding comments of %s: %w", thread.Permalink, err)
+	}
+
+	comments := []Comment{}
+	for _, comment := range listing.flatten(nil) {
+		// Thread comments don't carry their post, unlike the comment listing
+		comment.LinkTitle = thread.Title
+		comment.LinkPermalink = "https://www.reddit.com" + thread.Permalink
+		if comment.Permalink == "" {
+			comment.Permalink = comment.buildPermalink()
+		}
+		if comment.Permalink == "" {
+			continue // An empty permalink would collide on the unique index
+		}
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
+
+// pollFollowedThreads matches the comments of the threads rules are following. Comments already
+// processed are skipped by permalink, like those from the comment listings.
+func pollFollowedThreads(ctx context.Context, store Store, notifier Notifier, rules matchRules) {
+	if !followThreads || followedThreadsCollection == nil {
+		return
+	}
+	ctxFind, cancelFind := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelFind()
+	cursor, err := followedThreadsCollection.Find(ctxFind, map[string]interface{}{
+		"profile":        rules.profile,
+		"followed_until": map[string]interface{}{"$gt": time.Now()}, // The TTL monitor only runs every minute
+	})
+	if err != nil {
+		fmt.Printf("Error querying followed threads%s: %v\n", rules.logTag(), err)
+		return
+	}
+	threads := []FollowedThread{}
+	if err := cursor.All(ctxFind, &threads); err != nil {
+		fmt.Printf("Error reading followed threads%s: %v\n", rules.logTag(), err)
+		return
+	}
+
+	for _, thread := range threads {
+		if ctx.Err() != nil {
+			return
+		}
+		comments, err := redditClient.fetchThreadComments(ctx, thread)
+		if err != nil {
+			fmt.Printf("Error fetching followed thread %s: %v%s\n", thread.Permalink, err, rules.logTag())
+			continue
+		}
+		processComments(store, notifier, rules, comments)
+	}
+	if len(threads) > 0 {
+		fmt.Printf("Checked %d followed thread(s)%s\n", len(threads), rules.logTag())
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Twilio SMS ---
+
+// TwilioConfig holds the Twilio settings of the config file. Alerts of keyword groups with
+// "sms": true are texted when AccountSID is set.
+type TwilioConfig struct {
+	AccountSID string
+	AuthToken  string
+	From       string // Twilio number texts are sent from, E.164 (+15551234567)
+	To         string // Number texts are sent to
+	DailyCap   int    // Texts per day, later alerts of the day are only emailed
+}
+
+// twilio is the configured Twilio account, zero when SMS isn't used
+var twilio TwilioConfig
+
+// maxSMSLength caps texts at two SMS segments, in characters
+const maxSMSLength = 320
+
+// defaultSMSDailyCap is the daily text limit when sms_daily_cap isn't set
+const defaultSMSDailyCap = 10
+
+// twilioPermanentCodes are Twilio errors retrying can't fix: invalid, unreachable or opted-out numbers
+var twilioPermanentCodes = map[int]bool{
+	21211: true, // Invalid "To" number
+	21212: true, // Invalid "From" number
+	21214: true, // "To" number cannot be reached
+	21408: true, // Region not enabled for SMS
+	21606: true, // "From" number can't send SMS
+	21610: true, // Recipient unsubscribed (replied STOP)
+	21612: true, // Unreachable via the "From" number
+	21614: true, // "To" number is not a mobile number
+}
+
+// validate checks that a configured account has its token and both numbers.
+func (c TwilioConfig) validate() error {
+	if c.AccountSID == "" {
+		return nil
+	}
+	if c.AuthToken == "" || c.From == "" || c.To == "" {
+		return fmt.Errorf("twilio_auth_token, twilio_from and twilio_to must be set with twilio_account_sid")
+	}
+	if !strings.HasPrefix(c.From, "+") || !strings.HasPrefix(c.To, "+") {
+		return fmt.Errorf("twilio_from and twilio_to must be E.164 numbers, e.g. +15551234567")
+	}
+	return nil
+}
+
+// smsDailyCount counts the texts sent today, resetting at local midnight. Restarts reset it too.
+type smsDailyCount struct {
+	mu     sync.Mutex
+	day    string
+	sent   int
+	warned bool // The cap notice went out today
+}
+
+// smsSent is the running count of today's texts
+var smsSent = &smsDailyCount{}
+
+// reserve counts a text if today's cap allows one more. first is true on the first refusal of the
+// day, so the overflow is announced once.
+func (c *smsDailyCount) reserve(limit int) (ok, first bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if today := time.Now().Format(time.DateOnly); c.day != today {
+		c.day, c.sent, c.warned = today, 0, false
+	}
+	if c.sent < limit {
+		c.sent++
+		return true, false
+	}
+	first = !c.warned
+	c.warned = true
+	return false, first
+}
+
+// SMSNotifier texts alerts of sms keyword groups through the Twilio Messages API. Other alerts and
+// plain notifications are left to the other channels.
+type SMSNotifier struct {
+	config   TwilioConfig
+	endpoint string
+	fallback Notifier // Told once a day when the cap is reached, the alerts themselves are emailed anyway
+	client   *http.Client
+}
+
+// NewSMSNotifier returns a Notifier texting through the configured Twilio account, announcing
+// overflow through fallback.
+func NewSMSNotifier(fallback Notifier) *SMSNotifier {
+	endpoint := "https://api.twilio.com/2010-04-01/Accounts/" + url.PathEscape(twilio.AccountSID) + "/Messages.json"
+	return &SMSNotifier{config: twilio, endpoint: endpoint, fallback: fallback, client: httpClient}
+}
+
+// Notify does nothing, only matches of sms keyword groups are texted.
+func (n *SMSNotifier) Notify(subject, body string) error {
+	return nil
+}
+
+// NotifyAlert texts the alert if it matched an sms keyword group and today's cap isn't reached.
+func (n *SMSNotifier) NotifyAlert(alert Alert) error {
+	if !alert.SMS {
+		return nil
+	}
+	ok, first := smsSent.reserve(n.dailyCap())
+	if !ok {
+		fmt.Printf("WARN: Daily SMS cap of %d reached, %s is only sent through the other channels\n", n.dailyCap(), alert.Permalink)
+		if first && n.fallback != nil {
+			body := fmt.Sprintf("The daily SMS cap of %d was reached, further SMS alerts today are sent by email only.\n\nFirst alert over the cap:\n%s", n.dailyCap(), alert.URL())
+			if err := n.fallback.Notify("Reddit Monitor: daily SMS cap reached", body); err != nil {
+				fmt.Printf("Error sending SMS cap notice: %v\n", err)
+			}
+		}
+		return nil
+	}
+	return n.send(smsText(alert))
+}
+
+// dailyCap returns the configured cap, defaultSMSDailyCap if unset.
+func (n *SMSNotifier) dailyCap() int {
+	if n.config.DailyCap > 0 {
+		return n.config.DailyCap
+	}
+	return defaultSMSDailyCap
+}
+
+// smsText formats an alert compactly, e.g. "r/Wholesale: VA, leads https://redd.it/abc123",
+// capped at maxSMSLength. The keywords are cut first so the link stays whole.
+func smsText(alert Alert) string {
+	link := shortPermalink(alert.Permalink)
+	prefix := "r/" + alert.Subreddit + ": "
+	room := maxSMSLength - len([]rune(prefix)) - len([]rune(link)) - 1
+	keywordList := strings.Join(alert.Keywords, ", ")
+	if room > 3 {
+		keywordList = truncate(keywordList, room)
+	}
+	return truncate(prefix+keywordList+" "+link, maxSMSLength)
+}
+
+// shortPermalink returns a short link for a permalink: redd.it/<id> for posts, and the slug-less
+// comment URL for comments.
+func shortPermalink(permalink string) string {
+	parts := strings.Split(strings.Trim(permalink, "/"), "/")
+	for i, part := range parts {
+		if part != "comments" || i+1 >= len(parts) {
+			continue
+		}
+		postID := parts[i+1]
+		if i+3 < len(parts) && parts[i+3] != "" {
+			return "https://reddit.com/comments/" + postID + "/_/" + parts[i+3]
+		}
+		return "https://redd.it/" + postID
+	}
+	return "https://www.reddit.com" + permalink
+}
+
+// twilioError is the body of a failed Twilio request
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// send posts the text, retrying rate limits and server errors. Invalid numbers are permanent failures.
+func (n *SMSNotifier) send(text string) error {
+	form := url.Values{}
+	form.Set("From", n.config.From)
+	form.Set("To", n.config.To)
+	form.Set("Body", text)
+	payload := form.Encode()
+
+	err := withRetry("Twilio SMS", func() error {
+		req, err := http.NewRequest(http.MethodPost, n.endpoint, strings.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(n.config.AccountSID, n.config.AuthToken)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := n.client.Do(req)
+		if err != nil {
+			return &retryableError{err: err}
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		var twilioErr twilioError
+		if json.Unmarshal(respBody, &twilioErr) == nil && twilioPermanentCodes[twilioErr.Code] {
+			return &permanentError{err: fmt.Errorf("Twilio error %d: %s", twilioErr.Code, twilioErr.Message)}
+		}
+		return statusError(resp, respBody)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send SMS through Twilio: %w", err)
+	}
+	fmt.Println("SMS sent to", n.config.To)
+	return nil
+}
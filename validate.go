@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// --- Validate Subcommand ---
+
+// validationCheck is a single named pre-flight check run by `validate`.
+type validationCheck struct {
+	name string
+	run  func() error
+}
+
+// runValidate runs all configuration pre-flight checks, prints a pass/fail line for each
+// and returns the process exit code (1 if any check failed).
+func runValidate() int {
+	fmt.Println("Validating Reddit keyword monitor configuration...")
+
+	checks := []validationCheck{
+		{"Config file", loadConfig},
+		{"Environment variables", checkEnv},
+		{"Proxy", configureProxy},
+		{"Reddit connectivity", checkRedditConnectivity},
+		{"Subreddits", checkSubreddits},
+		{"Keyword patterns", checkKeywordPatterns},
+		{"MongoDB connection", checkMongo},
+		{"SMTP connection", checkSMTP},
+	}
+
+	failed := 0
+	for _, check := range checks {
+		if err := check.run(); err != nil {
+			fmt.Printf("[FAIL] %s: %v\n", check.name, err)
+			failed++
+		} else {
+			fmt.Printf("[PASS] %s\n", check.name)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d checks failed.\n", failed, len(checks))
+		return 1
+	}
+	fmt.Println("All checks passed.")
+	return 0
+}
+
+// checkSubreddits verifies at least one subreddit is configured.
+func checkSubreddits() error {
+	if len(subreddits) == 0 && len(profiles) == 0 {
+		return fmt.Errorf("no subreddits configured")
+	}
+	return nil
+}
+
+// checkKeywordPatterns compiles every keyword regex and reports the offending patterns.
+func checkKeywordPatterns() error {
+	if len(keywords) == 0 && len(keywordGroups) == 0 {
+		return fmt.Errorf("no keywords configured")
+	}
+	invalid := 0
+	check := func(name string, i int, spec KeywordSpec) {
+		err := spec.validate()
+		if err == nil && len(spec.Near) == 0 {
+			_, err = compileKeywordPattern(spec)
+		}
+		if err != nil {
+			fmt.Printf("       %s[%d] %q: %v\n", name, i, spec.label(), err)
+			invalid++
+		}
+	}
+	for i, spec := range keywords {
+		check("keywords", i, spec)
+	}
+	for _, group := range keywordGroups {
+		for i, spec := range group.Keywords {
+			check(group.Name, i, spec)
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d keyword pattern(s) failed to compile", invalid)
+	}
+	return nil
+}
+
+// checkMongo connects to and pings MongoDB, then disconnects.
+func checkMongo() error {
+	if mongoURI == "" && !mongoRequired() {
+		fmt.Println("       processed items aren't kept in MongoDB and MONGODB_URI is not set, skipping")
+		return nil
+	}
+	if mongoURI == "" {
+		return fmt.Errorf("MONGODB_URI is not set")
+	}
+	client, err := connectMongo()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = client.Disconnect(ctx)
+	return nil
+}
+
+// checkSMTP connects and authenticates to the SMTP server without sending anything.
+// It passes without connecting when emails go through an HTTP API provider.
+func checkSMTP() error {
+	if provider := activeEmailProvider(); provider != "smtp" {
+		fmt.Printf("       emails are sent through %s, skipping SMTP\n", provider)
+		return nil
+	}
+	if gmailUser == "" || gmailAppPassword == "" {
+		return fmt.Errorf("GMAIL_USER and GMAIL_APP_PASSWORD must be set")
+	}
+	client, err := dialSMTP()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Quit()
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Weekly Report ---
+
+// weeklyReportEnabled turns on the weekly report email, sent on weeklyReportSchedule (a cron
+// expression, Monday 08:00 by default) and covering the seven days before the day it is sent
+var weeklyReportEnabled = false
+var weeklyReportSchedule = "0 8 * * mon"
+
+// reportStateCollectionName stores the period of the last report sent, so a restart neither
+// sends a week twice nor skips one
+const reportStateCollectionName = "report_state"
+
+// weeklyReportStateID is the report_state document of the weekly report
+const weeklyReportStateID = "weekly_report"
+
+// weeklyReportTop is how many keywords and subreddits the report lists
+const weeklyReportTop = 10
+
+// reportState is the last period a report was sent for
+type reportState struct {
+	ID        string    `bson:"_id"`
+	PeriodEnd time.Time `bson:"period_end"` // Exclusive, local midnight
+	SentAt    time.Time `bson:"sent_at"`
+}
+
+// DayCount is the number of matches on one day (local time)
+type DayCount struct {
+	Day   string `bson:"_id"` // YYYY-MM-DD
+	Count int64  `bson:"count"`
+}
+
+// WeeklyReport is the content of a weekly report
+type WeeklyReport struct {
+	Since, Until  time.Time // Local midnights, Until exclusive
+	Total         int64
+	PreviousTotal int64        // The week before
+	Keywords      []CountDelta // The weeklyReportTop most matched
+	Subreddits    []CountDelta
+	Days          []DayCount // Every day of the week, oldest first
+}
+
+// WeeklyReporter sends the weekly report on its schedule
+type WeeklyReporter struct {
+	spec     string
+	schedule cron.Schedule
+	matches  *mongo.Collection // processed_items
+	state    *mongo.Collection // report_state
+	notifier Notifier
+	mu       sync.Mutex // A catch-up and a scheduled run don't both send
+}
+
+// NewWeeklyReporter returns a report sent on the cron expression spec, once registered.
+func NewWeeklyReporter(spec string, matches, state *mongo.Collection, notifier Notifier) (*WeeklyReporter, error) {
+	schedule, err := parseCronSchedule(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid weekly report schedule: %w", err)
+	}
+	return &WeeklyReporter{spec: spec, schedule: schedule, matches: matches, state: state, notifier: notifier}, nil
+}
+
+// Register schedules the report with c, and sends the report of the last scheduled run in the
+// background if it was missed while the monitor was down.
+func (r *WeeklyReporter) Register(c *Cron) error {
+	if err := c.AddFunc("weekly report", r.spec, r.fire); err != nil {
+		return err
+	}
+	go r.catchUp(time.Now())
+	return nil
+}
+
+// fire sends the report due now.
+func (r *WeeklyReporter) fire() {
+	if err := r.send(time.Now()); err != nil {
+		fmt.Printf("Error sending weekly report: %v\n", err)
+	}
+}
+
+// catchUp sends the report of the last scheduled run before now if a report was sent before but
+// not for that run's week. Without any report sent yet, the first one waits for the schedule.
+func (r *WeeklyReporter) catchUp(now time.Time) {
+	run := r.lastRun(now)
+	if run.IsZero() {
+		return
+	}
+	state, err := r.lastState()
+	if err != nil {
+		fmt.Printf("Error reading the weekly report state: %v\n", err)
+		return
+	}
+	if state == nil || !state.PeriodEnd.Before(reportPeriodEnd(run)) {
+		return
+	}
+	fmt.Printf("Info: The weekly report of %s was missed, sending it now\n", run.Format("2006-01-02 15:04"))
+	if err := r.send(run); err != nil {
+		fmt.Printf("Error sending weekly report: %v\n", err)
+	}
+}
+
+// lastRun returns the last scheduled run within the week before now, the zero time if there is none.
+func (r *WeeklyReporter) lastRun(now time.Time) time.Time {
+	var last time.Time
+	for t := r.schedule.Next(now.AddDate(0, 0, -7)); !t.IsZero() && !t.After(now); t = r.schedule.Next(t) {
+		last = t
+	}
+	return last
+}
+
+// lastState returns the stored state of the weekly report, nil if none was sent yet.
+func (r *WeeklyReporter) lastState() (*reportState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var state reportState
+	err := r.state.FindOne(ctx, map[string]interface{}{"_id": weeklyReportStateID}).Decode(&state)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// reportPeriodEnd returns the end of the week reported by a run at t: the local midnight starting t's day.
+func reportPeriodEnd(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// send reports the week before run's day, unless it was reported already, and stores the period
+// once the report went out.
+func (r *WeeklyReporter) send(run time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	until := reportPeriodEnd(run)
+	state, err := r.lastState()
+	if err != nil {
+		return fmt.Errorf("error reading the weekly report state: %w", err)
+	}
+	if state != nil && !state.PeriodEnd.Before(until) {
+		fmt.Printf("Info: The weekly report up to %s was sent already\n", until.Format("2006-01-02"))
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	report, err := queryWeeklyReport(ctx, r.matches, until)
+	if err != nil {
+		return err
+	}
+	subject, text, htmlText := formatWeeklyReport(report)
+	if htmlText == "" {
+		err = r.notifier.Notify(subject, text)
+	} else {
+		err = notifyHTML(r.notifier, subject, text, htmlText)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.state.UpdateOne(ctx, map[string]interface{}{"_id": weeklyReportStateID},
+		map[string]interface{}{"$set": map[string]interface{}{"period_end": until, "sent_at": time.Now()}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("weekly report sent but its state wasn't stored, a restart may send it again: %w", err)
+	}
+	return nil
+}
+
+// queryWeeklyReport aggregates the matches of the seven days before until: counts per keyword and
+// subreddit against the week before, and per day.
+func queryWeeklyReport(ctx context.Context, collection *mongo.Collection, until time.Time) (WeeklyReport, error) {
+	since := until.AddDate(0, 0, -7)
+	report := WeeklyReport{Since: since, Until: until}
+
+	// Both weeks, so one pass counts current and previous
+	filter := matchesFilter(since, "", "")
+	filter["processed_at"] = map[string]interface{}{"$gte": since.AddDate(0, 0, -7), "$lt": until}
+
+	keywords, err := aggregateCounts(ctx, collection, countsPipeline(filter, "keywords", since))
+	if err != nil {
+		return report, fmt.Errorf("error counting matches per keyword: %w", err)
+	}
+	subreddits, err := aggregateCounts(ctx, collection, countsPipeline(filter, "subreddit", since))
+	if err != nil {
+		return report, fmt.Errorf("error counting matches per subreddit: %w", err)
+	}
+	for _, sub := range subreddits { // Every match has one subreddit
+		report.Total += sub.Count
+		report.PreviousTotal += sub.Previous
+	}
+	report.Keywords = topCounts(keywords, weeklyReportTop)
+	report.Subreddits = topCounts(subreddits, weeklyReportTop)
+
+	// Days are bucketed at the UTC offset of the week's end, so a DST change within it shifts an hour
+	currentFilter := matchesFilter(since, "", "")
+	currentFilter["processed_at"] = map[string]interface{}{"$gte": since, "$lt": until}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: currentFilter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$processed_at", "timezone": until.Format("-07:00")}},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return report, fmt.Errorf("error counting matches per day: %w", err)
+	}
+	counted := []DayCount{}
+	if err := cursor.All(ctx, &counted); err != nil {
+		return report, fmt.Errorf("error counting matches per day: %w", err)
+	}
+	perDay := map[string]int64{}
+	for _, day := range counted {
+		perDay[day.Day] = day.Count
+	}
+	for day := since; day.Before(until); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		report.Days = append(report.Days, DayCount{Day: key, Count: perDay[key]})
+	}
+	return report, nil
+}
+
+// topCounts returns the first n counts with matches this week, counts being sorted busiest first.
+func topCounts(counts []CountDelta, n int) []CountDelta {
+	top := []CountDelta{}
+	for _, count := range counts {
+		if count.Count > 0 && len(top) < n {
+			top = append(top, count)
+		}
+	}
+	return top
+}
+
+// formatWeeklyReport returns the subject, plain text and HTML of a report. An empty week is a
+// short note without HTML, so it is still clear the monitor runs.
+func formatWeeklyReport(report WeeklyReport) (subject, text, htmlText string) {
+	period := fmt.Sprintf("%s to %s", report.Since.Format("Mon Jan 2"), report.Until.AddDate(0, 0, -1).Format("Mon Jan 2"))
+	if report.Total == 0 {
+		subject = fmt.Sprintf("Reddit Keyword Weekly Report: no matches, %s", period)
+		text = fmt.Sprintf("No matches from %s (%d the week before).\n\nThe monitor is running, watching %d subreddit(s) for %d keyword(s).\n",
+			period, report.PreviousTotal, len(subreddits), len(keywords))
+		return subject, text, ""
+	}
+
+	delta := formatDelta(report.Total, report.PreviousTotal)
+	subject = fmt.Sprintf("Reddit Keyword Weekly Report: %d match(es), %s, %s", report.Total, delta, period)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Matches from %s: %d, %s vs the week before (%d)\n", period, report.Total, delta, report.PreviousTotal)
+	for _, section := range []struct {
+		title  string
+		counts []CountDelta
+		prefix string
+	}{{"Top keywords", report.Keywords, ""}, {"Top subreddits", report.Subreddits, "r/"}} {
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		for _, count := range section.counts {
+			fmt.Fprintf(tw, "  %s%s\t%d\t%s\n", section.prefix, count.Name, count.Count, formatDelta(count.Count, count.Previous))
+		}
+		tw.Flush()
+	}
+	b.WriteString("\nMatches per day:\n")
+	busiest := maxDayCount(report.Days)
+	for _, day := range report.Days {
+		date, _ := time.ParseInLocation("2006-01-02", day.Day, report.Since.Location())
+		fmt.Fprintf(&b, "  %-10s  %-20s %d\n", date.Format("Mon Jan 2"), strings.Repeat("#", int(day.Count*20/busiest)), day.Count)
+	}
+	text = b.String()
+
+	var h strings.Builder
+	if err := weeklyReportTemplate.Execute(&h, weeklyReportPage{WeeklyReport: report, Period: period, Delta: delta, Busiest: busiest}); err != nil {
+		fmt.Printf("WARN: Could not render the weekly report as HTML, sending plain text: %v\n", err)
+		return subject, text, htmlBody(text)
+	}
+	return subject, text, h.String()
+}
+
+// maxDayCount returns the busiest day's count, at least 1 so bars can be scaled by it.
+func maxDayCount(days []DayCount) int64 {
+	busiest := int64(1)
+	for _, day := range days {
+		busiest = max(busiest, day.Count)
+	}
+	return busiest
+}
+
+// weeklyReportPage is the data of weeklyReportTemplate
+type weeklyReportPage struct {
+	WeeklyReport
+	Period  string
+	Delta   string
+	Busiest int64
+}
+
+// weeklyReportTemplate renders the report email. Styles are inline and bars are table cells, as
+// email clients drop style sheets and most SVG.
+var weeklyReportTemplate = template.Must(template.New("weekly").Funcs(template.FuncMap{
+	"delta": formatDelta,
+	"day": func(day string) string {
+		date, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			return day
+		}
+		return date.Format("Mon Jan 2")
+	},
+	"barWidth": func(count, busiest int64) int64 {
+		return max(count*300/busiest, 1) // Pixels, a sliver for empty days
+	},
+}).Parse(`<div style="font-family:sans-serif;font-size:14px">
+<p>Matches from {{.Period}}: <b>{{.Total}}</b>, {{.Delta}} vs the week before ({{.PreviousTotal}})</p>
+<h3>Matches per day</h3>
+<table cellspacing="0" cellpadding="3">
+{{range .Days}}<tr><td>{{day .Day}}</td><td><table cellspacing="0" cellpadding="0"><tr><td style="background:#3b82f6;height:14px;width:{{barWidth .Count $.Busiest}}px"></td></tr></table></td><td>{{.Count}}</td></tr>
+{{end}}</table>
+<h3>Top keywords</h3>
+<table cellspacing="0" cellpadding="3" border="1" style="border-collapse:collapse">
+<tr><th align="left">Keyword</th><th>Matches</th><th>Change</th></tr>
+{{range .Keywords}}<tr><td>{{.Name}}</td><td align="right">{{.Count}}</td><td align="right">{{delta .Count .Previous}}</td></tr>
+{{end}}</table>
+<h3>Top subreddits</h3>
+<table cellspacing="0" cellpadding="3" border="1" style="border-collapse:collapse">
+<tr><th align="left">Subreddit</th><th>Matches</th><th>Change</th></tr>
+{{range .Subreddits}}<tr><td><a href="https://www.reddit.com/r/{{.Name}}">r/{{.Name}}</a></td><td align="right">{{.Count}}</td><td align="right">{{delta .Count .Previous}}</td></tr>
+{{end}}</table>
+</div>
+`))